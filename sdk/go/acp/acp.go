@@ -1,9 +1,13 @@
 package acp
 
 import (
+	"context"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"sync"
 	"time"
 
@@ -25,10 +29,22 @@ type Creator struct {
 	mux          sync.Mutex
 	contentMap   map[string]Content // content_id -> content
 	contentIDMap map[string]string  // content_id -> block_id
+	openBlocks   []string           // 已开始未结束的区块, 按开始顺序
+	pricer       Pricer
+	log          EventLog
+	interceptors []Interceptor
+	handler      Handler
 }
 
-func NewCreator(writer *SSEWriter) *Creator {
-	return &Creator{
+type CreatorOption func(*Creator)
+
+// WithPricer 在区块结束时为未携带费用的用量本地计价
+func WithPricer(pricer Pricer) CreatorOption {
+	return func(m *Creator) { m.pricer = pricer }
+}
+
+func NewCreator(writer *SSEWriter, opts ...CreatorOption) *Creator {
+	m := &Creator{
 		Message: &Message{
 			ID:        uuid.NewString(),
 			Role:      RoleAssistant,
@@ -41,32 +57,39 @@ func NewCreator(writer *SSEWriter) *Creator {
 		contentMap:   make(map[string]Content),
 		contentIDMap: make(map[string]string),
 	}
+
+	for _, o := range opts {
+		o(m)
+	}
+	m.handler = chain(m.interceptors, m.process)
+
+	return m
 }
 
-func (m *Creator) AddEvent(e Event) (err error) {
-	switch e.Type() {
-	case EventTypeRunStarted, EventTypeRunFinished, EventTypeRunError:
-		if e.Type() == EventTypeRunStarted {
-			if m.hasStarted {
-				return nil
-			}
-			m.hasStarted = true
-		} else {
-			if m.hasFinished {
-				return errors.New("event stream already done")
-			}
-			m.hasFinished = true
-		}
+func (m *Creator) AddEvent(e Event) error {
+	return m.AddEventContext(context.Background(), e)
+}
 
-		err = m.processRunEvent(e)
-	case EventTypeBlockStart, EventTypeBlockEnd:
-		err = m.processBlockEvent(e)
-	case EventTypeContentStart, EventTypeContentDelta, EventTypeContentEnd:
-		err = m.processContentEvent(e)
-	default:
-		return fmt.Errorf("unsupport event: %s", e.Type())
+// AddEventContext 经拦截链处理事件, ctx 传递给各拦截器, 拦截器可通过 CreatorFrom 取得 m
+func (m *Creator) AddEventContext(ctx context.Context, e Event) error {
+	return m.handler(context.WithValue(ctx, creatorKey{}, m), e)
+}
+
+// process 校验事件, 写日志后聚合并发送, 是拦截链的末端.
+// 日志写入失败时消息保持不变, 保证日志重放与内存状态一致
+func (m *Creator) process(_ context.Context, e Event) error {
+	ok, err := m.check(e)
+	if err != nil || !ok {
+		return err
 	}
-	if err != nil {
+
+	if m.log != nil {
+		if err := m.log.Append(e); err != nil {
+			return fmt.Errorf("event log append failed: %w", err)
+		}
+	}
+
+	if err := m.apply(e); err != nil {
 		return err
 	}
 
@@ -80,10 +103,101 @@ func (m *Creator) AddEvent(e Event) (err error) {
 	return nil
 }
 
+// check 在不修改状态的前提下校验事件能否被 apply 接受, 返回 false 表示事件将被忽略.
+// 所有校验都在此处完成, apply 不再重复校验
+func (m *Creator) check(e Event) (ok bool, err error) {
+	switch evt := e.(type) {
+	case RunStartedEvent:
+		return !m.hasStarted, nil
+	case RunFinishedEvent, RunErrorEvent:
+		if m.hasFinished {
+			return false, errors.New("event stream already done")
+		}
+	case BlockStartEvent, BlockEndEvent, ContentStartEvent, ContentEndEvent, NoticeEvent:
+	case BlockErrorEvent:
+		if !slices.ContainsFunc(m.Blocks, func(b Block) bool { return b.ID == evt.BlockID }) {
+			return false, fmt.Errorf("%w: block %s not found", ErrBlockEvent, evt.BlockID)
+		}
+	case ContentDeltaEvent:
+		m.mux.Lock()
+		content, ok := m.contentMap[evt.ContentID]
+		m.mux.Unlock()
+		if ok {
+			return true, checkContent(content, evt.Content)
+		}
+	default:
+		return false, fmt.Errorf("unsupport event: %s", e.Type())
+	}
+	return true, nil
+}
+
+// apply 更新聚合状态, 事件须已通过 check 校验
+func (m *Creator) apply(e Event) error {
+	switch evt := e.(type) {
+	case RunStartedEvent:
+		m.hasStarted = true
+		return m.processRunEvent(e)
+	case RunFinishedEvent, RunErrorEvent:
+		m.hasFinished = true
+		return m.processRunEvent(e)
+	case BlockStartEvent, BlockEndEvent, BlockErrorEvent:
+		return m.processBlockEvent(e)
+	case ContentStartEvent, ContentDeltaEvent, ContentEndEvent:
+		return m.processContentEvent(e)
+	case NoticeEvent:
+		m.Notices = append(m.Notices, evt.Notice)
+	}
+	return nil
+}
+
+// Snapshot 返回当前消息的深拷贝, 未结束的内容按 ID 顺序附加到所属区块末尾,
+// 延迟物化的字段(如 DataContent.Data)在此时生成
+func (m *Creator) Snapshot() (*Message, error) {
+	m.mux.Lock()
+	msg := *m.Message
+	msg.Blocks = slices.Clone(m.Blocks)
+	for i := range msg.Blocks {
+		msg.Blocks[i].Contents = slices.Clone(msg.Blocks[i].Contents)
+	}
+	for _, id := range slices.Sorted(maps.Keys(m.contentIDMap)) {
+		content := m.contentMap[id]
+		if content == nil {
+			continue
+		}
+		if c, ok := content.(bufferedContent); ok {
+			c.flush()
+		}
+		for i := len(msg.Blocks) - 1; i >= 0; i-- {
+			if msg.Blocks[i].ID == m.contentIDMap[id] {
+				msg.Blocks[i].Contents = append(msg.Blocks[i].Contents, content)
+				break
+			}
+		}
+	}
+	data, err := json.Marshal(&msg)
+	m.mux.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var out Message
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Finished 运行是否已结束(RunFinished 或 RunError)
+func (m *Creator) Finished() bool {
+	return m.hasFinished
+}
+
 func (m *Creator) processRunEvent(e Event) error {
 	switch evt := e.(type) {
 	case RunStartedEvent:
 		m.ID = evt.RunID
+		m.SessionID = evt.SessionID
+		m.RunID = evt.RunID
 		return nil
 
 	case RunFinishedEvent:
@@ -91,6 +205,10 @@ func (m *Creator) processRunEvent(e Event) error {
 
 	case RunErrorEvent:
 		m.Errors = evt.Error
+		m.RunError = evt.Info.clone()
+		if m.Errors == "" && m.RunError != nil {
+			m.Errors = m.RunError.Message
+		}
 		return nil
 
 	default:
@@ -110,12 +228,26 @@ func (m *Creator) processBlockEvent(e Event) error {
 			Metadata:      evt.Metadata,
 			ParentBlockID: evt.ParentBlockID,
 		})
+		m.openBlocks = append(m.openBlocks, evt.BlockID)
 		return nil
 
 	case BlockEndEvent:
 		for i := len(m.Blocks) - 1; i >= 0; i-- {
 			if m.Blocks[i].ID == evt.BlockID {
-				m.Blocks[i].Usage = evt.Usage
+				m.Blocks[i].Usage = m.priceUsage(evt.Usage)
+				break
+			}
+		}
+		if i := slices.Index(m.openBlocks, evt.BlockID); i >= 0 {
+			m.openBlocks = slices.Delete(m.openBlocks, i, i+1)
+		}
+		m.rollupUsage()
+		return nil
+
+	case BlockErrorEvent:
+		for i := len(m.Blocks) - 1; i >= 0; i-- {
+			if m.Blocks[i].ID == evt.BlockID {
+				m.Blocks[i].Errors = append(m.Blocks[i].Errors, *evt.Error.clone())
 				break
 			}
 		}
@@ -126,6 +258,25 @@ func (m *Creator) processBlockEvent(e Event) error {
 	}
 }
 
+// priceUsage 返回用量副本, 避免修改调用方传入的事件
+func (m *Creator) priceUsage(u *Usage) *Usage {
+	if u == nil {
+		return nil
+	}
+
+	usage := *u
+	if usage.Cost == nil && m.pricer != nil {
+		if cost, ok := m.pricer.Cost(&usage); ok {
+			usage.Cost = &cost
+		}
+	}
+	return &usage
+}
+
+func (m *Creator) rollupUsage() {
+	m.Usage = m.TotalUsage()
+}
+
 func (m *Creator) processContentEvent(e Event) error {
 	switch evt := e.(type) {
 	case ContentStartEvent:
@@ -141,13 +292,18 @@ func (m *Creator) processContentEvent(e Event) error {
 		return nil
 
 	case ContentDeltaEvent:
-		return m.processContent(evt.ContentID, evt.Content)
+		m.processContent(evt.ContentID, evt.Content)
+		return nil
 
 	case ContentEndEvent:
 		m.mux.Lock()
 		content, ok1 := m.contentMap[evt.ContentID]
 		blockID, ok2 := m.contentIDMap[evt.ContentID]
-		if ok1 && ok2 {
+		// 没有任何增量的内容(如增量全部被拦截器丢弃)不写入区块
+		if ok1 && ok2 && content != nil {
+			if c, ok := content.(bufferedContent); ok {
+				c.flush()
+			}
 			for i := len(m.Blocks) - 1; i >= 0; i-- {
 				if m.Blocks[i].ID == blockID {
 					m.Blocks[i].Contents = append(m.Blocks[i].Contents, content)
@@ -155,9 +311,9 @@ func (m *Creator) processContentEvent(e Event) error {
 				}
 			}
 
-			delete(m.contentIDMap, evt.ContentID)
-			delete(m.contentMap, evt.ContentID)
 		}
+		delete(m.contentIDMap, evt.ContentID)
+		delete(m.contentMap, evt.ContentID)
 		m.mux.Unlock()
 		return nil
 
@@ -166,113 +322,81 @@ func (m *Creator) processContentEvent(e Event) error {
 	}
 }
 
-func (m *Creator) processContent(id string, sc StreamContent) error {
+// processContent 应用增量, 增量须已通过 checkContent 校验
+func (m *Creator) processContent(id string, sc StreamContent) {
 	m.mux.Lock()
 	defer m.mux.Unlock()
 
 	content, ok := m.contentMap[id]
 	if !ok {
-		return nil
+		return
 	}
 
-	switch sc.SType() {
-	case ContentTypeText:
-		evt, ok := sc.(StreamTextContent)
-		if !ok {
-			return ErrContentEvent
-		}
-
+	switch evt := sc.(type) {
+	case StreamTextContent:
 		if content == nil {
 			content = NewTextContent(id, evt.Delta)
 		} else {
 			content.(*TextContent).Append(evt.Delta)
 		}
 
-	case ContentTypeThinking:
-		evt, ok := sc.(StreamThinkingContent)
-		if !ok {
-			return ErrContentEvent
+	case StreamTextAnnotationContent:
+		if content == nil {
+			content = NewTextContent(id, "")
 		}
+		content.(*TextContent).AddAnnotations(evt.Annotations...)
 
+	case StreamThinkingContent:
 		if content == nil {
 			content = NewThinkingContent(id, evt.Delta)
 		} else {
 			content.(*ThinkingContent).Append(evt.Delta)
 		}
-
-	case ContentTypeToolCall:
-		evt, ok := sc.(StreamToolCallContent)
-		if !ok {
-			return ErrContentEvent
+		if evt.Signature != "" {
+			content.(*ThinkingContent).Signature = evt.Signature
+		}
+		if evt.RedactedData != "" {
+			content.(*ThinkingContent).RedactedData = evt.RedactedData
 		}
 
+	case StreamToolCallContent:
 		if content == nil {
 			content = NewToolCallContent(evt.ToolName)
 		}
-
-	case ContentTypeToolArgs:
-		evt, ok := sc.(StreamToolArgsContent)
-		if !ok || content == nil {
-			return ErrContentEvent
+		if evt.ToolCallID != "" {
+			content.(*ToolCallContent).ToolCallID = evt.ToolCallID
 		}
+
+	case StreamToolArgsContent:
 		content.(*ToolCallContent).ToolArgs += evt.Delta
 
-	case ContentTypeToolResult:
-		evt, ok := sc.(StreamToolResultContent)
-		if !ok || content == nil {
-			return ErrContentEvent
-		}
+	case StreamToolResultContent:
 		if evt.Error != nil {
 			content.(*ToolCallContent).Error = evt.Error
 		} else {
 			content.(*ToolCallContent).ToolResult += evt.Delta
 		}
 
-	case ContentTypeFile:
-		evt, ok := sc.(StreamFileContent)
-		if !ok {
-			return ErrContentEvent
-		}
-
+	case StreamFileContent:
 		if content == nil {
 			content = NewFileContent(evt.MimeType, evt.FileID)
 		}
 
-	case ContentTypeData:
-		evt, ok := sc.(StreamDataContent)
-		if !ok {
-			return ErrContentEvent
-		}
-
-		// base64 decode
-		decoded, err := base64.StdEncoding.DecodeString(evt.Delta)
-		if err != nil {
-			return err
-		}
-
+	case StreamDataContent:
+		// 已在 checkContent 中校验 base64
+		decoded, _ := base64.StdEncoding.DecodeString(evt.Delta)
 		if content == nil {
 			content = NewDataContent(evt.MimeType, decoded)
 		} else {
-			content.(*DataContent).Origin = append(content.(*DataContent).Origin, decoded...)
-			content.(*DataContent).Data = base64.StdEncoding.EncodeToString(content.(*DataContent).Origin)
-		}
-
-	case ContentTypeArtifact:
-		evt, ok := sc.(StreamArtifactContent)
-		if !ok {
-			return ErrContentEvent
+			content.(*DataContent).Append(decoded)
 		}
 
+	case StreamArtifactContent:
 		if content == nil {
 			content = NewArtifactContent(evt.MimeType, evt.FileID)
 		}
 
-	case ContentTypeVariable:
-		evt, ok := sc.(StreamVariableContent)
-		if !ok {
-			return ErrContentEvent
-		}
-
+	case StreamVariableContent:
 		if content == nil {
 			content = NewVariableContent(evt.Delta)
 		} else {
@@ -281,19 +405,11 @@ func (m *Creator) processContent(id string, sc StreamContent) error {
 			}
 		}
 
-	case ContentTypeInteraction:
-		evt, ok := sc.(StreamInteractionContent)
-		if !ok {
-			return ErrContentEvent
-		}
-
+	case StreamInteractionContent:
 		if content == nil {
 			content = NewInteractionContent(evt.InteractionID, evt.A2UIVersion, evt.A2UIMessage)
 		} else {
-			interaction, ok := content.(*InteractionContent)
-			if !ok {
-				return ErrContentEvent
-			}
+			interaction := content.(*InteractionContent)
 			if interaction.InteractionID == "" && evt.InteractionID != "" {
 				interaction.InteractionID = evt.InteractionID
 			}
@@ -303,106 +419,56 @@ func (m *Creator) processContent(id string, sc StreamContent) error {
 			interaction.AddMessage(evt.A2UIMessage)
 		}
 
-	case ContentTypeCustom:
-		evt, ok := sc.(StreamCustomContent)
-		if !ok {
-			return ErrContentEvent
-		}
-
-		if content == nil {
-			content = NewCustomContent(evt.Raw)
-		} else {
-			return ErrContentEvent
-		}
-
-	case ContentTypeMcpCall:
-		evt, ok := sc.(StreamMCPCallContent)
-		if !ok {
-			return ErrContentEvent
-		}
+	case StreamCustomContent:
+		content = NewCustomContent(evt.Raw)
 
+	case StreamMCPCallContent:
 		if content == nil {
 			content = NewMCPContent(evt.Server, evt.ToolName)
 		}
 
-	case ContentTypeMcpArgs:
-		evt, ok := sc.(StreamMCPArgsContent)
-		if !ok || content == nil {
-			return ErrContentEvent
-		}
+	case StreamMCPArgsContent:
 		content.(*MCPContent).ToolArgs += evt.Delta
 
-	case ContentTypeMcpResult:
-		evt, ok := sc.(StreamMCPResultContent)
-		if !ok || content == nil {
-			return ErrContentEvent
-		}
+	case StreamMCPResultContent:
 		if evt.Error != nil {
 			content.(*MCPContent).Error = evt.Error
 		} else {
 			content.(*MCPContent).ToolResult += evt.Delta
 		}
 
-	case ContentTypeCommandExecution:
-		evt, ok := sc.(StreamCommandContent)
-		if !ok {
-			return ErrContentEvent
-		}
-
+	case StreamCommandContent:
 		if content == nil {
 			content = NewCommandContent(evt.Command)
 		}
 
-	case ContentTypeCommandExecutionResult:
-		evt, ok := sc.(StreamCommandResultContent)
-		if !ok || content == nil {
-			return ErrContentEvent
-		}
+	case StreamCommandResultContent:
 		if evt.Error != nil {
 			content.(*CommandContent).Error = evt.Error
 		} else {
 			content.(*CommandContent).Result += evt.Delta
 		}
 
-	case ContentTypeCodeExecution:
-		evt, ok := sc.(StreamCodeExecutionContent)
-		if !ok {
-			return ErrContentEvent
-		}
-
+	case StreamCodeExecutionContent:
 		if content == nil {
 			content = NewCodeExecutionContent(evt.Lang, evt.Delta)
 		} else {
 			content.(*CodeExecutionContent).Code += evt.Delta
 		}
 
-	case ContentTypeCodeExecutionResult:
-		evt, ok := sc.(StreamCodeExecutionResultContent)
-		if !ok || content == nil {
-			return ErrContentEvent
-		}
+	case StreamCodeExecutionResultContent:
 		if evt.Error != nil {
 			content.(*CodeExecutionContent).Error = evt.Error
 		} else {
 			content.(*CodeExecutionContent).Result += evt.Delta
 		}
 
-	case ContentTypeWebSearch:
-		evt, ok := sc.(StreamWebSearchContent)
-		if !ok {
-			return ErrContentEvent
-		}
-
+	case StreamWebSearchContent:
 		if content == nil {
 			content = NewWebSearchContent(evt.Delta)
 		}
 
-	case ContentTypeWebSearchResult:
-		evt, ok := sc.(StreamWebSearchResultContent)
-		if !ok {
-			return ErrContentEvent
-		}
-
+	case StreamWebSearchResultContent:
 		if evt.Error != nil {
 			content.(*WebSearchContent).Error = evt.Error
 		} else {
@@ -410,74 +476,142 @@ func (m *Creator) processContent(id string, sc StreamContent) error {
 			content.(*WebSearchContent).Results = evt.Results
 		}
 
-	case ContentTypeTodoList:
-		evt, ok := sc.(StreamTodoListContent)
-		if !ok {
-			return ErrContentEvent
-		}
-
+	case StreamTodoListContent:
 		if content == nil {
 			content = NewTodoListContent(evt.Todos)
 		} else {
-			todoList, ok := content.(*TodoListContent)
-			if !ok {
-				return ErrContentEvent
-			}
-			todoList.Todos = evt.Todos
-		}
-
-	case ContentTypeSkillLoad:
-		evt, ok := sc.(StreamSkillLoadedContent)
-		if !ok {
-			return ErrContentEvent
+			content.(*TodoListContent).Todos = evt.Todos
 		}
 
+	case StreamSkillLoadedContent:
 		if content == nil {
 			content = NewSkillLoadedContent(evt.Name)
 		} else {
-			skillLoaded, ok := content.(*SkillLoadedContent)
-			if !ok {
-				return ErrContentEvent
-			}
-			if skillLoaded.Name != "" {
-				return ErrContentEvent
-			}
-			skillLoaded.Name = evt.Name
-		}
-
-	case ContentTypeQA:
-		evt, ok := sc.(StreamQAContent)
-		if !ok {
-			return ErrContentEvent
+			content.(*SkillLoadedContent).Name = evt.Name
 		}
 
+	case StreamQAContent:
 		if content == nil {
 			content = NewQAContent(evt.QAID, evt.QAType, evt.QAName, evt.Message, evt.Options)
 		} else {
-			streamQA, ok := content.(*QAContent)
-			if !ok {
-				return ErrContentEvent
+			qa := content.(*QAContent)
+			qa.QAID = evt.QAID
+			qa.QAType = evt.QAType
+			qa.QAName = evt.QAName
+			qa.Message = evt.Message
+			qa.Options = evt.Options
+		}
+
+	case StreamQAResultContent:
+		content.(*QAContent).Answer = evt.Answer
+
+	case StreamApprovalContent:
+		approval := evt.Approval
+		edit := approval.Decision == ApprovalEdit
+		switch c := content.(type) {
+		case *ToolCallContent:
+			c.Approval = &approval
+			if edit {
+				approval.OriginalArgs, c.ToolArgs = c.ToolArgs, approval.Args
+			}
+		case *MCPContent:
+			c.Approval = &approval
+			if edit {
+				approval.OriginalArgs, c.ToolArgs = c.ToolArgs, approval.Args
+			}
+		case *CommandContent:
+			c.Approval = &approval
+			if edit {
+				approval.OriginalArgs, c.Command = c.Command, approval.Args
 			}
-			streamQA.QAID = evt.QAID
-			streamQA.QAType = evt.QAType
-			streamQA.QAName = evt.QAName
-			streamQA.Message = evt.Message
-			streamQA.Options = evt.Options
 		}
+	}
 
-	case ContentTypeQAResult:
-		evt, ok := sc.(StreamQAResultContent)
-		if !ok || content == nil {
-			return ErrContentEvent
-		}
+	m.contentMap[id] = content
+}
 
-		qa, ok := content.(*QAContent)
-		if !ok {
-			return ErrContentEvent
+// checkContent 校验增量能否应用于当前内容, 是 processContent 唯一的校验
+func checkContent(content Content, sc StreamContent) error {
+	var stype string
+	fits, required := true, false
+	switch sc.(type) {
+	case StreamTextContent:
+		stype, fits = ContentTypeText, contentIs[*TextContent](content)
+	case StreamTextAnnotationContent:
+		stype, fits = ContentTypeTextAnnotation, contentIs[*TextContent](content)
+	case StreamThinkingContent:
+		stype, fits = ContentTypeThinking, contentIs[*ThinkingContent](content)
+	case StreamToolCallContent:
+		stype, fits = ContentTypeToolCall, contentIs[*ToolCallContent](content)
+	case StreamToolArgsContent:
+		stype, fits, required = ContentTypeToolArgs, contentIs[*ToolCallContent](content), true
+	case StreamToolResultContent:
+		stype, fits, required = ContentTypeToolResult, contentIs[*ToolCallContent](content), true
+	case StreamFileContent:
+		stype, fits = ContentTypeFile, contentIs[*FileContent](content)
+	case StreamDataContent:
+		if _, err := base64.StdEncoding.DecodeString(sc.(StreamDataContent).Delta); err != nil {
+			return err
 		}
-		qa.Answer = evt.Answer
+		stype, fits = ContentTypeData, contentIs[*DataContent](content)
+	case StreamArtifactContent:
+		stype, fits = ContentTypeArtifact, contentIs[*ArtifactContent](content)
+	case StreamVariableContent:
+		stype, fits = ContentTypeVariable, contentIs[*VariableContent](content)
+	case StreamInteractionContent:
+		stype, fits = ContentTypeInteraction, contentIs[*InteractionContent](content)
+	case StreamCustomContent:
+		stype, fits = ContentTypeCustom, content == nil
+	case StreamMCPCallContent:
+		stype, fits = ContentTypeMcpCall, contentIs[*MCPContent](content)
+	case StreamMCPArgsContent:
+		stype, fits, required = ContentTypeMcpArgs, contentIs[*MCPContent](content), true
+	case StreamMCPResultContent:
+		stype, fits, required = ContentTypeMcpResult, contentIs[*MCPContent](content), true
+	case StreamCommandContent:
+		stype, fits = ContentTypeCommandExecution, contentIs[*CommandContent](content)
+	case StreamCommandResultContent:
+		stype, fits, required = ContentTypeCommandExecutionResult, contentIs[*CommandContent](content), true
+	case StreamCodeExecutionContent:
+		stype, fits = ContentTypeCodeExecution, contentIs[*CodeExecutionContent](content)
+	case StreamCodeExecutionResultContent:
+		stype, fits, required = ContentTypeCodeExecutionResult, contentIs[*CodeExecutionContent](content), true
+	case StreamWebSearchContent:
+		stype, fits = ContentTypeWebSearch, contentIs[*WebSearchContent](content)
+	case StreamWebSearchResultContent:
+		stype, fits, required = ContentTypeWebSearchResult, contentIs[*WebSearchContent](content), true
+	case StreamTodoListContent:
+		stype, fits = ContentTypeTodoList, contentIs[*TodoListContent](content)
+	case StreamSkillLoadedContent:
+		skill, ok := content.(*SkillLoadedContent)
+		stype, fits = ContentTypeSkillLoad, content == nil || (ok && skill.Name == "")
+	case StreamQAContent:
+		stype, fits = ContentTypeQA, contentIs[*QAContent](content)
+	case StreamQAResultContent:
+		stype, fits, required = ContentTypeQAResult, contentIs[*QAContent](content), true
+	case StreamApprovalContent:
+		switch content.(type) {
+		case nil, *ToolCallContent, *MCPContent, *CommandContent:
+		default:
+			fits = false
+		}
+		stype, required = ContentTypeApproval, true
+	default:
+		// 未知类型被忽略
+		return nil
 	}
 
-	m.contentMap[id] = content
+	if sc.SType() != stype || !fits || (required && content == nil) {
+		return ErrContentEvent
+	}
 	return nil
 }
+
+// contentIs 内容尚未创建或为 T 类型
+func contentIs[T Content](c Content) bool {
+	if c == nil {
+		return true
+	}
+	_, ok := c.(T)
+	return ok
+}
@@ -1,6 +1,7 @@
 package acp
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"testing"
@@ -9,6 +10,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/mel2oo/go-dkit/json"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestUserMessage(t *testing.T) {
@@ -126,3 +128,37 @@ func TestBase64(t *testing.T) {
 
 	fmt.Println(base64.StdEncoding.EncodeToString([]byte(data)))
 }
+
+func TestCreatorFrom(t *testing.T) {
+	var seen []EventType
+	var text string
+	observe := func(ctx context.Context, e Event, next Handler) error {
+		if err := next(ctx, e); err != nil {
+			return err
+		}
+		m, ok := CreatorFrom(ctx)
+		require.True(t, ok)
+		seen = append(seen, e.Type())
+		if e.Type() == EventTypeContentEnd {
+			text = m.Blocks[0].Contents[0].(*TextContent).Text
+		}
+		return nil
+	}
+	m := NewCreator(nil, WithInterceptors(observe))
+
+	assert.NoError(t, m.AddEvent(NewRunStartedEvent("s1", "r1")))
+	assert.NoError(t, m.AddEvent(NewBlockStartEvent("b1")))
+	assert.NoError(t, m.AddEvent(NewContentStartEvent("c1", "b1")))
+	assert.NoError(t, m.AddEvent(NewContentDeltaEvent("c1", NewStreamTextContent("hi"))))
+	assert.NoError(t, m.AddEvent(NewContentEndEvent("c1")))
+	assert.Error(t, m.AddEvent(NewBlockErrorEvent("missing", nil)))
+
+	// next 返回后看到的是聚合后的状态, 被拒绝的事件不会被观察到
+	assert.Equal(t, []EventType{
+		EventTypeRunStarted, EventTypeBlockStart, EventTypeContentStart, EventTypeContentDelta, EventTypeContentEnd,
+	}, seen)
+	assert.Equal(t, "hi", text)
+
+	_, ok := CreatorFrom(context.Background())
+	assert.False(t, ok)
+}
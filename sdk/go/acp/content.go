@@ -4,21 +4,23 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 // Content Type
 const (
-	ContentTypeText        = "text"
-	ContentTypeThinking    = "thinking"
-	ContentTypeToolCall    = "tool_call"
-	ContentTypeToolArgs    = "tool_args"
-	ContentTypeToolResult  = "tool_result"
-	ContentTypeFile        = "file"
-	ContentTypeData        = "data"
-	ContentTypeArtifact    = "artifact"
-	ContentTypeVariable    = "variable"
-	ContentTypeInteraction = "interaction"
-	ContentTypeCustom      = "custom"
+	ContentTypeText           = "text"
+	ContentTypeTextAnnotation = "text_annotation"
+	ContentTypeThinking       = "thinking"
+	ContentTypeToolCall       = "tool_call"
+	ContentTypeToolArgs       = "tool_args"
+	ContentTypeToolResult     = "tool_result"
+	ContentTypeFile           = "file"
+	ContentTypeData           = "data"
+	ContentTypeArtifact       = "artifact"
+	ContentTypeVariable       = "variable"
+	ContentTypeInteraction    = "interaction"
+	ContentTypeCustom         = "custom"
 
 	ContentTypeMcpCall                = "mcp_call"
 	ContentTypeMcpArgs                = "mcp_args"
@@ -33,6 +35,7 @@ const (
 	ContentTypeSkillLoad              = "skill_loaded"
 	ContentTypeQA                     = "qa"
 	ContentTypeQAResult               = "qa_result"
+	ContentTypeApproval               = "approval"
 )
 
 type Error struct {
@@ -40,6 +43,56 @@ type Error struct {
 	Message string `json:"message"`
 }
 
+// Annotation Type
+const (
+	AnnotationTypeCitation = "citation"
+	AnnotationTypeFile     = "file"
+	AnnotationTypeArtifact = "artifact"
+)
+
+// 文本标注, StartIndex/EndIndex 为文本中按字符(rune)计的左闭右开区间
+type Annotation struct {
+	Type          string `json:"type"`
+	StartIndex    int    `json:"start_index"`
+	EndIndex      int    `json:"end_index"`
+	CitationIndex *int   `json:"citation_index,omitempty"` // WebSearchContent.Results 下标
+	Url           string `json:"url,omitempty"`
+	Title         string `json:"title,omitempty"`
+	FileID        string `json:"file_id,omitempty"`
+	MimeType      string `json:"mime_type,omitempty"`
+}
+
+func NewCitationAnnotation(start, end, index int, result WebSearchResult) Annotation {
+	return Annotation{
+		Type:          AnnotationTypeCitation,
+		StartIndex:    start,
+		EndIndex:      end,
+		CitationIndex: &index,
+		Url:           result.Url,
+		Title:         result.Title,
+	}
+}
+
+func NewFileAnnotation(start, end int, mimeType, fileID string) Annotation {
+	return Annotation{
+		Type:       AnnotationTypeFile,
+		StartIndex: start,
+		EndIndex:   end,
+		FileID:     fileID,
+		MimeType:   mimeType,
+	}
+}
+
+func NewArtifactAnnotation(start, end int, mimeType, fileID string) Annotation {
+	return Annotation{
+		Type:       AnnotationTypeArtifact,
+		StartIndex: start,
+		EndIndex:   end,
+		FileID:     fileID,
+		MimeType:   mimeType,
+	}
+}
+
 /********************************************************/
 /*************** Stream Content Structure ***************/
 /********************************************************/
@@ -75,11 +128,27 @@ func NewStreamTextContent(delta string) StreamTextContent {
 	}
 }
 
-// 思考流式消息
+// 文本标注流式消息
+type StreamTextAnnotationContent struct {
+	StreamBaseContent
+
+	Annotations []Annotation `json:"annotations"`
+}
+
+func NewStreamTextAnnotationContent(annotations ...Annotation) StreamTextAnnotationContent {
+	return StreamTextAnnotationContent{
+		StreamBaseContent: NewStreamBaseContent(ContentTypeTextAnnotation),
+		Annotations:       annotations,
+	}
+}
+
+// 思考流式消息, Signature 为模型返回的思考签名, 多轮对话回传时需保留
 type StreamThinkingContent struct {
 	StreamBaseContent
 
-	Delta string `json:"delta"`
+	Delta        string `json:"delta"`
+	Signature    string `json:"signature,omitempty"`
+	RedactedData string `json:"redacted_data,omitempty"`
 }
 
 func NewStreamThinkingContent(delta string) StreamThinkingContent {
@@ -89,11 +158,27 @@ func NewStreamThinkingContent(delta string) StreamThinkingContent {
 	}
 }
 
+func NewStreamThinkingSignatureContent(signature string) StreamThinkingContent {
+	return StreamThinkingContent{
+		StreamBaseContent: NewStreamBaseContent(ContentTypeThinking),
+		Signature:         signature,
+	}
+}
+
+// NewStreamRedactedThinkingContent 模型返回的加密思考内容, 原样保留以便回传
+func NewStreamRedactedThinkingContent(data string) StreamThinkingContent {
+	return StreamThinkingContent{
+		StreamBaseContent: NewStreamBaseContent(ContentTypeThinking),
+		RedactedData:      data,
+	}
+}
+
 // 工具调用流式消息
 type StreamToolCallContent struct {
 	StreamBaseContent
 
-	ToolName string `json:"tool_name"`
+	ToolName   string `json:"tool_name"`
+	ToolCallID string `json:"tool_call_id,omitempty"` // 模型供应商给出的调用 ID
 }
 
 func NewStreamToolCallContent(toolName string) StreamToolCallContent {
@@ -468,6 +553,121 @@ func NewStreamQAResultContent(answer map[string]any) StreamQAResultContent {
 	}
 }
 
+// 审批决定
+const (
+	ApprovalApprove = "approve"
+	ApprovalDeny    = "deny"
+	ApprovalEdit    = "edit"
+)
+
+// 工具/MCP/命令调用的审批结果
+type Approval struct {
+	Decision     string `json:"decision"`
+	Args         string `json:"args,omitempty"`          // edit 时修改后的参数或命令
+	OriginalArgs string `json:"original_args,omitempty"` // edit 时被替换的原参数或命令, 由 Creator 聚合时记录
+	Reason       string `json:"reason,omitempty"`
+	QAID         string `json:"qa_id,omitempty"`
+	Auto         bool   `json:"auto,omitempty"` // 策略无需人工审批
+}
+
+// Approved 是否允许执行
+func (a Approval) Approved() bool {
+	return a.Decision == ApprovalApprove || a.Decision == ApprovalEdit
+}
+
+// ArgsOr 返回执行时应使用的参数
+func (a Approval) ArgsOr(original string) string {
+	if a.Decision == ApprovalEdit {
+		return a.Args
+	}
+	return original
+}
+
+// 审批结果流式消息, 写入待执行的调用内容; edit 时替换其参数或命令, 原值记录在 OriginalArgs
+type StreamApprovalContent struct {
+	StreamBaseContent
+	Approval
+}
+
+func NewStreamApprovalContent(approval Approval) StreamApprovalContent {
+	return StreamApprovalContent{
+		StreamBaseContent: NewStreamBaseContent(ContentTypeApproval),
+		Approval:          approval,
+	}
+}
+
+func unmarshalStreamContent(data []byte) (StreamContent, error) {
+	var base StreamBaseContent
+	if err := json.Unmarshal(data, &base); err != nil {
+		return nil, err
+	}
+
+	switch base.SType() {
+	case ContentTypeText:
+		return decodeStreamContent[StreamTextContent](data)
+	case ContentTypeTextAnnotation:
+		return decodeStreamContent[StreamTextAnnotationContent](data)
+	case ContentTypeThinking:
+		return decodeStreamContent[StreamThinkingContent](data)
+	case ContentTypeToolCall:
+		return decodeStreamContent[StreamToolCallContent](data)
+	case ContentTypeToolArgs:
+		return decodeStreamContent[StreamToolArgsContent](data)
+	case ContentTypeToolResult:
+		return decodeStreamContent[StreamToolResultContent](data)
+	case ContentTypeFile:
+		return decodeStreamContent[StreamFileContent](data)
+	case ContentTypeData:
+		return decodeStreamContent[StreamDataContent](data)
+	case ContentTypeArtifact:
+		return decodeStreamContent[StreamArtifactContent](data)
+	case ContentTypeVariable:
+		return decodeStreamContent[StreamVariableContent](data)
+	case ContentTypeInteraction:
+		return decodeStreamContent[StreamInteractionContent](data)
+	case ContentTypeCustom:
+		return decodeStreamContent[StreamCustomContent](data)
+	case ContentTypeMcpCall:
+		return decodeStreamContent[StreamMCPCallContent](data)
+	case ContentTypeMcpArgs:
+		return decodeStreamContent[StreamMCPArgsContent](data)
+	case ContentTypeMcpResult:
+		return decodeStreamContent[StreamMCPResultContent](data)
+	case ContentTypeCommandExecution:
+		return decodeStreamContent[StreamCommandContent](data)
+	case ContentTypeCommandExecutionResult:
+		return decodeStreamContent[StreamCommandResultContent](data)
+	case ContentTypeCodeExecution:
+		return decodeStreamContent[StreamCodeExecutionContent](data)
+	case ContentTypeCodeExecutionResult:
+		return decodeStreamContent[StreamCodeExecutionResultContent](data)
+	case ContentTypeWebSearch:
+		return decodeStreamContent[StreamWebSearchContent](data)
+	case ContentTypeWebSearchResult:
+		return decodeStreamContent[StreamWebSearchResultContent](data)
+	case ContentTypeTodoList:
+		return decodeStreamContent[StreamTodoListContent](data)
+	case ContentTypeSkillLoad:
+		return decodeStreamContent[StreamSkillLoadedContent](data)
+	case ContentTypeQA:
+		return decodeStreamContent[StreamQAContent](data)
+	case ContentTypeQAResult:
+		return decodeStreamContent[StreamQAResultContent](data)
+	case ContentTypeApproval:
+		return decodeStreamContent[StreamApprovalContent](data)
+	default:
+		return nil, fmt.Errorf("unsupported content type: %s", base.SType())
+	}
+}
+
+func decodeStreamContent[T StreamContent](data []byte) (StreamContent, error) {
+	var c T
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
 /********************************************************/
 /*************** Session Content Structure **************/
 /********************************************************/
@@ -494,7 +694,10 @@ func (c BaseContent) Type() string {
 type TextContent struct {
 	BaseContent
 
-	Text string `json:"text"`
+	Text        string       `json:"text"`
+	Annotations []Annotation `json:"annotations,omitempty"`
+
+	buf *strings.Builder
 }
 
 func NewTextContent(id, text string) *TextContent {
@@ -504,13 +707,31 @@ func NewTextContent(id, text string) *TextContent {
 	}
 }
 
-func (c *TextContent) Append(delta string) { c.Text += delta }
+// Append 基于 strings.Builder 累积, Builder.String 不拷贝底层字节, 整体为线性开销
+func (c *TextContent) Append(delta string) {
+	// Text 被重新赋值后不再与 Builder 共享底层字节, 需重建; 共享时比较只检查指针
+	if c.buf == nil || c.buf.String() != c.Text {
+		c.buf = &strings.Builder{}
+		c.buf.WriteString(c.Text)
+	}
+	c.buf.WriteString(delta)
+	c.Text = c.buf.String()
+}
+
+func (c *TextContent) AddAnnotations(annotations ...Annotation) {
+	c.Annotations = append(c.Annotations, annotations...)
+}
 
 // 思考消息
 type ThinkingContent struct {
 	BaseContent
 
-	Text string `json:"text"`
+	Text      string `json:"text"`
+	Signature string `json:"signature,omitempty"`
+	// 加密的思考内容, 非空时 Text 为空
+	RedactedData string `json:"redacted_data,omitempty"`
+
+	buf *strings.Builder
 }
 
 func NewThinkingContent(id, text string) *ThinkingContent {
@@ -520,16 +741,27 @@ func NewThinkingContent(id, text string) *ThinkingContent {
 	}
 }
 
-func (c *ThinkingContent) Append(delta string) { c.Text += delta }
+// Append 基于 strings.Builder 累积, Builder.String 不拷贝底层字节, 整体为线性开销
+func (c *ThinkingContent) Append(delta string) {
+	// Text 被重新赋值后不再与 Builder 共享底层字节, 需重建; 共享时比较只检查指针
+	if c.buf == nil || c.buf.String() != c.Text {
+		c.buf = &strings.Builder{}
+		c.buf.WriteString(c.Text)
+	}
+	c.buf.WriteString(delta)
+	c.Text = c.buf.String()
+}
 
 // 工具调用
 type ToolCallContent struct {
 	BaseContent
 
-	ToolName   string `json:"tool_name"`
-	ToolArgs   string `json:"tool_args"`
-	ToolResult string `json:"tool_result,omitempty"`
-	Error      *Error `json:"error,omitempty"`
+	ToolName   string    `json:"tool_name"`
+	ToolCallID string    `json:"tool_call_id,omitempty"`
+	ToolArgs   string    `json:"tool_args"`
+	ToolResult string    `json:"tool_result,omitempty"`
+	Error      *Error    `json:"error,omitempty"`
+	Approval   *Approval `json:"approval,omitempty"`
 }
 
 func NewToolCallContent(toolName string) *ToolCallContent {
@@ -555,6 +787,11 @@ func NewFileContent(mimeType string, fileID string) *FileContent {
 	}
 }
 
+// 延迟物化的流式内容, 在内容结束或快照时统一生成最终字段
+type bufferedContent interface {
+	flush()
+}
+
 // 数据消息
 type DataContent struct {
 	BaseContent
@@ -562,6 +799,8 @@ type DataContent struct {
 	MimeType string `json:"mime_type"`
 	Data     string `json:"data"` // base64
 	Origin   []byte `json:"-"`
+
+	dirty bool
 }
 
 func NewDataContent(mimeType string, data []byte) *DataContent {
@@ -573,6 +812,19 @@ func NewDataContent(mimeType string, data []byte) *DataContent {
 	}
 }
 
+// Append 仅追加原始字节, Data 的 base64 编码延迟到 flush 时一次完成
+func (c *DataContent) Append(data []byte) {
+	c.Origin = append(c.Origin, data...)
+	c.dirty = true
+}
+
+func (c *DataContent) flush() {
+	if c.dirty {
+		c.Data = base64.StdEncoding.EncodeToString(c.Origin)
+		c.dirty = false
+	}
+}
+
 // 制品消息
 type ArtifactContent struct {
 	BaseContent
@@ -649,11 +901,12 @@ func NewCustomContent(raw string) *CustomContent {
 type MCPContent struct {
 	BaseContent
 
-	Server     string `json:"server"`
-	ToolName   string `json:"tool_name"`
-	ToolArgs   string `json:"tool_args"`
-	ToolResult string `json:"tool_result,omitempty"`
-	Error      *Error `json:"error,omitempty"`
+	Server     string    `json:"server"`
+	ToolName   string    `json:"tool_name"`
+	ToolArgs   string    `json:"tool_args"`
+	ToolResult string    `json:"tool_result,omitempty"`
+	Error      *Error    `json:"error,omitempty"`
+	Approval   *Approval `json:"approval,omitempty"`
 }
 
 func NewMCPContent(mcpName, toolName string) *MCPContent {
@@ -668,10 +921,11 @@ func NewMCPContent(mcpName, toolName string) *MCPContent {
 type CommandContent struct {
 	BaseContent
 
-	Command  string `json:"command"`
-	Result   string `json:"result,omitempty"`
-	ExitCode *int   `json:"exit_code,omitempty"`
-	Error    *Error `json:"error,omitempty"`
+	Command  string    `json:"command"`
+	Result   string    `json:"result,omitempty"`
+	ExitCode *int      `json:"exit_code,omitempty"`
+	Error    *Error    `json:"error,omitempty"`
+	Approval *Approval `json:"approval,omitempty"`
 }
 
 func NewCommandContent(command string) *CommandContent {
@@ -771,6 +1025,30 @@ func NewQAContent(qaid, qatype, qaname, message string, options map[string]any)
 	}
 }
 
+// UnmarshalJSON 兼容 id/name 等旧字段名
+func (c *QAContent) UnmarshalJSON(data []byte) error {
+	type alias QAContent
+	var raw struct {
+		alias
+
+		ID   string `json:"id,omitempty"`
+		Name string `json:"name,omitempty"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*c = QAContent(raw.alias)
+	if c.QAID == "" {
+		c.QAID = raw.ID
+	}
+	if c.QAName == "" {
+		c.QAName = raw.Name
+	}
+	c.ContentType = ContentTypeQA
+	return nil
+}
+
 func unmarshalContent(data []byte) (Content, error) {
 	var base BaseContent
 	if err := json.Unmarshal(data, &base); err != nil {
@@ -868,7 +1146,7 @@ func unmarshalContent(data []byte) (Content, error) {
 			return nil, err
 		}
 		return &c, nil
-	case ContentTypeQA:
+	case ContentTypeQA, ContentTypeQAResult:
 		var c QAContent
 		if err := json.Unmarshal(data, &c); err != nil {
 			return nil, err
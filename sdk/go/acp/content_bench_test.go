@@ -0,0 +1,141 @@
+package acp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const benchDeltaSize = 1 << 10
+
+var benchPayloadSizes = []int{1 << 20, 10 << 20}
+
+func TestCreatorAggregatesTextDeltas(t *testing.T) {
+	creator := NewCreator(nil)
+	blockID := uuid.NewString()
+	contentID := uuid.NewString()
+
+	require.NoError(t, creator.AddEvent(NewBlockStartEvent(blockID)))
+	require.NoError(t, creator.AddEvent(NewContentStartEvent(contentID, blockID)))
+	for _, delta := range []string{"hello", " ", "world"} {
+		require.NoError(t, creator.AddEvent(NewContentDeltaEvent(contentID, NewStreamTextContent(delta))))
+	}
+	require.NoError(t, creator.AddEvent(NewContentEndEvent(contentID)))
+
+	text, ok := creator.Blocks[0].Contents[0].(*TextContent)
+	require.True(t, ok)
+	assert.Equal(t, "hello world", text.Text)
+}
+
+func TestTextContentAppendAfterReassign(t *testing.T) {
+	text := NewTextContent("", "a")
+	text.Append("b")
+	text.Text = "x"
+	text.Append("y")
+	assert.Equal(t, "xy", text.Text)
+
+	// 重新赋值为等长字符串
+	text = NewTextContent("", "a")
+	text.Append("b")
+	text.Text = "xy"
+	text.Append("z")
+	assert.Equal(t, "xyz", text.Text)
+
+	thinking := NewThinkingContent("", "a")
+	thinking.Append("b")
+	thinking.Text = "xy"
+	thinking.Append("z")
+	assert.Equal(t, "xyz", thinking.Text)
+}
+
+func TestCreatorSnapshotMaterializesOpenContent(t *testing.T) {
+	creator := NewCreator(nil)
+	require.NoError(t, creator.AddEvent(NewBlockStartEvent("b1")))
+	require.NoError(t, creator.AddEvent(NewContentStartEvent("data", "b1")))
+	require.NoError(t, creator.AddEvent(NewContentDeltaEvent("data", NewStreamDataContent("image/png", []byte{0x01, 0x02}))))
+	require.NoError(t, creator.AddEvent(NewContentDeltaEvent("data", NewStreamDataContent("image/png", []byte{0x03}))))
+
+	snapshot, err := creator.Snapshot()
+	require.NoError(t, err)
+	require.Len(t, snapshot.Blocks[0].Contents, 1)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte{0x01, 0x02, 0x03}), snapshot.Blocks[0].Contents[0].(*DataContent).Data)
+	// 快照不影响后续聚合
+	assert.Empty(t, creator.Blocks[0].Contents)
+
+	require.NoError(t, creator.AddEvent(NewContentDeltaEvent("data", NewStreamDataContent("image/png", []byte{0x04}))))
+	require.NoError(t, creator.AddEvent(NewContentEndEvent("data")))
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte{0x01, 0x02, 0x03, 0x04}), creator.Blocks[0].Contents[0].(*DataContent).Data)
+}
+
+func TestCreatorAggregatesDataDeltas(t *testing.T) {
+	creator := NewCreator(nil)
+	blockID := uuid.NewString()
+	contentID := uuid.NewString()
+
+	require.NoError(t, creator.AddEvent(NewBlockStartEvent(blockID)))
+	require.NoError(t, creator.AddEvent(NewContentStartEvent(contentID, blockID)))
+	for _, delta := range [][]byte{{0x01, 0x02}, {0x03}, {0x04, 0x05, 0x06}} {
+		require.NoError(t, creator.AddEvent(NewContentDeltaEvent(contentID, NewStreamDataContent("image/png", delta))))
+	}
+	require.NoError(t, creator.AddEvent(NewContentEndEvent(contentID)))
+
+	data, ok := creator.Blocks[0].Contents[0].(*DataContent)
+	require.True(t, ok)
+	want := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06}
+	assert.Equal(t, want, data.Origin)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(want), data.Data)
+}
+
+func BenchmarkCreatorTextDeltas(b *testing.B) {
+	delta := strings.Repeat("x", benchDeltaSize)
+
+	for _, size := range benchPayloadSizes {
+		b.Run(fmt.Sprintf("%dMB", size>>20), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				creator := NewCreator(nil)
+				blockID := uuid.NewString()
+				contentID := uuid.NewString()
+
+				_ = creator.AddEvent(NewBlockStartEvent(blockID))
+				_ = creator.AddEvent(NewContentStartEvent(contentID, blockID))
+				for n := 0; n < size; n += benchDeltaSize {
+					_ = creator.AddEvent(NewContentDeltaEvent(contentID, NewStreamTextContent(delta)))
+				}
+				_ = creator.AddEvent(NewContentEndEvent(contentID))
+			}
+		})
+	}
+}
+
+func BenchmarkCreatorDataDeltas(b *testing.B) {
+	delta := bytes.Repeat([]byte{0xAB}, benchDeltaSize)
+
+	for _, size := range benchPayloadSizes {
+		b.Run(fmt.Sprintf("%dMB", size>>20), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				creator := NewCreator(nil)
+				blockID := uuid.NewString()
+				contentID := uuid.NewString()
+
+				_ = creator.AddEvent(NewBlockStartEvent(blockID))
+				_ = creator.AddEvent(NewContentStartEvent(contentID, blockID))
+				for n := 0; n < size; n += benchDeltaSize {
+					_ = creator.AddEvent(NewContentDeltaEvent(contentID, NewStreamDataContent("audio/wav", delta)))
+				}
+				_ = creator.AddEvent(NewContentEndEvent(contentID))
+			}
+		})
+	}
+}
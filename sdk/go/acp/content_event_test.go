@@ -139,6 +139,33 @@ func TestCreatorAggregatesQAResultContentIntoQAContent(t *testing.T) {
 	assert.Equal(t, options, qa.Options)
 }
 
+func TestCreatorAggregatesApprovalIntoCallContent(t *testing.T) {
+	creator := NewCreator(nil)
+	blockID := uuid.NewString()
+
+	require.NoError(t, creator.AddEvent(NewBlockStartEvent(blockID)))
+	require.NoError(t, creator.AddEvent(NewContentStartEvent("mcp", blockID)))
+	require.NoError(t, creator.AddEvent(NewContentDeltaEvent("mcp", NewStreamMCPCallContent("fs", "write"))))
+	require.NoError(t, creator.AddEvent(NewContentDeltaEvent("mcp", NewStreamMCPArgsContent(`{"path":"/etc"}`))))
+	require.NoError(t, creator.AddEvent(NewContentDeltaEvent("mcp", NewStreamApprovalContent(Approval{Decision: ApprovalEdit, Args: `{"path":"/tmp"}`}))))
+	require.NoError(t, creator.AddEvent(NewContentEndEvent("mcp")))
+
+	require.NoError(t, creator.AddEvent(NewContentStartEvent("text", blockID)))
+	require.NoError(t, creator.AddEvent(NewContentDeltaEvent("text", NewStreamTextContent("hi"))))
+	assert.ErrorIs(t, creator.AddEvent(NewContentDeltaEvent("text", NewStreamApprovalContent(Approval{Decision: ApprovalApprove}))), ErrContentEvent)
+
+	mcp, ok := creator.Blocks[0].Contents[0].(*MCPContent)
+	require.True(t, ok)
+	assert.Equal(t, `{"path":"/tmp"}`, mcp.ToolArgs)
+	assert.Equal(t, &Approval{Decision: ApprovalEdit, Args: `{"path":"/tmp"}`, OriginalArgs: `{"path":"/etc"}`}, mcp.Approval)
+
+	data, err := json.Marshal(NewContentDeltaEvent("mcp", NewStreamApprovalContent(Approval{Decision: ApprovalDeny, Reason: "no"})))
+	require.NoError(t, err)
+	decoded, err := UnmarshalEvent(data)
+	require.NoError(t, err)
+	assert.Equal(t, Approval{Decision: ApprovalDeny, Reason: "no"}, decoded.(ContentDeltaEvent).Content.(StreamApprovalContent).Approval)
+}
+
 func TestUnmarshalMessageWithInteractionAndCustom(t *testing.T) {
 	payload := `{
 		"id": "m1",
@@ -292,3 +319,53 @@ func TestUnmarshalMessageWithQAResultContent(t *testing.T) {
 	require.True(t, ok)
 	assert.Equal(t, map[string]any{"choice": "A"}, qa.Answer)
 }
+
+func TestCreatorAggregatesTextAnnotations(t *testing.T) {
+	creator := NewCreator(nil)
+	blockID := uuid.NewString()
+	contentID := uuid.NewString()
+	result := WebSearchResult{Title: "Go", Url: "https://go.dev", Snippet: "The Go language"}
+
+	require.NoError(t, creator.AddEvent(NewBlockStartEvent(blockID)))
+	require.NoError(t, creator.AddEvent(NewContentStartEvent(contentID, blockID)))
+	require.NoError(t, creator.AddEvent(NewContentDeltaEvent(contentID, NewStreamTextContent("Go is fast."))))
+	require.NoError(t, creator.AddEvent(NewContentDeltaEvent(contentID, NewStreamTextAnnotationContent(NewCitationAnnotation(0, 2, 0, result)))))
+	require.NoError(t, creator.AddEvent(NewContentDeltaEvent(contentID, NewStreamTextAnnotationContent(NewFileAnnotation(3, 10, "application/pdf", "file_1")))))
+	require.NoError(t, creator.AddEvent(NewContentEndEvent(contentID)))
+
+	require.Len(t, creator.Blocks, 1)
+	require.Len(t, creator.Blocks[0].Contents, 1)
+
+	text, ok := creator.Blocks[0].Contents[0].(*TextContent)
+	require.True(t, ok)
+	assert.Equal(t, "Go is fast.", text.Text)
+	require.Len(t, text.Annotations, 2)
+	assert.Equal(t, AnnotationTypeCitation, text.Annotations[0].Type)
+	require.NotNil(t, text.Annotations[0].CitationIndex)
+	assert.Equal(t, 0, *text.Annotations[0].CitationIndex)
+	assert.Equal(t, "https://go.dev", text.Annotations[0].Url)
+	assert.Equal(t, AnnotationTypeFile, text.Annotations[1].Type)
+	assert.Equal(t, "file_1", text.Annotations[1].FileID)
+
+	data, err := json.Marshal(creator.Message)
+	require.NoError(t, err)
+
+	var msg Message
+	require.NoError(t, json.Unmarshal(data, &msg))
+	decoded, ok := msg.Blocks[0].Contents[0].(*TextContent)
+	require.True(t, ok)
+	assert.Equal(t, text.Annotations, decoded.Annotations)
+}
+
+func TestCreatorTextAnnotationRejectsNonText(t *testing.T) {
+	creator := NewCreator(nil)
+	blockID := uuid.NewString()
+	contentID := uuid.NewString()
+
+	require.NoError(t, creator.AddEvent(NewBlockStartEvent(blockID)))
+	require.NoError(t, creator.AddEvent(NewContentStartEvent(contentID, blockID)))
+	require.NoError(t, creator.AddEvent(NewContentDeltaEvent(contentID, NewStreamThinkingContent("hmm"))))
+
+	err := creator.AddEvent(NewContentDeltaEvent(contentID, NewStreamTextAnnotationContent(NewArtifactAnnotation(0, 1, "text/html", "art_1"))))
+	assert.ErrorIs(t, err, ErrContentEvent)
+}
@@ -0,0 +1,156 @@
+package acp
+
+import (
+	"context"
+	"errors"
+)
+
+// 错误码
+const (
+	ErrorCodeInternal      = "internal"
+	ErrorCodeInvalidInput  = "invalid_input"
+	ErrorCodeTimeout       = "timeout"
+	ErrorCodeCanceled      = "canceled"
+	ErrorCodeRateLimited   = "rate_limited"
+	ErrorCodeUnavailable   = "unavailable"
+	ErrorCodeLimitExceeded = "limit_exceeded"
+	ErrorCodeToolFailed    = "tool_failed"
+	ErrorCodeSubagent      = "subagent_failed"
+)
+
+// 错误原因链的最大深度
+const maxErrorCauseDepth = 8
+
+// 结构化运行错误, BlockID/ContentID 为空表示作用于整个运行
+type RunError struct {
+	Code      string         `json:"code"`
+	Message   string         `json:"message"`
+	Retryable bool           `json:"retryable,omitempty"`
+	BlockID   string         `json:"block_id,omitempty"`
+	ContentID string         `json:"content_id,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+	Cause     *RunError      `json:"cause,omitempty"`
+}
+
+func NewRunError(code, message string) *RunError {
+	return &RunError{Code: code, Message: message}
+}
+
+func (e *RunError) Error() string {
+	if e.Code == "" {
+		return e.Message
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *RunError) Unwrap() error {
+	if e.Cause == nil {
+		return nil
+	}
+	return e.Cause
+}
+
+func (e *RunError) ErrorCode() string {
+	return e.Code
+}
+
+func (e *RunError) IsRetryable() bool {
+	return e.Retryable
+}
+
+func (e *RunError) ErrorDetails() map[string]any {
+	return e.Details
+}
+
+func (e *RunError) ErrorScope() (string, string) {
+	return e.BlockID, e.ContentID
+}
+
+// ContentError 转换为内容级错误
+func (e *RunError) ContentError() *Error {
+	return &Error{Type: e.Code, Message: e.Message}
+}
+
+// 可映射为 RunError 的错误, 通过 errors.As 在错误链中查找
+type CodedError interface {
+	error
+	ErrorCode() string
+	IsRetryable() bool
+}
+
+// 可选: 附带错误详情
+type detailedError interface {
+	ErrorDetails() map[string]any
+}
+
+// 可选: 指明错误所属的区块与内容
+type scopedError interface {
+	ErrorScope() (blockID, contentID string)
+}
+
+// ToRunError 将 Go 错误映射为 RunError:
+//   - *RunError 直接复制
+//   - 错误链中的 CodedError 决定错误码与是否可重试
+//   - context.DeadlineExceeded 映射为可重试的 timeout, context.Canceled 映射为 canceled
+//   - 其余为 internal
+//
+// 包装层通过 Cause 逐层保留
+func ToRunError(err error) *RunError {
+	return toRunError(err, 0)
+}
+
+func toRunError(err error, depth int) *RunError {
+	if err == nil {
+		return nil
+	}
+
+	if re, ok := err.(*RunError); ok {
+		return re.clone()
+	}
+
+	out := &RunError{Code: ErrorCodeInternal, Message: err.Error()}
+	var coded CodedError
+	switch {
+	case errors.As(err, &coded):
+		out.Code = coded.ErrorCode()
+		out.Retryable = coded.IsRetryable()
+		if d, ok := coded.(detailedError); ok {
+			out.Details = d.ErrorDetails()
+		}
+	case errors.Is(err, context.DeadlineExceeded):
+		out.Code, out.Retryable = ErrorCodeTimeout, true
+	case errors.Is(err, context.Canceled):
+		out.Code = ErrorCodeCanceled
+	}
+
+	var scoped scopedError
+	if errors.As(err, &scoped) {
+		out.BlockID, out.ContentID = scoped.ErrorScope()
+	}
+
+	if depth < maxErrorCauseDepth {
+		out.Cause = toRunError(unwrapFirst(err), depth+1)
+	}
+	return out
+}
+
+func unwrapFirst(err error) error {
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		return u.Unwrap()
+	case interface{ Unwrap() []error }:
+		if errs := u.Unwrap(); len(errs) > 0 {
+			return errs[0]
+		}
+	}
+	return nil
+}
+
+func (e *RunError) clone() *RunError {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Cause = e.Cause.clone()
+	return &c
+}
@@ -0,0 +1,141 @@
+package acp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type upstreamError struct {
+	status int
+}
+
+func (e *upstreamError) Error() string { return fmt.Sprintf("upstream status %d", e.status) }
+
+func (e *upstreamError) ErrorCode() string { return ErrorCodeUnavailable }
+
+func (e *upstreamError) IsRetryable() bool { return e.status >= 500 }
+
+func (e *upstreamError) ErrorDetails() map[string]any { return map[string]any{"status": e.status} }
+
+func TestToRunError(t *testing.T) {
+	assert.Nil(t, ToRunError(nil))
+
+	err := fmt.Errorf("call model: %w", &upstreamError{status: 503})
+	re := ToRunError(err)
+	assert.Equal(t, ErrorCodeUnavailable, re.Code)
+	assert.Equal(t, "call model: upstream status 503", re.Message)
+	assert.True(t, re.Retryable)
+	assert.Equal(t, map[string]any{"status": 503}, re.Details)
+	require.NotNil(t, re.Cause)
+	assert.Equal(t, "upstream status 503", re.Cause.Message)
+	assert.Nil(t, re.Cause.Cause)
+
+	re = ToRunError(fmt.Errorf("wait: %w", context.DeadlineExceeded))
+	assert.Equal(t, ErrorCodeTimeout, re.Code)
+	assert.True(t, re.Retryable)
+
+	re = ToRunError(context.Canceled)
+	assert.Equal(t, ErrorCodeCanceled, re.Code)
+	assert.False(t, re.Retryable)
+
+	re = ToRunError(errors.New("boom"))
+	assert.Equal(t, &RunError{Code: ErrorCodeInternal, Message: "boom"}, re)
+}
+
+func TestToRunErrorScoped(t *testing.T) {
+	err := fmt.Errorf("stream: %w", &LimitError{Limit: LimitContentBytes, Max: 10, BlockID: "b1", ContentID: "c1"})
+	re := ToRunError(err)
+	assert.Equal(t, ErrorCodeLimitExceeded, re.Code)
+	assert.False(t, re.Retryable)
+	assert.Equal(t, "b1", re.BlockID)
+	assert.Equal(t, "c1", re.ContentID)
+	assert.Equal(t, map[string]any{"limit": LimitContentBytes, "max": int64(10)}, re.Details)
+
+	orig := &RunError{Code: ErrorCodeToolFailed, Message: "tool", Cause: NewRunError(ErrorCodeTimeout, "slow")}
+	re = ToRunError(orig)
+	assert.Equal(t, orig, re)
+	assert.NotSame(t, orig.Cause, re.Cause)
+
+	// RunError 可作为 Go 错误使用
+	wrapped := fmt.Errorf("agent: %w", orig)
+	var target *RunError
+	require.True(t, errors.As(wrapped, &target))
+	assert.Equal(t, "tool_failed: tool", target.Error())
+	assert.Equal(t, ErrorCodeToolFailed, ToRunError(wrapped).Code)
+}
+
+func TestCreatorStructuredErrors(t *testing.T) {
+	m := NewCreator(nil)
+	require.NoError(t, m.AddEvent(NewRunStartedEvent("s1", "r1")))
+	require.NoError(t, m.AddEvent(NewBlockStartEvent("b1")))
+	require.NoError(t, m.AddEvent(NewBlockErrorEvent("b1", &RunError{Code: ErrorCodeToolFailed, Message: "search failed", ContentID: "c1"})))
+	assert.ErrorIs(t, m.AddEvent(NewBlockErrorEvent("missing", NewRunError(ErrorCodeInternal, "x"))), ErrBlockEvent)
+	require.NoError(t, m.AddEvent(NewBlockEndEvent("b1", nil)))
+	require.NoError(t, m.AddEvent(NewRunErrorEventFrom("r1", fmt.Errorf("model: %w", &upstreamError{status: 429}))))
+
+	assert.Equal(t, []RunError{{Code: ErrorCodeToolFailed, Message: "search failed", BlockID: "b1", ContentID: "c1"}}, m.Blocks[0].Errors)
+	assert.Equal(t, "model: upstream status 429", m.Errors)
+	require.NotNil(t, m.RunError)
+	assert.Equal(t, ErrorCodeUnavailable, m.RunError.Code)
+	assert.False(t, m.RunError.Retryable)
+
+	data, err := json.Marshal(m.Message)
+	require.NoError(t, err)
+	var decoded Message
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, m.Blocks[0].Errors, decoded.Blocks[0].Errors)
+	assert.Equal(t, m.RunError.Code, decoded.RunError.Code)
+}
+
+func TestStructuredErrorEventRoundTrip(t *testing.T) {
+	for _, e := range []Event{
+		NewBlockErrorEvent("b1", &RunError{Code: ErrorCodeRateLimited, Message: "slow down", Retryable: true, Details: map[string]any{"retry_after": 2.0}}),
+		NewRunErrorEventFrom("r1", &RunError{Code: ErrorCodeInternal, Message: "boom", Cause: NewRunError(ErrorCodeTimeout, "slow")}),
+	} {
+		data, err := json.Marshal(e)
+		require.NoError(t, err)
+		decoded, err := UnmarshalEvent(data)
+		require.NoError(t, err)
+		assert.Equal(t, e, decoded)
+	}
+
+	// 旧客户端只读取 error 字段
+	evt := NewRunErrorEventFrom("r1", errors.New("boom"))
+	assert.Equal(t, "boom", evt.Error)
+	assert.Equal(t, ErrorCodeInternal, evt.Info.Code)
+}
+
+func TestSubagentStructuredErrors(t *testing.T) {
+	parent := NewCreator(nil)
+	require.NoError(t, parent.AddEvent(NewRunStartedEvent("s1", "r1")))
+
+	p := NewSubagentProxy(parent, "sub")
+	require.NoError(t, p.AddEvent(NewRunStartedEvent("s2", "r2")))
+	require.NoError(t, p.AddEvent(NewBlockStartEvent("b1")))
+	require.NoError(t, p.AddEvent(NewBlockErrorEvent("b1", &RunError{Code: ErrorCodeToolFailed, Message: "x", ContentID: "c1"})))
+	require.NoError(t, p.AddEvent(NewBlockEndEvent("b1", nil)))
+
+	err := p.AddEvent(NewRunErrorEventFrom("r2", &upstreamError{status: 502}))
+	assert.ErrorIs(t, err, ErrSubagentRun)
+	var subErr *SubagentError
+	require.ErrorAs(t, err, &subErr)
+	assert.Equal(t, "r2", subErr.RunID)
+	re := ToRunError(err)
+	assert.Equal(t, ErrorCodeSubagent, re.Code)
+	assert.True(t, re.Retryable)
+	assert.Equal(t, map[string]any{"run_id": "r2"}, re.Details)
+	require.NotNil(t, re.Cause)
+	assert.Equal(t, ErrorCodeUnavailable, re.Cause.Code)
+	assert.True(t, re.Cause.Retryable)
+
+	blockErrs := parent.Blocks[1].Errors
+	require.Len(t, blockErrs, 1)
+	assert.Equal(t, p.namespaced("b1"), blockErrs[0].BlockID)
+	assert.Equal(t, p.namespaced("c1"), blockErrs[0].ContentID)
+}
@@ -1,6 +1,8 @@
 package acp
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -12,9 +14,11 @@ const (
 	EventTypeRunError     EventType = "run_error"
 	EventTypeBlockStart   EventType = "block_start"
 	EventTypeBlockEnd     EventType = "block_end"
+	EventTypeBlockError   EventType = "block_error"
 	EventTypeContentStart EventType = "content_start"
 	EventTypeContentDelta EventType = "content_delta"
 	EventTypeContentEnd   EventType = "content_end"
+	EventTypeNotice       EventType = "notice"
 )
 
 type Event interface {
@@ -76,6 +80,8 @@ type RunErrorEvent struct {
 
 	RunID string `json:"run_id"`
 	Error string `json:"error"`
+	// 结构化错误, Error 保留其消息文本以兼容旧客户端
+	Info *RunError `json:"info,omitempty"`
 }
 
 func NewRunErrorEvent(runID string, message string) RunErrorEvent {
@@ -86,6 +92,15 @@ func NewRunErrorEvent(runID string, message string) RunErrorEvent {
 	}
 }
 
+// NewRunErrorEventFrom 通过 ToRunError 映射 err 生成结构化的运行错误事件
+func NewRunErrorEventFrom(runID string, err error) RunErrorEvent {
+	evt := NewRunErrorEvent(runID, "")
+	if evt.Info = ToRunError(err); evt.Info != nil {
+		evt.Error = evt.Info.Message
+	}
+	return evt
+}
+
 // 区块事件
 type BlockOption func(*BlockStartEvent)
 
@@ -135,11 +150,6 @@ type BlockEndEvent struct {
 	Usage   *Usage `json:"usage,omitempty"`
 }
 
-type Usage struct {
-	PromptTokens     int64 `json:"prompt_tokens"`
-	CompletionTokens int64 `json:"completion_tokens"`
-}
-
 func NewBlockEndEvent(blockID string, usage *Usage) BlockEndEvent {
 	return BlockEndEvent{
 		BaseEvent: NewBaseEvent(EventTypeBlockEnd),
@@ -148,6 +158,27 @@ func NewBlockEndEvent(blockID string, usage *Usage) BlockEndEvent {
 	}
 }
 
+// 区块错误事件, 记录区块内的非致命错误, 不结束区块与运行
+type BlockErrorEvent struct {
+	BaseEvent
+
+	BlockID string   `json:"block_id"`
+	Error   RunError `json:"error"`
+}
+
+// NewBlockErrorEvent 的 err.BlockID 会被设为 blockID
+func NewBlockErrorEvent(blockID string, err *RunError) BlockErrorEvent {
+	evt := BlockErrorEvent{
+		BaseEvent: NewBaseEvent(EventTypeBlockError),
+		BlockID:   blockID,
+	}
+	if err != nil {
+		evt.Error = *err.clone()
+	}
+	evt.Error.BlockID = blockID
+	return evt
+}
+
 // 消息事件
 type ContentStartEvent struct {
 	BaseEvent
@@ -191,3 +222,82 @@ func NewContentDeltaEvent(contentID string, content StreamContent) ContentDeltaE
 		Content:   content,
 	}
 }
+
+// 提示事件, 告知客户端运行中的非致命情况, 如触发限额
+type Notice struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	BlockID   string `json:"block_id,omitempty"`
+	ContentID string `json:"content_id,omitempty"`
+}
+
+type NoticeEvent struct {
+	BaseEvent
+	Notice
+}
+
+func NewNoticeEvent(notice Notice) NoticeEvent {
+	return NoticeEvent{
+		BaseEvent: NewBaseEvent(EventTypeNotice),
+		Notice:    notice,
+	}
+}
+
+// UnmarshalEvent 按事件类型反序列化 JSON 事件
+func UnmarshalEvent(data []byte) (Event, error) {
+	var base BaseEvent
+	if err := json.Unmarshal(data, &base); err != nil {
+		return nil, err
+	}
+
+	switch base.Type() {
+	case EventTypeRunStarted:
+		return decodeEvent[RunStartedEvent](data)
+	case EventTypeRunFinished:
+		return decodeEvent[RunFinishedEvent](data)
+	case EventTypeRunError:
+		return decodeEvent[RunErrorEvent](data)
+	case EventTypeBlockStart:
+		return decodeEvent[BlockStartEvent](data)
+	case EventTypeBlockEnd:
+		return decodeEvent[BlockEndEvent](data)
+	case EventTypeBlockError:
+		return decodeEvent[BlockErrorEvent](data)
+	case EventTypeContentStart:
+		return decodeEvent[ContentStartEvent](data)
+	case EventTypeContentEnd:
+		return decodeEvent[ContentEndEvent](data)
+	case EventTypeNotice:
+		return decodeEvent[NoticeEvent](data)
+	case EventTypeContentDelta:
+		var raw struct {
+			BaseEvent
+
+			ContentID string          `json:"content_id"`
+			Content   json.RawMessage `json:"content"`
+		}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+
+		content, err := unmarshalStreamContent(raw.Content)
+		if err != nil {
+			return nil, err
+		}
+		return ContentDeltaEvent{
+			BaseEvent: raw.BaseEvent,
+			ContentID: raw.ContentID,
+			Content:   content,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupport event: %s", base.Type())
+	}
+}
+
+func decodeEvent[T Event](data []byte) (Event, error) {
+	var e T
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
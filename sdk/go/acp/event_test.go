@@ -0,0 +1,42 @@
+package acp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalEventRoundTrip(t *testing.T) {
+	events := []Event{
+		NewRunStartedEvent("s1", "r1"),
+		NewBlockStartEvent("b1", WithIsSubagent(), WithMetadata(map[string]any{"k": "v"})),
+		NewContentStartEvent("c1", "b1"),
+		NewContentDeltaEvent("c1", NewStreamTextContent("hi")),
+		NewContentDeltaEvent("c1", NewStreamToolErrorContent(&Error{Type: "x", Message: "y"})),
+		NewContentDeltaEvent("c1", NewStreamCommandResultContent("ok", 0)),
+		NewContentDeltaEvent("c1", NewStreamQAResultContent(map[string]any{"a": "b"})),
+		NewContentEndEvent("c1"),
+		NewBlockEndEvent("b1", &Usage{PromptTokens: 1, Model: "m"}),
+		NewRunErrorEvent("r1", "boom"),
+		NewRunFinishedEvent("r1"),
+	}
+
+	for _, e := range events {
+		data, err := json.Marshal(e)
+		require.NoError(t, err)
+
+		decoded, err := UnmarshalEvent(data)
+		require.NoError(t, err)
+		assert.Equal(t, e, decoded)
+	}
+}
+
+func TestUnmarshalEventUnknown(t *testing.T) {
+	_, err := UnmarshalEvent([]byte(`{"type":"nope"}`))
+	assert.Error(t, err)
+
+	_, err = UnmarshalEvent([]byte(`{"type":"content_delta","content":{"type":"nope"}}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,42 @@
+package acp
+
+import "context"
+
+// 事件处理函数
+type Handler func(ctx context.Context, e Event) error
+
+// 事件拦截器, 在聚合和发送之前执行.
+// 调用 next 继续处理, 可替换传入的事件; 不调用 next 即丢弃事件; 多次调用 next 可注入额外事件.
+// 注入事件应通过 next 而不是 Creator.AddEvent, 否则会重新进入整条拦截链.
+type Interceptor func(ctx context.Context, e Event, next Handler) error
+
+type creatorKey struct{}
+
+// CreatorFrom 返回正在处理事件的 Creator. 拦截器在 next 成功返回后可读取聚合结果,
+// 以此观察已被接受的事件; 被忽略的事件(如重复的 RunStarted)同样会成功返回.
+func CreatorFrom(ctx context.Context) (*Creator, bool) {
+	m, ok := ctx.Value(creatorKey{}).(*Creator)
+	return m, ok
+}
+
+// WithInterceptors 追加拦截器, 先注册的在外层, 即按注册顺序看到事件
+func WithInterceptors(interceptors ...Interceptor) CreatorOption {
+	return func(m *Creator) { m.interceptors = append(m.interceptors, interceptors...) }
+}
+
+// ChainInterceptors 将多个拦截器组合为一个, 顺序与 WithInterceptors 相同
+func ChainInterceptors(interceptors ...Interceptor) Interceptor {
+	return func(ctx context.Context, e Event, next Handler) error {
+		return chain(interceptors, next)(ctx, e)
+	}
+}
+
+func chain(interceptors []Interceptor, h Handler) Handler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], h
+		h = func(ctx context.Context, e Event) error {
+			return interceptor(ctx, e, next)
+		}
+	}
+	return h
+}
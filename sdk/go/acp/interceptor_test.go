@@ -0,0 +1,130 @@
+package acp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterceptorOrder(t *testing.T) {
+	var trace []string
+	record := func(name string) Interceptor {
+		return func(ctx context.Context, e Event, next Handler) error {
+			trace = append(trace, name+">"+string(e.Type()))
+			err := next(ctx, e)
+			trace = append(trace, name+"<")
+			return err
+		}
+	}
+
+	m := NewCreator(nil,
+		WithInterceptors(record("a"), record("b")),
+		WithInterceptors(record("c")),
+	)
+	require.NoError(t, m.AddEvent(NewRunStartedEvent("s1", "r1")))
+
+	assert.Equal(t, []string{"a>run_started", "b>run_started", "c>run_started", "c<", "b<", "a<"}, trace)
+}
+
+func TestInterceptorTransformDropInject(t *testing.T) {
+	upper := func(ctx context.Context, e Event, next Handler) error {
+		if evt, ok := e.(ContentDeltaEvent); ok {
+			if text, ok := evt.Content.(StreamTextContent); ok {
+				evt.Content = NewStreamTextContent(strings.ToUpper(text.Delta))
+				return next(ctx, evt)
+			}
+		}
+		return next(ctx, e)
+	}
+	dropThinking := func(ctx context.Context, e Event, next Handler) error {
+		if evt, ok := e.(ContentDeltaEvent); ok && evt.Content.SType() == ContentTypeThinking {
+			return nil
+		}
+		return next(ctx, e)
+	}
+	// 在每个区块开头注入一段提示文本
+	inject := func(ctx context.Context, e Event, next Handler) error {
+		if err := next(ctx, e); err != nil {
+			return err
+		}
+		if evt, ok := e.(BlockStartEvent); ok {
+			id := evt.BlockID + "-notice"
+			for _, injected := range []Event{
+				NewContentStartEvent(id, evt.BlockID),
+				NewContentDeltaEvent(id, NewStreamTextContent("notice")),
+				NewContentEndEvent(id),
+			} {
+				if err := next(ctx, injected); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	var buf bytes.Buffer
+	m := NewCreator(NewSSEWriter(&buf), WithInterceptors(inject, dropThinking, upper))
+	require.NoError(t, m.AddEvent(NewRunStartedEvent("s1", "r1")))
+	require.NoError(t, m.AddEvent(NewBlockStartEvent("b1")))
+	require.NoError(t, m.AddEvent(NewContentStartEvent("c1", "b1")))
+	require.NoError(t, m.AddEvent(NewContentDeltaEvent("c1", NewStreamThinkingContent("secret"))))
+	require.NoError(t, m.AddEvent(NewContentEndEvent("c1")))
+	require.NoError(t, m.AddEvent(NewContentStartEvent("c2", "b1")))
+	require.NoError(t, m.AddEvent(NewContentDeltaEvent("c2", NewStreamTextContent("hello"))))
+	require.NoError(t, m.AddEvent(NewContentEndEvent("c2")))
+
+	// 丢弃增量后内容为空, 不写入区块
+	require.Len(t, m.Blocks[0].Contents, 2)
+	assert.Equal(t, "NOTICE", m.Blocks[0].Contents[0].(*TextContent).Text)
+	assert.Equal(t, "HELLO", m.Blocks[0].Contents[1].(*TextContent).Text)
+
+	out := buf.String()
+	assert.NotContains(t, out, "secret")
+	assert.Contains(t, out, "HELLO")
+	assert.Contains(t, out, "NOTICE")
+}
+
+type ctxKey struct{}
+
+func TestInterceptorContextAndError(t *testing.T) {
+	errBlocked := errors.New("blocked")
+	var seen any
+	m := NewCreator(nil, WithInterceptors(
+		func(ctx context.Context, e Event, next Handler) error {
+			seen = ctx.Value(ctxKey{})
+			return next(ctx, e)
+		},
+		func(ctx context.Context, e Event, next Handler) error {
+			if e.Type() == EventTypeBlockStart {
+				return errBlocked
+			}
+			return next(ctx, e)
+		},
+	))
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "v")
+	require.NoError(t, m.AddEventContext(ctx, NewRunStartedEvent("s1", "r1")))
+	assert.Equal(t, "v", seen)
+
+	assert.ErrorIs(t, m.AddEvent(NewBlockStartEvent("b1")), errBlocked)
+	assert.Empty(t, m.Blocks)
+}
+
+func TestChainInterceptors(t *testing.T) {
+	var order []int
+	step := func(i int) Interceptor {
+		return func(ctx context.Context, e Event, next Handler) error {
+			order = append(order, i)
+			return next(ctx, e)
+		}
+	}
+
+	m := NewCreator(nil, WithInterceptors(step(1), ChainInterceptors(step(2), step(3)), step(4)))
+	require.NoError(t, m.AddEvent(NewRunStartedEvent("s1", "r1")))
+	assert.Equal(t, []int{1, 2, 3, 4}, order)
+}
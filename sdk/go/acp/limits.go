@@ -0,0 +1,411 @@
+package acp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// 限额名称, 同时用作提示事件的 Code
+const (
+	LimitContentBytes  = "content_bytes"
+	LimitBlockContents = "block_contents"
+	LimitBlocks        = "blocks"
+	LimitMessageBytes  = "message_bytes"
+	LimitEventRate     = "event_rate"
+)
+
+// 超出限额的错误, 可用 errors.Is(err, ErrLimitExceeded) 或 errors.As 判断
+type LimitError struct {
+	Limit     string
+	Max       int64
+	BlockID   string
+	ContentID string
+}
+
+func (e *LimitError) Error() string {
+	target := ""
+	switch {
+	case e.ContentID != "":
+		target = " (content " + e.ContentID + ")"
+	case e.BlockID != "":
+		target = " (block " + e.BlockID + ")"
+	}
+	return fmt.Sprintf("%s: %s > %d%s", ErrLimitExceeded, e.Limit, e.Max, target)
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+func (e *LimitError) ErrorCode() string {
+	return ErrorCodeLimitExceeded
+}
+
+// IsRetryable 仅速率限额可重试
+func (e *LimitError) IsRetryable() bool {
+	return e.Limit == LimitEventRate
+}
+
+func (e *LimitError) ErrorDetails() map[string]any {
+	return map[string]any{"limit": e.Limit, "max": e.Max}
+}
+
+func (e *LimitError) ErrorScope() (string, string) {
+	return e.BlockID, e.ContentID
+}
+
+type LimitAction int
+
+const (
+	// LimitTruncate 截断或丢弃超出部分并继续运行.
+	// 工具参数为 JSON, 截断后无法解析, 超限时仍返回 *LimitError
+	LimitTruncate LimitAction = iota
+	// LimitFail 返回 *LimitError
+	LimitFail
+)
+
+// 默认截断标记
+const DefaultTruncateMarker = "\n...[truncated]"
+
+// Creator 的大小和速率限额, 零值表示不限制.
+// 字节数按增量中的文本(数据内容按 base64)长度计算.
+type Limits struct {
+	MaxContentBytes    int64
+	MaxBlockContents   int
+	MaxBlocks          int
+	MaxMessageBytes    int64
+	MaxEventsPerSecond int
+
+	Action LimitAction
+	// 截断文本类内容时追加的标记, 为空时使用 DefaultTruncateMarker
+	Marker string
+}
+
+// WithLimits 以拦截器实现限额, 与 WithInterceptors 按注册顺序排列.
+// 每次触发限额都会发送一个 NoticeEvent; 截断模式下同一对象只提示一次.
+// 限额状态按 Creator 独立创建, 同一个选项可用于多个 Creator.
+func WithLimits(limits Limits) CreatorOption {
+	return func(m *Creator) { WithInterceptors(newLimiter(limits).intercept)(m) }
+}
+
+type limitedContent struct {
+	blockID   string
+	bytes     int64
+	truncated bool
+}
+
+type limiter struct {
+	limits Limits
+	now    func() time.Time
+
+	blocks        int
+	blockContents map[string]int
+	droppedBlocks map[string]bool
+	contents      map[string]*limitedContent
+	dropped       map[string]bool // 被丢弃的内容
+	messageBytes  int64
+	messageFull   bool
+	notified      map[string]bool
+
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(limits Limits) *limiter {
+	if limits.Marker == "" {
+		limits.Marker = DefaultTruncateMarker
+	}
+	return &limiter{
+		limits:        limits,
+		now:           time.Now,
+		blockContents: make(map[string]int),
+		droppedBlocks: make(map[string]bool),
+		contents:      make(map[string]*limitedContent),
+		dropped:       make(map[string]bool),
+		notified:      make(map[string]bool),
+		tokens:        float64(limits.MaxEventsPerSecond),
+	}
+}
+
+func (l *limiter) intercept(ctx context.Context, e Event, next Handler) error {
+	if !l.allow() {
+		err := &LimitError{Limit: LimitEventRate, Max: int64(l.limits.MaxEventsPerSecond)}
+		if l.limits.Action == LimitFail {
+			return l.fail(ctx, next, err)
+		}
+		// 截断模式下结构事件照常通过, 超速的文本增量截断其所属内容
+		if evt, ok := e.(ContentDeltaEvent); ok {
+			c, known := l.contents[evt.ContentID]
+			if _, payload := payloadOf(evt.Content); known && payload {
+				if c.truncated {
+					return nil
+				}
+				err.BlockID, err.ContentID = c.blockID, evt.ContentID
+				return l.truncate(ctx, next, evt, c, "", err)
+			}
+		}
+	}
+
+	switch evt := e.(type) {
+	case BlockStartEvent:
+		if l.droppedBlocks[evt.ParentBlockID] {
+			l.droppedBlocks[evt.BlockID] = true
+			return nil
+		}
+		if l.limits.MaxBlocks > 0 && l.blocks >= l.limits.MaxBlocks {
+			err := &LimitError{Limit: LimitBlocks, Max: int64(l.limits.MaxBlocks), BlockID: evt.BlockID}
+			if l.limits.Action == LimitFail {
+				return l.fail(ctx, next, err)
+			}
+			l.droppedBlocks[evt.BlockID] = true
+			return l.notice(ctx, next, LimitBlocks, err)
+		}
+		l.blocks++
+		return next(ctx, e)
+
+	case BlockEndEvent:
+		if l.droppedBlocks[evt.BlockID] {
+			delete(l.droppedBlocks, evt.BlockID)
+			return nil
+		}
+		delete(l.blockContents, evt.BlockID)
+		return next(ctx, e)
+
+	case BlockErrorEvent:
+		if l.droppedBlocks[evt.BlockID] {
+			return nil
+		}
+		return next(ctx, e)
+
+	case ContentStartEvent:
+		if l.droppedBlocks[evt.RelatedBlockID] {
+			l.dropped[evt.ContentID] = true
+			return nil
+		}
+		if n := l.limits.MaxBlockContents; n > 0 && l.blockContents[evt.RelatedBlockID] >= n {
+			err := &LimitError{Limit: LimitBlockContents, Max: int64(n), BlockID: evt.RelatedBlockID, ContentID: evt.ContentID}
+			if l.limits.Action == LimitFail {
+				return l.fail(ctx, next, err)
+			}
+			l.dropped[evt.ContentID] = true
+			return l.notice(ctx, next, LimitBlockContents+"/"+evt.RelatedBlockID, err)
+		}
+		l.blockContents[evt.RelatedBlockID]++
+		l.contents[evt.ContentID] = &limitedContent{blockID: evt.RelatedBlockID}
+		return next(ctx, e)
+
+	case ContentDeltaEvent:
+		return l.delta(ctx, next, evt)
+
+	case ContentEndEvent:
+		delete(l.contents, evt.ContentID)
+		if l.dropped[evt.ContentID] {
+			delete(l.dropped, evt.ContentID)
+			return nil
+		}
+		return next(ctx, e)
+
+	default:
+		return next(ctx, e)
+	}
+}
+
+func (l *limiter) delta(ctx context.Context, next Handler, evt ContentDeltaEvent) error {
+	if l.dropped[evt.ContentID] {
+		return nil
+	}
+	c, ok := l.contents[evt.ContentID]
+	if !ok {
+		return next(ctx, evt)
+	}
+
+	payload, ok := payloadOf(evt.Content)
+	if !ok {
+		return next(ctx, evt)
+	}
+	if c.truncated || l.messageFull {
+		return nil
+	}
+
+	size := int64(len(payload))
+	allowed, limit, bound := size, "", int64(0)
+	if m := l.limits.MaxContentBytes; m > 0 && c.bytes+size > m {
+		allowed, limit, bound = m-c.bytes, LimitContentBytes, m
+	}
+	if m := l.limits.MaxMessageBytes; m > 0 && l.messageBytes+size > m && m-l.messageBytes < allowed {
+		allowed, limit, bound = m-l.messageBytes, LimitMessageBytes, m
+	}
+	if limit == "" {
+		c.bytes += size
+		l.messageBytes += size
+		return next(ctx, evt)
+	}
+
+	err := &LimitError{Limit: limit, Max: bound, BlockID: c.blockID, ContentID: evt.ContentID}
+	if l.limits.Action == LimitFail {
+		return l.fail(ctx, next, err)
+	}
+	if limit == LimitMessageBytes {
+		l.messageFull = true
+	}
+	return l.truncate(ctx, next, evt, c, cut(evt.Content, payload, int(allowed)), err)
+}
+
+// truncate 发送截断后的增量和截断标记, 之后该内容的增量全部丢弃
+func (l *limiter) truncate(ctx context.Context, next Handler, evt ContentDeltaEvent, c *limitedContent, kept string, err *LimitError) error {
+	switch evt.Content.(type) {
+	case StreamToolArgsContent, StreamMCPArgsContent:
+		return l.fail(ctx, next, err)
+	}
+
+	c.truncated = true
+	c.bytes += int64(len(kept))
+	l.messageBytes += int64(len(kept))
+
+	if _, isData := evt.Content.(StreamDataContent); !isData {
+		kept += l.limits.Marker
+	}
+	if kept != "" {
+		evt.Content = withPayload(evt.Content, kept)
+		if e := next(ctx, evt); e != nil {
+			return e
+		}
+	}
+
+	key := err.Limit + "/" + evt.ContentID
+	if err.Limit == LimitMessageBytes {
+		key = err.Limit
+	}
+	return l.notice(ctx, next, key, err)
+}
+
+func (l *limiter) fail(ctx context.Context, next Handler, err *LimitError) error {
+	if e := next(ctx, limitNotice(err)); e != nil {
+		return errors.Join(err, e)
+	}
+	return err
+}
+
+// notice 同一 key 只发送一次提示
+func (l *limiter) notice(ctx context.Context, next Handler, key string, err *LimitError) error {
+	if l.notified[key] {
+		return nil
+	}
+	l.notified[key] = true
+	return next(ctx, limitNotice(err))
+}
+
+func limitNotice(err *LimitError) NoticeEvent {
+	return NewNoticeEvent(Notice{
+		Code:      err.Limit,
+		Message:   err.Error(),
+		BlockID:   err.BlockID,
+		ContentID: err.ContentID,
+	})
+}
+
+// allow 令牌桶限速, 桶容量为每秒事件数
+func (l *limiter) allow() bool {
+	rate := float64(l.limits.MaxEventsPerSecond)
+	if rate <= 0 {
+		return true
+	}
+
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens = min(rate, l.tokens+now.Sub(l.last).Seconds()*rate)
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// cut 截取不超过 n 字节的前缀, 文本按字符边界, 数据按 base64 分组边界
+func cut(sc StreamContent, s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if n >= len(s) {
+		return s
+	}
+	if _, ok := sc.(StreamDataContent); ok {
+		return s[:n/4*4]
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// payloadOf 返回增量中计入限额的文本
+func payloadOf(sc StreamContent) (string, bool) {
+	switch c := sc.(type) {
+	case StreamTextContent:
+		return c.Delta, true
+	case StreamThinkingContent:
+		return c.Delta, true
+	case StreamToolArgsContent:
+		return c.Delta, true
+	case StreamToolResultContent:
+		return c.Delta, c.Error == nil
+	case StreamMCPArgsContent:
+		return c.Delta, true
+	case StreamMCPResultContent:
+		return c.Delta, c.Error == nil
+	case StreamCommandResultContent:
+		return c.Delta, c.Error == nil
+	case StreamCodeExecutionContent:
+		return c.Delta, true
+	case StreamCodeExecutionResultContent:
+		return c.Delta, c.Error == nil
+	case StreamDataContent:
+		return c.Delta, true
+	default:
+		return "", false
+	}
+}
+
+func withPayload(sc StreamContent, s string) StreamContent {
+	switch c := sc.(type) {
+	case StreamTextContent:
+		c.Delta = s
+		return c
+	case StreamThinkingContent:
+		c.Delta = s
+		return c
+	case StreamToolArgsContent:
+		c.Delta = s
+		return c
+	case StreamToolResultContent:
+		c.Delta = s
+		return c
+	case StreamMCPArgsContent:
+		c.Delta = s
+		return c
+	case StreamMCPResultContent:
+		c.Delta = s
+		return c
+	case StreamCommandResultContent:
+		c.Delta = s
+		return c
+	case StreamCodeExecutionContent:
+		c.Delta = s
+		return c
+	case StreamCodeExecutionResultContent:
+		c.Delta = s
+		return c
+	case StreamDataContent:
+		c.Delta = s
+		return c
+	default:
+		return sc
+	}
+}
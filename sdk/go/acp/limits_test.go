@@ -0,0 +1,217 @@
+package acp
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func toolCallEvents(contentID, blockID string, chunks ...string) []Event {
+	events := []Event{
+		NewContentStartEvent(contentID, blockID),
+		NewContentDeltaEvent(contentID, NewStreamToolCallContent("dump")),
+	}
+	for _, c := range chunks {
+		events = append(events, NewContentDeltaEvent(contentID, NewStreamToolResultContent(c)))
+	}
+	return append(events, NewContentEndEvent(contentID))
+}
+
+func addAll(t *testing.T, m *Creator, events ...Event) {
+	t.Helper()
+	for _, e := range events {
+		require.NoError(t, m.AddEvent(e))
+	}
+}
+
+func TestLimitContentBytesTruncate(t *testing.T) {
+	m := NewCreator(nil, WithLimits(Limits{MaxContentBytes: 10}))
+	addAll(t, m, NewRunStartedEvent("s1", "r1"), NewBlockStartEvent("b1"))
+	addAll(t, m, toolCallEvents("c1", "b1", "12345", "67890abc", "def")...)
+	addAll(t, m, toolCallEvents("c2", "b1", "small")...)
+
+	call := m.Blocks[0].Contents[0].(*ToolCallContent)
+	assert.Equal(t, "1234567890"+DefaultTruncateMarker, call.ToolResult)
+	assert.Equal(t, "small", m.Blocks[0].Contents[1].(*ToolCallContent).ToolResult)
+
+	require.Len(t, m.Notices, 1)
+	assert.Equal(t, Notice{
+		Code:      LimitContentBytes,
+		Message:   "limit exceeded: content_bytes > 10 (content c1)",
+		BlockID:   "b1",
+		ContentID: "c1",
+	}, m.Notices[0])
+}
+
+func TestLimitContentBytesRuneBoundary(t *testing.T) {
+	m := NewCreator(nil, WithLimits(Limits{MaxContentBytes: 4, Marker: "…"}))
+	addAll(t, m,
+		NewRunStartedEvent("s1", "r1"),
+		NewBlockStartEvent("b1"),
+		NewContentStartEvent("c1", "b1"),
+		NewContentDeltaEvent("c1", NewStreamTextContent("a你好")),
+		NewContentEndEvent("c1"),
+	)
+	assert.Equal(t, "a你…", m.Blocks[0].Contents[0].(*TextContent).Text)
+}
+
+func TestLimitFail(t *testing.T) {
+	m := NewCreator(nil, WithLimits(Limits{MaxContentBytes: 4, Action: LimitFail}))
+	addAll(t, m, NewRunStartedEvent("s1", "r1"), NewBlockStartEvent("b1"), NewContentStartEvent("c1", "b1"))
+
+	err := m.AddEvent(NewContentDeltaEvent("c1", NewStreamTextContent("too long")))
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+
+	var limitErr *LimitError
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, LimitContentBytes, limitErr.Limit)
+	assert.Equal(t, int64(4), limitErr.Max)
+	assert.Equal(t, "c1", limitErr.ContentID)
+
+	// 失败前仍发送可见的提示
+	require.Len(t, m.Notices, 1)
+	assert.Equal(t, LimitContentBytes, m.Notices[0].Code)
+}
+
+func TestLimitToolArgsNotTruncated(t *testing.T) {
+	m := NewCreator(nil, WithLimits(Limits{MaxContentBytes: 8}))
+	addAll(t, m,
+		NewRunStartedEvent("s1", "r1"),
+		NewBlockStartEvent("b1"),
+		NewContentStartEvent("c1", "b1"),
+		NewContentDeltaEvent("c1", NewStreamToolCallContent("search")),
+		NewContentDeltaEvent("c1", NewStreamToolArgsContent(`{"q":`)),
+	)
+
+	// 截断模式下参数超限也返回错误, 不写入无法解析的参数
+	err := m.AddEvent(NewContentDeltaEvent("c1", NewStreamToolArgsContent(`"long query"}`)))
+	var limitErr *LimitError
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, LimitContentBytes, limitErr.Limit)
+	assert.Equal(t, "c1", limitErr.ContentID)
+
+	snapshot, err := m.Snapshot()
+	require.NoError(t, err)
+	assert.Equal(t, `{"q":`, snapshot.Blocks[0].Contents[0].(*ToolCallContent).ToolArgs)
+	assert.NotContains(t, snapshot.Blocks[0].Contents[0].(*ToolCallContent).ToolArgs, DefaultTruncateMarker)
+	require.Len(t, m.Notices, 1)
+	assert.Equal(t, LimitContentBytes, m.Notices[0].Code)
+
+	m = NewCreator(nil, WithLimits(Limits{MaxContentBytes: 4}))
+	addAll(t, m,
+		NewRunStartedEvent("s1", "r1"),
+		NewBlockStartEvent("b1"),
+		NewContentStartEvent("c1", "b1"),
+		NewContentDeltaEvent("c1", NewStreamMCPCallContent("fs", "read")),
+	)
+	assert.ErrorIs(t, m.AddEvent(NewContentDeltaEvent("c1", NewStreamMCPArgsContent(`{"path":"/"}`))), ErrLimitExceeded)
+}
+
+func TestLimitBlocksAndContents(t *testing.T) {
+	m := NewCreator(nil, WithLimits(Limits{MaxBlocks: 1, MaxBlockContents: 1}))
+	addAll(t, m, NewRunStartedEvent("s1", "r1"), NewBlockStartEvent("b1"))
+	addAll(t, m, toolCallEvents("c1", "b1", "a")...)
+	addAll(t, m, toolCallEvents("c2", "b1", "b")...)
+	addAll(t, m, toolCallEvents("c3", "b1", "c")...)
+	addAll(t, m, NewBlockEndEvent("b1", nil))
+
+	addAll(t, m, NewBlockStartEvent("b2"), NewBlockStartEvent("b3", WithParentBlockID("b2")))
+	addAll(t, m, toolCallEvents("c4", "b3", "d")...)
+	addAll(t, m, NewBlockEndEvent("b3", nil), NewBlockEndEvent("b2", nil), NewRunFinishedEvent("r1"))
+
+	require.Len(t, m.Blocks, 1)
+	require.Len(t, m.Blocks[0].Contents, 1)
+
+	codes := make([]string, 0, len(m.Notices))
+	for _, n := range m.Notices {
+		codes = append(codes, n.Code)
+	}
+	assert.Equal(t, []string{LimitBlockContents, LimitBlocks}, codes)
+
+	tree, err := m.BlockTree()
+	require.NoError(t, err)
+	assert.Len(t, tree.Roots, 1)
+}
+
+func TestLimitOptionReused(t *testing.T) {
+	opt := WithLimits(Limits{MaxBlocks: 1})
+	for range 2 {
+		m := NewCreator(nil, opt)
+		addAll(t, m, NewRunStartedEvent("s1", "r1"), NewBlockStartEvent("b1"), NewBlockEndEvent("b1", nil), NewRunFinishedEvent("r1"))
+		assert.Len(t, m.Blocks, 1)
+		assert.Empty(t, m.Notices)
+	}
+}
+
+func TestLimitMessageBytes(t *testing.T) {
+	m := NewCreator(nil, WithLimits(Limits{MaxMessageBytes: 8}))
+	addAll(t, m, NewRunStartedEvent("s1", "r1"), NewBlockStartEvent("b1"))
+	addAll(t, m, toolCallEvents("c1", "b1", "12345")...)
+	addAll(t, m, toolCallEvents("c2", "b1", "6789", "more")...)
+	addAll(t, m, toolCallEvents("c3", "b1", "dropped")...)
+
+	assert.Equal(t, "12345", m.Blocks[0].Contents[0].(*ToolCallContent).ToolResult)
+	assert.Equal(t, "678"+DefaultTruncateMarker, m.Blocks[0].Contents[1].(*ToolCallContent).ToolResult)
+	// 超出消息总量后其他内容只保留头部信息
+	third := m.Blocks[0].Contents[2].(*ToolCallContent)
+	assert.Equal(t, "dump", third.ToolName)
+	assert.Empty(t, third.ToolResult)
+	require.Len(t, m.Notices, 1)
+	assert.Equal(t, LimitMessageBytes, m.Notices[0].Code)
+}
+
+func TestLimitDataContent(t *testing.T) {
+	m := NewCreator(nil, WithLimits(Limits{MaxContentBytes: 10}))
+	addAll(t, m,
+		NewRunStartedEvent("s1", "r1"),
+		NewBlockStartEvent("b1"),
+		NewContentStartEvent("c1", "b1"),
+		NewContentDeltaEvent("c1", NewStreamDataContent("text/plain", []byte("hello world"))),
+		NewContentEndEvent("c1"),
+	)
+
+	data := m.Blocks[0].Contents[0].(*DataContent)
+	assert.Equal(t, "aGVsbG8g", data.Data)
+}
+
+func TestLimitEventRate(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newLimiter(Limits{MaxEventsPerSecond: 5})
+	l.now = func() time.Time { return now }
+	m := NewCreator(nil, WithInterceptors(l.intercept))
+
+	addAll(t, m, NewRunStartedEvent("s1", "r1"), NewBlockStartEvent("b1"), NewContentStartEvent("c1", "b1"))
+	for range 5 {
+		addAll(t, m, NewContentDeltaEvent("c1", NewStreamTextContent("x")))
+	}
+	addAll(t, m, NewContentEndEvent("c1"))
+
+	assert.Equal(t, "xx"+DefaultTruncateMarker, m.Blocks[0].Contents[0].(*TextContent).Text)
+	require.Len(t, m.Notices, 1)
+	assert.Equal(t, LimitEventRate, m.Notices[0].Code)
+
+	// 令牌随时间恢复
+	now = now.Add(time.Second)
+	addAll(t, m, NewContentStartEvent("c2", "b1"), NewContentDeltaEvent("c2", NewStreamTextContent("ok")), NewContentEndEvent("c2"))
+	assert.Equal(t, "ok", m.Blocks[0].Contents[1].(*TextContent).Text)
+
+	failing := newLimiter(Limits{MaxEventsPerSecond: 1, Action: LimitFail})
+	failing.now = func() time.Time { return now }
+	m = NewCreator(nil, WithInterceptors(failing.intercept))
+	require.NoError(t, m.AddEvent(NewRunStartedEvent("s1", "r1")))
+	assert.ErrorIs(t, m.AddEvent(NewBlockStartEvent("b1")), ErrLimitExceeded)
+}
+
+func TestNoticeEventRoundTrip(t *testing.T) {
+	e := NewNoticeEvent(Notice{Code: LimitBlocks, Message: "too many blocks", BlockID: "b1"})
+	data, err := json.Marshal(e)
+	require.NoError(t, err)
+
+	decoded, err := UnmarshalEvent(data)
+	require.NoError(t, err)
+	assert.Equal(t, e, decoded)
+}
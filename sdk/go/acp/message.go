@@ -9,12 +9,18 @@ const (
 )
 
 type Message struct {
-	ID        string  `json:"id,omitempty"`
-	Role      string  `json:"role"`
-	Blocks    []Block `json:"blocks"`
-	CreatedAt int64   `json:"created_at"`
-	UpdatedAt int64   `json:"updated_at"`
-	Errors    string  `json:"errors,omitempty"`
+	ID        string      `json:"id,omitempty"`
+	SessionID string      `json:"session_id,omitempty"`
+	RunID     string      `json:"run_id,omitempty"`
+	Role      string      `json:"role"`
+	Blocks    []Block     `json:"blocks"`
+	CreatedAt int64       `json:"created_at"`
+	UpdatedAt int64       `json:"updated_at"`
+	Errors    string      `json:"errors,omitempty"`
+	RunError  *RunError   `json:"run_error,omitempty"`
+	Notices   []Notice    `json:"notices,omitempty"`
+	Usage     *Usage      `json:"usage,omitempty"`
+	Metrics   *RunMetrics `json:"metrics,omitempty"`
 }
 
 func (m *Message) GetInputs() (*TextContent, []*FileContent) {
@@ -52,6 +58,15 @@ func (m *Message) GetVariables() (*TextContent, *VariableContent) {
 	return text, variables
 }
 
+// TotalUsage 汇总所有区块(含子智能体区块)的用量
+func (m *Message) TotalUsage() *Usage {
+	total := &Usage{}
+	for _, b := range m.Blocks {
+		total.Add(b.Usage)
+	}
+	return total
+}
+
 type Block struct {
 	ID            string         `json:"id,omitempty"`
 	Contents      []Content      `json:"contents"`
@@ -60,6 +75,7 @@ type Block struct {
 	IsSubagent    bool           `json:"is_subagent,omitempty"`
 	Metadata      map[string]any `json:"metadata,omitempty"`
 	ParentBlockID string         `json:"parent_block_id,omitempty"`
+	Errors        []RunError     `json:"errors,omitempty"`
 }
 
 func (b *Block) UnmarshalJSON(data []byte) error {
@@ -71,6 +87,7 @@ func (b *Block) UnmarshalJSON(data []byte) error {
 		IsSubagent    bool              `json:"is_subagent,omitempty"`
 		Metadata      map[string]any    `json:"metadata,omitempty"`
 		ParentBlockID string            `json:"parent_block_id,omitempty"`
+		Errors        []RunError        `json:"errors,omitempty"`
 	}
 
 	var rb rawBlock
@@ -84,6 +101,7 @@ func (b *Block) UnmarshalJSON(data []byte) error {
 	b.IsSubagent = rb.IsSubagent
 	b.Metadata = rb.Metadata
 	b.ParentBlockID = rb.ParentBlockID
+	b.Errors = rb.Errors
 	b.Contents = make([]Content, 0, len(rb.Contents))
 
 	for _, raw := range rb.Contents {
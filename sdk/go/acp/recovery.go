@@ -0,0 +1,88 @@
+package acp
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// 追加式事件日志, Creator 在发送事件前写入, 用于进程重启后恢复运行
+type EventLog interface {
+	Append(e Event) error
+}
+
+// WithEventLog 在发送前将每个事件追加到日志
+func WithEventLog(log EventLog) CreatorOption {
+	return func(m *Creator) { m.log = log }
+}
+
+// RecoverCreator 重放事件日志重建 Creator, 重放的事件不会再次写入日志或发送.
+// 重放不经过拦截器, 拦截器自身的状态(如 WithLimits 的计数, qa.Coordinator 的待答问题)
+// 不会恢复, 需要时由调用方根据返回的 Creator 重建
+func RecoverCreator(writer *SSEWriter, events []Event, opts ...CreatorOption) (*Creator, error) {
+	m := NewCreator(writer, opts...)
+	for i, e := range events {
+		ok, err := m.check(e)
+		if err == nil && ok {
+			err = m.apply(e)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("replay event %d (%s): %w", i, e.Type(), err)
+		}
+		if i == 0 && e.Timestamp() > 0 {
+			m.CreatedAt = time.UnixMilli(e.Timestamp()).UnixMicro()
+		}
+		if e.Timestamp() > 0 {
+			m.UpdatedAt = time.UnixMilli(e.Timestamp()).UnixMicro()
+		}
+	}
+	return m, nil
+}
+
+// Started 是否已收到 RunStarted
+func (m *Creator) Started() bool {
+	return m.hasStarted
+}
+
+// OpenBlocks 返回尚未结束的区块 ID, 按开始顺序
+func (m *Creator) OpenBlocks() []string {
+	return slices.Clone(m.openBlocks)
+}
+
+// OpenContents 返回尚未结束的内容 ID
+func (m *Creator) OpenContents() []string {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	return slices.Sorted(maps.Keys(m.contentIDMap))
+}
+
+// Abort 结束所有未完成的内容和区块, 并以 RunError 终止运行
+func (m *Creator) Abort(message string) error {
+	if m.hasFinished {
+		return fmt.Errorf("%w: run already finished", ErrRunEvent)
+	}
+
+	for _, id := range m.OpenContents() {
+		if err := m.AddEvent(NewContentEndEvent(id)); err != nil {
+			return err
+		}
+	}
+	for i := len(m.openBlocks) - 1; i >= 0; i-- {
+		if err := m.AddEvent(NewBlockEndEvent(m.openBlocks[i], nil)); err != nil {
+			return err
+		}
+	}
+	return m.AddEvent(NewRunErrorEvent(m.RunID, message))
+}
+
+// ReplayEvents 向重连的客户端补发历史事件
+func ReplayEvents(w *SSEWriter, events []Event) error {
+	for _, e := range events {
+		if err := w.Send(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package acp
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sliceLog struct {
+	events []Event
+	err    error
+}
+
+func (l *sliceLog) Append(e Event) error {
+	if l.err != nil {
+		return l.err
+	}
+	l.events = append(l.events, e)
+	return nil
+}
+
+func partialRun(t *testing.T, m *Creator) {
+	require.NoError(t, m.AddEvent(NewRunStartedEvent("s1", "r1")))
+	require.NoError(t, m.AddEvent(NewBlockStartEvent("b1")))
+	require.NoError(t, m.AddEvent(NewContentStartEvent("c1", "b1")))
+	require.NoError(t, m.AddEvent(NewContentDeltaEvent("c1", NewStreamTextContent("done"))))
+	require.NoError(t, m.AddEvent(NewContentEndEvent("c1")))
+	require.NoError(t, m.AddEvent(NewBlockStartEvent("b2", WithParentBlockID("b1"))))
+	require.NoError(t, m.AddEvent(NewContentStartEvent("c2", "b2")))
+	require.NoError(t, m.AddEvent(NewContentDeltaEvent("c2", NewStreamTextContent("half "))))
+}
+
+func TestCreatorEventLog(t *testing.T) {
+	log := &sliceLog{}
+	var buf bytes.Buffer
+	m := NewCreator(NewSSEWriter(&buf), WithEventLog(log))
+	partialRun(t, m)
+
+	assert.Len(t, log.events, 8)
+	assert.Equal(t, 8, strings.Count(buf.String(), "event:"))
+
+	// 重复的 RunStarted 被忽略, 不写日志
+	require.NoError(t, m.AddEvent(NewRunStartedEvent("s1", "r1")))
+	assert.Len(t, log.events, 8)
+}
+
+func TestCreatorEventLogError(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewCreator(NewSSEWriter(&buf), WithEventLog(&sliceLog{err: errors.New("disk full")}))
+
+	err := m.AddEvent(NewRunStartedEvent("s1", "r1"))
+	assert.ErrorContains(t, err, "disk full")
+	assert.Empty(t, buf.String(), "event must not be sent before it is logged")
+	// 未写入日志的事件不修改消息
+	assert.Empty(t, m.RunID)
+	assert.NotEqual(t, "r1", m.ID)
+}
+
+func TestCreatorEventLogSkipsInvalidEvents(t *testing.T) {
+	log := &sliceLog{}
+	m := NewCreator(nil, WithEventLog(log))
+	partialRun(t, m)
+
+	assert.ErrorIs(t, m.AddEvent(NewContentDeltaEvent("c2", NewStreamToolArgsContent("{}"))), ErrContentEvent)
+	assert.ErrorIs(t, m.AddEvent(NewBlockErrorEvent("missing", NewRunError(ErrorCodeInternal, "x"))), ErrBlockEvent)
+	assert.Len(t, log.events, 8)
+
+	// 日志可完整重放
+	_, err := RecoverCreator(nil, log.events)
+	require.NoError(t, err)
+}
+
+func TestRecoverCreator(t *testing.T) {
+	log := &sliceLog{}
+	original := NewCreator(nil, WithEventLog(log))
+	partialRun(t, original)
+
+	var buf bytes.Buffer
+	m, err := RecoverCreator(NewSSEWriter(&buf), log.events)
+	require.NoError(t, err)
+	assert.Empty(t, buf.String(), "replay must not resend events")
+
+	assert.True(t, m.Started())
+	assert.False(t, m.Finished())
+	assert.Equal(t, "r1", m.ID)
+	assert.Equal(t, "s1", m.SessionID)
+	assert.Equal(t, []string{"b1", "b2"}, m.OpenBlocks())
+	assert.Equal(t, []string{"c2"}, m.OpenContents())
+	assert.Equal(t, log.events[0].Timestamp()*1000, m.CreatedAt)
+	assert.Equal(t, original.Blocks[0].Contents, m.Blocks[0].Contents)
+
+	// 恢复后继续运行
+	require.NoError(t, m.AddEvent(NewContentDeltaEvent("c2", NewStreamTextContent("resumed"))))
+	require.NoError(t, m.AddEvent(NewContentEndEvent("c2")))
+	require.NoError(t, m.AddEvent(NewBlockEndEvent("b2", nil)))
+	require.NoError(t, m.AddEvent(NewBlockEndEvent("b1", nil)))
+	require.NoError(t, m.AddEvent(NewRunFinishedEvent("r1")))
+
+	assert.Empty(t, m.OpenBlocks())
+	require.Len(t, m.Blocks, 2)
+	assert.Equal(t, "done", m.Blocks[0].Contents[0].(*TextContent).Text)
+	assert.Equal(t, "half resumed", m.Blocks[1].Contents[0].(*TextContent).Text)
+}
+
+func TestRecoverCreatorAbort(t *testing.T) {
+	log := &sliceLog{}
+	partialRun(t, NewCreator(nil, WithEventLog(log)))
+
+	recovered := &sliceLog{}
+	m, err := RecoverCreator(nil, log.events, WithEventLog(recovered))
+	require.NoError(t, err)
+	require.NoError(t, m.Abort("process restarted"))
+
+	assert.True(t, m.Finished())
+	assert.Equal(t, "process restarted", m.Errors)
+	assert.Empty(t, m.OpenBlocks())
+	assert.Empty(t, m.OpenContents())
+	assert.Equal(t, "half ", m.Blocks[1].Contents[0].(*TextContent).Text)
+
+	types := make([]EventType, 0, len(recovered.events))
+	for _, e := range recovered.events {
+		types = append(types, e.Type())
+	}
+	assert.Equal(t, []EventType{
+		EventTypeContentEnd, EventTypeBlockEnd, EventTypeBlockEnd, EventTypeRunError,
+	}, types)
+	assert.Equal(t, "b2", recovered.events[1].(BlockEndEvent).BlockID)
+
+	assert.ErrorIs(t, m.Abort("again"), ErrRunEvent)
+}
+
+func TestRecoverCreatorFinishedRun(t *testing.T) {
+	log := &sliceLog{}
+	m := NewCreator(nil, WithEventLog(log))
+	partialRun(t, m)
+	require.NoError(t, m.Abort("x"))
+
+	// 日志中包含两次结束事件时重放失败
+	events := append(log.events, NewRunFinishedEvent("r1"))
+	_, err := RecoverCreator(nil, events)
+	assert.Error(t, err)
+
+	recovered, err := RecoverCreator(nil, log.events)
+	require.NoError(t, err)
+	assert.True(t, recovered.Finished())
+}
+
+func TestRecoverCreatorInvalidDelta(t *testing.T) {
+	log := &sliceLog{}
+	m := NewCreator(nil, WithEventLog(log))
+	partialRun(t, m)
+
+	// 重放与实时处理共用校验, 类型不匹配的增量同样被拒绝
+	events := append(log.events, NewContentDeltaEvent("c2", NewStreamThinkingContent("x")))
+	_, err := RecoverCreator(nil, events)
+	assert.ErrorIs(t, err, ErrContentEvent)
+}
+
+func TestReplayEvents(t *testing.T) {
+	log := &sliceLog{}
+	partialRun(t, NewCreator(nil, WithEventLog(log)))
+
+	var buf bytes.Buffer
+	require.NoError(t, ReplayEvents(NewSSEWriter(&buf), log.events))
+	assert.Equal(t, len(log.events), strings.Count(buf.String(), "event:"))
+}
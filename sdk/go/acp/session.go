@@ -0,0 +1,85 @@
+package acp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRunNotFinished  = errors.New("run not finished")
+	ErrSessionMismatch = errors.New("message belongs to another session")
+)
+
+// 会话, 按时间顺序保存用户与助手的多轮消息
+type Session struct {
+	ID        string         `json:"id"`
+	Title     string         `json:"title,omitempty"`
+	CreatedAt int64          `json:"created_at"`
+	UpdatedAt int64          `json:"updated_at"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+	Messages  []Message      `json:"messages"`
+}
+
+// NewSession id 为空时自动生成
+func NewSession(id string) *Session {
+	if id == "" {
+		id = uuid.NewString()
+	}
+
+	now := time.Now().UnixMicro()
+	return &Session{
+		ID:        id,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Messages:  make([]Message, 0),
+	}
+}
+
+// Append 追加消息, 未设置 SessionID 的消息归属到当前会话
+func (s *Session) Append(msgs ...Message) error {
+	for i := range msgs {
+		if msgs[i].SessionID != "" && msgs[i].SessionID != s.ID {
+			return fmt.Errorf("%w: %s", ErrSessionMismatch, msgs[i].SessionID)
+		}
+	}
+
+	for _, msg := range msgs {
+		msg.SessionID = s.ID
+		s.Messages = append(s.Messages, msg)
+	}
+	s.UpdatedAt = time.Now().UnixMicro()
+	return nil
+}
+
+// AppendCreator 追加已结束运行聚合出的助手消息的快照, 之后对 Creator 的修改不影响会话
+func (s *Session) AppendCreator(c *Creator) error {
+	if !c.Finished() {
+		return ErrRunNotFinished
+	}
+	msg, err := c.Snapshot()
+	if err != nil {
+		return err
+	}
+	return s.Append(*msg)
+}
+
+// LastMessage 无消息时返回 nil
+func (s *Session) LastMessage() *Message {
+	if len(s.Messages) == 0 {
+		return nil
+	}
+	return &s.Messages[len(s.Messages)-1]
+}
+
+// FindRun 按 RunID 查找消息
+func (s *Session) FindRun(runID string) *Message {
+	for i := len(s.Messages) - 1; i >= 0; i-- {
+		if s.Messages[i].RunID == runID {
+			return &s.Messages[i]
+		}
+	}
+	return nil
+}
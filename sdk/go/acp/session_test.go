@@ -0,0 +1,69 @@
+package acp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionAppendCreator(t *testing.T) {
+	session := NewSession("s1")
+	session.Title = "weather"
+	session.Metadata = map[string]any{"channel": "web"}
+
+	require.NoError(t, session.Append(Message{
+		ID:     "u1",
+		Role:   RoleUser,
+		Blocks: []Block{{ID: "b0", Contents: []Content{NewTextContent("", "weather?")}}},
+	}))
+
+	creator := NewCreator(nil)
+	require.NoError(t, creator.AddEvent(NewRunStartedEvent("s1", "r1")))
+	require.NoError(t, creator.AddEvent(NewBlockStartEvent("b1")))
+	require.NoError(t, creator.AddEvent(NewContentStartEvent("c1", "b1")))
+	require.NoError(t, creator.AddEvent(NewContentDeltaEvent("c1", NewStreamTextContent("sunny"))))
+	require.NoError(t, creator.AddEvent(NewContentEndEvent("c1")))
+	require.NoError(t, creator.AddEvent(NewBlockEndEvent("b1", nil)))
+
+	assert.ErrorIs(t, session.AppendCreator(creator), ErrRunNotFinished)
+
+	require.NoError(t, creator.AddEvent(NewRunFinishedEvent("r1")))
+	assert.Equal(t, "s1", creator.SessionID)
+	assert.Equal(t, "r1", creator.RunID)
+	require.NoError(t, session.AppendCreator(creator))
+
+	require.Len(t, session.Messages, 2)
+	assert.Equal(t, "s1", session.Messages[0].SessionID)
+	assert.Equal(t, "r1", session.LastMessage().RunID)
+	assert.Equal(t, RoleAssistant, session.FindRun("r1").Role)
+	assert.Nil(t, session.FindRun("missing"))
+
+	// 会话保存的是快照, 之后对 Creator 的修改不影响已保存的消息
+	creator.Blocks[0].Metadata = map[string]any{"late": true}
+	creator.Blocks[0].Contents[0].(*TextContent).Text = "rainy"
+	assert.Nil(t, session.LastMessage().Blocks[0].Metadata)
+	assert.Equal(t, "sunny", session.LastMessage().Blocks[0].Contents[0].(*TextContent).Text)
+
+	data, err := json.Marshal(session)
+	require.NoError(t, err)
+
+	var decoded Session
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "weather", decoded.Title)
+	assert.Equal(t, "web", decoded.Metadata["channel"])
+	require.Len(t, decoded.Messages, 2)
+	assert.Equal(t, "r1", decoded.Messages[1].RunID)
+	assert.Equal(t, "sunny", decoded.Messages[1].Blocks[0].Contents[0].(*TextContent).Text)
+}
+
+func TestSessionRejectsForeignMessage(t *testing.T) {
+	session := NewSession("")
+	assert.NotEmpty(t, session.ID)
+	assert.Nil(t, session.LastMessage())
+
+	err := session.Append(Message{SessionID: "other", Role: RoleUser})
+	assert.ErrorIs(t, err, ErrSessionMismatch)
+	assert.Empty(t, session.Messages)
+}
@@ -0,0 +1,269 @@
+package acp
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var ErrSubagentRun = errors.New("subagent run error")
+
+// SubagentError 子运行失败时 SubagentProxy.AddEvent 返回的错误,
+// 映射为 subagent_failed, 子运行的结构化错误作为原因保留
+type SubagentError struct {
+	RunID string
+	Err   *RunError
+}
+
+func (e *SubagentError) Error() string {
+	return fmt.Sprintf("%s: run %s: %s", ErrSubagentRun, e.RunID, e.Err)
+}
+
+func (e *SubagentError) Unwrap() []error {
+	return []error{e.Err, ErrSubagentRun}
+}
+
+func (e *SubagentError) ErrorCode() string {
+	return ErrorCodeSubagent
+}
+
+func (e *SubagentError) IsRetryable() bool {
+	return e.Err.Retryable
+}
+
+func (e *SubagentError) ErrorDetails() map[string]any {
+	return map[string]any{"run_id": e.RunID}
+}
+
+// 子智能体事件代理, 将子智能体的 ACP 事件改写后写入父 Creator:
+//   - 子运行的 RunStarted/RunFinished/RunError 转换为父消息中子智能体区块的开始与结束
+//   - 子区块与子内容 ID 以子智能体区块 ID 为前缀, 避免与父运行冲突
+//   - 子运行中的顶层区块挂载到子智能体区块下
+//
+// SubagentProxy 与父 Creator 共用同一写入协程, 不可与父运行并发写入
+type SubagentProxy struct {
+	parent  *Creator
+	blockID string
+	opts    []BlockOption
+
+	started  bool
+	finished bool
+	blocks   []string // 已开始未结束的子区块, 按开始顺序
+	contents []string // 已开始未结束的子内容, 按开始顺序
+}
+
+func NewSubagentProxy(parent *Creator, blockID string, opts ...BlockOption) *SubagentProxy {
+	return &SubagentProxy{
+		parent:  parent,
+		blockID: blockID,
+		opts:    opts,
+	}
+}
+
+// BlockID 返回父消息中子智能体区块的 ID
+func (p *SubagentProxy) BlockID() string {
+	return p.blockID
+}
+
+func (p *SubagentProxy) AddEvent(e Event) error {
+	if p.finished {
+		return errors.New("subagent stream already done")
+	}
+
+	switch evt := e.(type) {
+	case RunStartedEvent:
+		opts := append([]BlockOption{}, p.opts...)
+		opts = append(opts, WithIsSubagent(), withSubagentRun(evt.SessionID, evt.RunID))
+		return p.start(opts...)
+
+	case RunFinishedEvent:
+		return p.Close()
+
+	case RunErrorEvent:
+		if err := p.start(); err != nil {
+			return err
+		}
+		if err := p.parent.AddEvent(NewBlockErrorEvent(p.blockID, p.runError(evt))); err != nil {
+			return err
+		}
+		if err := p.Close(); err != nil {
+			return err
+		}
+		cause := evt.Info.clone()
+		if cause == nil {
+			cause = &RunError{Code: ErrorCodeInternal, Message: evt.Error}
+		}
+		return &SubagentError{RunID: evt.RunID, Err: cause}
+
+	case BlockStartEvent:
+		if err := p.start(); err != nil {
+			return err
+		}
+		evt.BlockID = p.namespaced(evt.BlockID)
+		if evt.ParentBlockID == "" {
+			evt.ParentBlockID = p.blockID
+		} else {
+			evt.ParentBlockID = p.namespaced(evt.ParentBlockID)
+		}
+		if err := p.parent.AddEvent(evt); err != nil {
+			return err
+		}
+		p.blocks = append(p.blocks, evt.BlockID)
+		return nil
+
+	case BlockEndEvent:
+		evt.BlockID = p.namespaced(evt.BlockID)
+		if err := p.parent.AddEvent(evt); err != nil {
+			return err
+		}
+		p.blocks = removeID(p.blocks, evt.BlockID)
+		return nil
+
+	case BlockErrorEvent:
+		evt.BlockID = p.namespaced(evt.BlockID)
+		evt.Error.BlockID = evt.BlockID
+		if evt.Error.ContentID != "" {
+			evt.Error.ContentID = p.namespaced(evt.Error.ContentID)
+		}
+		return p.parent.AddEvent(evt)
+
+	case ContentStartEvent:
+		if err := p.start(); err != nil {
+			return err
+		}
+		evt.ContentID = p.namespaced(evt.ContentID)
+		if evt.RelatedBlockID == "" {
+			evt.RelatedBlockID = p.blockID
+		} else {
+			evt.RelatedBlockID = p.namespaced(evt.RelatedBlockID)
+		}
+		if err := p.parent.AddEvent(evt); err != nil {
+			return err
+		}
+		p.contents = append(p.contents, evt.ContentID)
+		return nil
+
+	case ContentDeltaEvent:
+		evt.ContentID = p.namespaced(evt.ContentID)
+		return p.parent.AddEvent(evt)
+
+	case ContentEndEvent:
+		evt.ContentID = p.namespaced(evt.ContentID)
+		if err := p.parent.AddEvent(evt); err != nil {
+			return err
+		}
+		p.contents = removeID(p.contents, evt.ContentID)
+		return nil
+
+	case NoticeEvent:
+		if evt.BlockID != "" {
+			evt.BlockID = p.namespaced(evt.BlockID)
+		}
+		if evt.ContentID != "" {
+			evt.ContentID = p.namespaced(evt.ContentID)
+		}
+		return p.parent.AddEvent(evt)
+
+	default:
+		return fmt.Errorf("unsupport event: %s", e.Type())
+	}
+}
+
+// Close 结束子智能体区块, 子运行未发送 RunFinished 时也需调用.
+// 子运行中未结束的内容和区块按开始的逆序先行结束.
+func (p *SubagentProxy) Close() error {
+	if p.finished {
+		return nil
+	}
+	if err := p.start(); err != nil {
+		return err
+	}
+	for i := len(p.contents) - 1; i >= 0; i-- {
+		if err := p.parent.AddEvent(NewContentEndEvent(p.contents[i])); err != nil {
+			return err
+		}
+	}
+	p.contents = nil
+	for i := len(p.blocks) - 1; i >= 0; i-- {
+		if err := p.parent.AddEvent(NewBlockEndEvent(p.blocks[i], nil)); err != nil {
+			return err
+		}
+	}
+	p.blocks = nil
+
+	p.finished = true
+	return p.parent.AddEvent(NewBlockEndEvent(p.blockID, nil))
+}
+
+func (p *SubagentProxy) start(opts ...BlockOption) error {
+	if p.started {
+		return nil
+	}
+	if len(opts) == 0 {
+		opts = append(append(opts, p.opts...), WithIsSubagent())
+	}
+
+	p.started = true
+	return p.parent.AddEvent(NewBlockStartEvent(p.blockID, opts...))
+}
+
+// runError 将子运行错误转换为子智能体区块上的错误, 子运行的结构化错误作为原因保留
+func (p *SubagentProxy) runError(evt RunErrorEvent) *RunError {
+	re := &RunError{
+		Code:    ErrorCodeSubagent,
+		Message: evt.Error,
+		Details: map[string]any{"run_id": evt.RunID},
+	}
+	if cause := evt.Info.clone(); cause != nil {
+		for c := cause; c != nil; c = c.Cause {
+			c.BlockID, c.ContentID = p.namespaced(c.BlockID), p.namespaced(c.ContentID)
+		}
+		re.Retryable = cause.Retryable
+		re.Cause = cause
+	}
+	return re
+}
+
+func removeID(ids []string, id string) []string {
+	if i := slices.Index(ids, id); i >= 0 {
+		return slices.Delete(ids, i, i+1)
+	}
+	return ids
+}
+
+func (p *SubagentProxy) namespaced(id string) string {
+	if id == "" {
+		return id
+	}
+	return p.blockID + "/" + id
+}
+
+func withSubagentRun(sessionID, runID string) BlockOption {
+	return func(e *BlockStartEvent) {
+		metadata := make(map[string]any, len(e.Metadata)+2)
+		for k, v := range e.Metadata {
+			metadata[k] = v
+		}
+		if sessionID != "" {
+			metadata["subagent_session_id"] = sessionID
+		}
+		if runID != "" {
+			metadata["subagent_run_id"] = runID
+		}
+		e.Metadata = metadata
+	}
+}
+
+// Attach 消费子智能体事件流直至通道关闭或子运行结束, 事件在 blockID 区块下合并进当前运行
+func (m *Creator) Attach(blockID string, events <-chan Event, opts ...BlockOption) error {
+	proxy := NewSubagentProxy(m, blockID, opts...)
+	for e := range events {
+		if err := proxy.AddEvent(e); err != nil {
+			return err
+		}
+		if proxy.finished {
+			return nil
+		}
+	}
+	return proxy.Close()
+}
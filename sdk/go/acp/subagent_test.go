@@ -0,0 +1,111 @@
+package acp
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreatorAttachSubagent(t *testing.T) {
+	parent := NewCreator(nil)
+	parentBlockID := uuid.NewString()
+	subBlockID := uuid.NewString()
+
+	require.NoError(t, parent.AddEvent(NewRunStartedEvent("s1", "run_parent")))
+	require.NoError(t, parent.AddEvent(NewBlockStartEvent(parentBlockID)))
+
+	events := make(chan Event, 16)
+	events <- NewRunStartedEvent("s2", "run_child")
+	events <- NewBlockStartEvent("b1")
+	events <- NewContentStartEvent("c1", "b1")
+	events <- NewContentDeltaEvent("c1", NewStreamTextContent("child says hi"))
+	events <- NewContentEndEvent("c1")
+	events <- NewBlockStartEvent("b2", WithParentBlockID("b1"))
+	events <- NewBlockEndEvent("b2", &Usage{PromptTokens: 3})
+	events <- NewBlockEndEvent("b1", &Usage{PromptTokens: 7})
+	events <- NewRunFinishedEvent("run_child")
+	close(events)
+
+	require.NoError(t, parent.Attach(subBlockID, events, WithParentBlockID(parentBlockID)))
+	require.NoError(t, parent.AddEvent(NewBlockEndEvent(parentBlockID, nil)))
+	require.NoError(t, parent.AddEvent(NewRunFinishedEvent("run_parent")))
+
+	assert.Equal(t, "run_parent", parent.ID)
+	require.Len(t, parent.Blocks, 4)
+
+	sub := parent.Blocks[1]
+	assert.Equal(t, subBlockID, sub.ID)
+	assert.True(t, sub.IsSubagent)
+	assert.Equal(t, parentBlockID, sub.ParentBlockID)
+	assert.Equal(t, "run_child", sub.Metadata["subagent_run_id"])
+
+	b1 := parent.Blocks[2]
+	assert.Equal(t, subBlockID+"/b1", b1.ID)
+	assert.Equal(t, subBlockID, b1.ParentBlockID)
+	require.Len(t, b1.Contents, 1)
+	assert.Equal(t, "child says hi", b1.Contents[0].(*TextContent).Text)
+
+	b2 := parent.Blocks[3]
+	assert.Equal(t, subBlockID+"/b2", b2.ID)
+	assert.Equal(t, subBlockID+"/b1", b2.ParentBlockID)
+
+	tree, err := parent.BlockTree()
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), tree.Find(subBlockID).Usage().PromptTokens)
+	assert.Equal(t, int64(10), parent.Usage.PromptTokens)
+}
+
+func TestSubagentProxyRunError(t *testing.T) {
+	parent := NewCreator(nil)
+	proxy := NewSubagentProxy(parent, "sub")
+
+	require.NoError(t, proxy.AddEvent(NewBlockStartEvent("b1")))
+	err := proxy.AddEvent(NewRunErrorEvent("run_child", "boom"))
+	assert.ErrorIs(t, err, ErrSubagentRun)
+	// 子运行错误记录在子智能体区块上, 不终止父运行
+	assert.Empty(t, parent.Errors)
+	assert.False(t, parent.Finished())
+
+	require.Len(t, parent.Blocks, 2)
+	assert.True(t, parent.Blocks[0].IsSubagent)
+	assert.Equal(t, []RunError{{
+		Code:    ErrorCodeSubagent,
+		Message: "boom",
+		BlockID: "sub",
+		Details: map[string]any{"run_id": "run_child"},
+	}}, parent.Blocks[0].Errors)
+	assert.Error(t, proxy.AddEvent(NewBlockEndEvent("b1", nil)))
+}
+
+func TestCreatorAttachClosesOnChannelClose(t *testing.T) {
+	parent := NewCreator(nil)
+	events := make(chan Event, 2)
+	events <- NewContentStartEvent("c1", "")
+	events <- NewContentEndEvent("c1")
+	close(events)
+
+	require.NoError(t, parent.Attach("sub", events))
+	require.Len(t, parent.Blocks, 1)
+	assert.Equal(t, "sub", parent.Blocks[0].ID)
+	assert.True(t, parent.Blocks[0].IsSubagent)
+}
+
+func TestSubagentProxyCloseEndsOpenChildren(t *testing.T) {
+	parent := NewCreator(nil)
+	require.NoError(t, parent.AddEvent(NewRunStartedEvent("s1", "r1")))
+
+	p := NewSubagentProxy(parent, "sub")
+	require.NoError(t, p.AddEvent(NewRunStartedEvent("s2", "r2")))
+	require.NoError(t, p.AddEvent(NewBlockStartEvent("b1")))
+	require.NoError(t, p.AddEvent(NewBlockStartEvent("b2", WithParentBlockID("b1"))))
+	require.NoError(t, p.AddEvent(NewContentStartEvent("c1", "b2")))
+	require.NoError(t, p.AddEvent(NewContentDeltaEvent("c1", NewStreamTextContent("partial"))))
+
+	// 子运行提前中断
+	require.NoError(t, p.Close())
+	assert.Empty(t, parent.OpenContents())
+	assert.Empty(t, parent.OpenBlocks())
+	assert.Equal(t, "partial", parent.Blocks[2].Contents[0].(*TextContent).Text)
+}
@@ -0,0 +1,167 @@
+package acp
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrBlockCycle     = errors.New("block tree cycle detected")
+	ErrBlockOrphan    = errors.New("block parent not found")
+	ErrBlockDuplicate = errors.New("duplicate block id")
+)
+
+// 区块树节点, Block 指向 Message.Blocks 中的元素, Message.Blocks 扩容后需重新构建
+type BlockNode struct {
+	Block    *Block
+	Parent   *BlockNode
+	Children []*BlockNode
+}
+
+// Walk 先序遍历子树, fn 返回 false 时不再进入该节点的子节点
+func (n *BlockNode) Walk(fn func(node *BlockNode, depth int) bool) {
+	n.walk(fn, 0)
+}
+
+func (n *BlockNode) walk(fn func(node *BlockNode, depth int) bool, depth int) {
+	if !fn(n, depth) {
+		return
+	}
+	for _, c := range n.Children {
+		c.walk(fn, depth+1)
+	}
+}
+
+// Depth 根节点深度为 0
+func (n *BlockNode) Depth() int {
+	depth := 0
+	for p := n.Parent; p != nil; p = p.Parent {
+		depth++
+	}
+	return depth
+}
+
+// Ancestors 由近及远返回祖先节点
+func (n *BlockNode) Ancestors() []*BlockNode {
+	var nodes []*BlockNode
+	for p := n.Parent; p != nil; p = p.Parent {
+		nodes = append(nodes, p)
+	}
+	return nodes
+}
+
+// Descendants 先序返回所有后代节点, 不含自身
+func (n *BlockNode) Descendants() []*BlockNode {
+	var nodes []*BlockNode
+	n.Walk(func(node *BlockNode, _ int) bool {
+		if node != n {
+			nodes = append(nodes, node)
+		}
+		return true
+	})
+	return nodes
+}
+
+// Usage 汇总子树(含自身)的用量
+func (n *BlockNode) Usage() *Usage {
+	total := &Usage{}
+	n.Walk(func(node *BlockNode, _ int) bool {
+		total.Add(node.Block.Usage)
+		return true
+	})
+	return total
+}
+
+type BlockTree struct {
+	Roots []*BlockNode
+
+	nodes map[string]*BlockNode // block_id -> node
+}
+
+// Find 按区块 ID 查找节点
+func (t *BlockTree) Find(id string) *BlockNode {
+	return t.nodes[id]
+}
+
+// Walk 按区块顺序先序遍历所有根节点
+func (t *BlockTree) Walk(fn func(node *BlockNode, depth int) bool) {
+	for _, r := range t.Roots {
+		r.walk(fn, 0)
+	}
+}
+
+// Usage 汇总整棵树的用量
+func (t *BlockTree) Usage() *Usage {
+	total := &Usage{}
+	for _, r := range t.Roots {
+		total.Add(r.Usage())
+	}
+	return total
+}
+
+// BlockTree 按 ParentBlockID 构建区块树, 兄弟节点保持 Blocks 中的顺序
+func (m *Message) BlockTree() (*BlockTree, error) {
+	t := &BlockTree{
+		Roots: make([]*BlockNode, 0),
+		nodes: make(map[string]*BlockNode, len(m.Blocks)),
+	}
+
+	all := make([]*BlockNode, 0, len(m.Blocks))
+	for i := range m.Blocks {
+		node := &BlockNode{Block: &m.Blocks[i]}
+		all = append(all, node)
+
+		id := m.Blocks[i].ID
+		if id == "" {
+			continue
+		}
+		if _, ok := t.nodes[id]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrBlockDuplicate, id)
+		}
+		t.nodes[id] = node
+	}
+
+	for _, node := range all {
+		parentID := node.Block.ParentBlockID
+		if parentID == "" {
+			t.Roots = append(t.Roots, node)
+			continue
+		}
+
+		parent, ok := t.nodes[parentID]
+		if !ok {
+			return nil, fmt.Errorf("%w: block %s references %s", ErrBlockOrphan, node.Block.ID, parentID)
+		}
+		node.Parent = parent
+		parent.Children = append(parent.Children, node)
+	}
+
+	// 环上的节点无法从根节点到达
+	reached := 0
+	t.Walk(func(*BlockNode, int) bool {
+		reached++
+		return true
+	})
+	if reached != len(all) {
+		for _, node := range all {
+			if node.inCycle() {
+				return nil, fmt.Errorf("%w: %s", ErrBlockCycle, node.Block.ID)
+			}
+		}
+		return nil, ErrBlockCycle
+	}
+
+	return t, nil
+}
+
+func (n *BlockNode) inCycle() bool {
+	slow, fast := n, n
+	for fast != nil && fast.Parent != nil {
+		slow = slow.Parent
+		fast = fast.Parent.Parent
+		if slow == fast {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package acp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageBlockTree(t *testing.T) {
+	msg := Message{Blocks: []Block{
+		{ID: "root", Usage: &Usage{PromptTokens: 10}},
+		{ID: "sub", ParentBlockID: "root", IsSubagent: true, Usage: &Usage{PromptTokens: 5}},
+		{ID: "p1", ParentBlockID: "sub", IsParallel: true, Usage: &Usage{CompletionTokens: 1}},
+		{ID: "p2", ParentBlockID: "sub", IsParallel: true, Usage: &Usage{CompletionTokens: 2}},
+		{ID: "tail"},
+	}}
+
+	tree, err := msg.BlockTree()
+	require.NoError(t, err)
+	require.Len(t, tree.Roots, 2)
+	assert.Equal(t, "root", tree.Roots[0].Block.ID)
+	assert.Equal(t, "tail", tree.Roots[1].Block.ID)
+
+	sub := tree.Find("sub")
+	require.NotNil(t, sub)
+	assert.Equal(t, 1, sub.Depth())
+	require.Len(t, sub.Children, 2)
+	assert.Equal(t, "p1", sub.Children[0].Block.ID)
+	assert.Equal(t, "p2", sub.Children[1].Block.ID)
+
+	p2 := tree.Find("p2")
+	var ancestors []string
+	for _, n := range p2.Ancestors() {
+		ancestors = append(ancestors, n.Block.ID)
+	}
+	assert.Equal(t, []string{"sub", "root"}, ancestors)
+
+	var descendants []string
+	for _, n := range tree.Find("root").Descendants() {
+		descendants = append(descendants, n.Block.ID)
+	}
+	assert.Equal(t, []string{"sub", "p1", "p2"}, descendants)
+
+	usage := sub.Usage()
+	assert.Equal(t, int64(5), usage.PromptTokens)
+	assert.Equal(t, int64(3), usage.CompletionTokens)
+	assert.Equal(t, int64(15), tree.Usage().PromptTokens)
+
+	var visited []string
+	tree.Walk(func(n *BlockNode, depth int) bool {
+		visited = append(visited, n.Block.ID)
+		return n.Block.ID != "sub"
+	})
+	assert.Equal(t, []string{"root", "sub", "tail"}, visited)
+
+	assert.Nil(t, tree.Find("missing"))
+}
+
+func TestMessageBlockTreeErrors(t *testing.T) {
+	orphan := Message{Blocks: []Block{{ID: "a", ParentBlockID: "missing"}}}
+	_, err := orphan.BlockTree()
+	assert.ErrorIs(t, err, ErrBlockOrphan)
+
+	cycle := Message{Blocks: []Block{
+		{ID: "root"},
+		{ID: "a", ParentBlockID: "b"},
+		{ID: "b", ParentBlockID: "a"},
+	}}
+	_, err = cycle.BlockTree()
+	assert.ErrorIs(t, err, ErrBlockCycle)
+
+	self := Message{Blocks: []Block{{ID: "a", ParentBlockID: "a"}}}
+	_, err = self.BlockTree()
+	assert.ErrorIs(t, err, ErrBlockCycle)
+
+	duplicate := Message{Blocks: []Block{{ID: "a"}, {ID: "a"}}}
+	_, err = duplicate.BlockTree()
+	assert.ErrorIs(t, err, ErrBlockDuplicate)
+}
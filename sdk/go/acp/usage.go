@@ -0,0 +1,100 @@
+package acp
+
+// 用量统计, CachedPromptTokens 包含于 PromptTokens, ReasoningTokens 包含于 CompletionTokens
+type Usage struct {
+	PromptTokens       int64    `json:"prompt_tokens"`
+	CompletionTokens   int64    `json:"completion_tokens"`
+	CachedPromptTokens int64    `json:"cached_prompt_tokens,omitempty"`
+	ReasoningTokens    int64    `json:"reasoning_tokens,omitempty"`
+	Model              string   `json:"model,omitempty"`
+	LatencyMs          int64    `json:"latency_ms,omitempty"`
+	Cost               *float64 `json:"cost,omitempty"`
+
+	unpriced bool // 累加过无费用的用量, 合计费用未知
+}
+
+func (u *Usage) TotalTokens() int64 {
+	if u == nil {
+		return 0
+	}
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// Add 累加用量, 模型不一致时清空 Model.
+// 只有所有非空用量都有费用时才累加费用, 否则 Cost 为 nil 表示合计未知;
+// LatencyMs 只对单次调用有意义, 不累加.
+func (u *Usage) Add(o *Usage) {
+	if o == nil {
+		return
+	}
+
+	if u.PromptTokens == 0 && u.CompletionTokens == 0 && u.Model == "" {
+		u.Model = o.Model
+	} else if u.Model != o.Model {
+		u.Model = ""
+	}
+
+	u.PromptTokens += o.PromptTokens
+	u.CompletionTokens += o.CompletionTokens
+	u.CachedPromptTokens += o.CachedPromptTokens
+	u.ReasoningTokens += o.ReasoningTokens
+
+	switch {
+	case o.Cost == nil && (o.TotalTokens() > 0 || o.unpriced):
+		u.unpriced, u.Cost = true, nil
+	case o.Cost != nil && !u.unpriced:
+		cost := *o.Cost
+		if u.Cost != nil {
+			cost += *u.Cost
+		}
+		u.Cost = &cost
+	}
+}
+
+// 计费接口, 返回 false 表示无法计价
+type Pricer interface {
+	Cost(u *Usage) (float64, bool)
+}
+
+// 单价, 按每百万 token 计
+type Pricing struct {
+	PromptPerMTokens       float64 `json:"prompt_per_m_tokens"`
+	CompletionPerMTokens   float64 `json:"completion_per_m_tokens"`
+	CachedPromptPerMTokens float64 `json:"cached_prompt_per_m_tokens,omitempty"`
+}
+
+// 模型名 -> 单价
+type PricingTable map[string]Pricing
+
+func (t PricingTable) Cost(u *Usage) (float64, bool) {
+	if u == nil {
+		return 0, false
+	}
+
+	p, ok := t[u.Model]
+	if !ok {
+		return 0, false
+	}
+
+	uncached := u.PromptTokens - u.CachedPromptTokens
+	if uncached < 0 {
+		uncached = 0
+	}
+
+	cost := float64(uncached)*p.PromptPerMTokens +
+		float64(u.CachedPromptTokens)*p.CachedPromptPerMTokens +
+		float64(u.CompletionTokens)*p.CompletionPerMTokens
+	return cost / 1e6, true
+}
+
+// 运行性能摘要, 由事件时间戳计算, 时间单位为毫秒
+type RunMetrics struct {
+	DurationMs      int64   `json:"duration_ms"`
+	TTFTMs          *int64  `json:"ttft_ms,omitempty"`
+	InterTokenMs    float64 `json:"inter_token_ms,omitempty"` // 相邻文本增量的平均间隔
+	TokensPerSecond float64 `json:"tokens_per_second,omitempty"`
+	ToolCalls       int     `json:"tool_calls,omitempty"`
+	ToolErrors      int     `json:"tool_errors,omitempty"`
+	ToolDurationMs  int64   `json:"tool_duration_ms,omitempty"` // 工具调用耗时之和
+	Failed          bool    `json:"failed,omitempty"`
+}
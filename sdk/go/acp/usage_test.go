@@ -0,0 +1,73 @@
+package acp
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreatorRollsUpUsage(t *testing.T) {
+	pricing := PricingTable{
+		"gpt-4o": {PromptPerMTokens: 2.5, CompletionPerMTokens: 10, CachedPromptPerMTokens: 1.25},
+	}
+	creator := NewCreator(nil, WithPricer(pricing))
+	parentID := uuid.NewString()
+	childID := uuid.NewString()
+
+	parentUsage := &Usage{PromptTokens: 1000, CompletionTokens: 200, CachedPromptTokens: 400, Model: "gpt-4o", LatencyMs: 120}
+	childUsage := &Usage{PromptTokens: 500, CompletionTokens: 100, ReasoningTokens: 50, Model: "gpt-4o", LatencyMs: 80}
+
+	require.NoError(t, creator.AddEvent(NewBlockStartEvent(parentID)))
+	require.NoError(t, creator.AddEvent(NewBlockStartEvent(childID, WithIsSubagent(), WithParentBlockID(parentID))))
+	require.NoError(t, creator.AddEvent(NewBlockEndEvent(childID, childUsage)))
+	require.NoError(t, creator.AddEvent(NewBlockEndEvent(parentID, parentUsage)))
+
+	assert.Nil(t, parentUsage.Cost, "caller usage must not be mutated")
+
+	require.NotNil(t, creator.Blocks[0].Usage.Cost)
+	assert.InDelta(t, (600*2.5+400*1.25+200*10)/1e6, *creator.Blocks[0].Usage.Cost, 1e-12)
+
+	require.NotNil(t, creator.Usage)
+	assert.Equal(t, int64(1500), creator.Usage.PromptTokens)
+	assert.Equal(t, int64(300), creator.Usage.CompletionTokens)
+	assert.Equal(t, int64(400), creator.Usage.CachedPromptTokens)
+	assert.Equal(t, int64(50), creator.Usage.ReasoningTokens)
+	assert.Zero(t, creator.Usage.LatencyMs)
+	assert.Equal(t, int64(1800), creator.Usage.TotalTokens())
+	assert.Equal(t, "gpt-4o", creator.Usage.Model)
+	require.NotNil(t, creator.Usage.Cost)
+	assert.InDelta(t, (600*2.5+400*1.25+200*10+500*2.5+100*10)/1e6, *creator.Usage.Cost, 1e-12)
+}
+
+func TestUsageAddMixedModels(t *testing.T) {
+	total := &Usage{}
+	total.Add(&Usage{PromptTokens: 1, Model: "a"})
+	total.Add(&Usage{PromptTokens: 1, Model: "b"})
+	total.Add(nil)
+
+	assert.Equal(t, int64(2), total.PromptTokens)
+	assert.Empty(t, total.Model)
+	assert.Nil(t, total.Cost)
+}
+
+func TestUsageAddPartiallyPriced(t *testing.T) {
+	cost := 0.5
+	total := &Usage{}
+	total.Add(&Usage{PromptTokens: 10, Cost: &cost})
+	total.Add(&Usage{}) // 空用量不影响费用
+	require.NotNil(t, total.Cost)
+	assert.InDelta(t, 0.5, *total.Cost, 1e-12)
+
+	total.Add(&Usage{PromptTokens: 5, Model: "unknown"})
+	assert.Nil(t, total.Cost)
+	total.Add(&Usage{PromptTokens: 1, Cost: &cost})
+	assert.Nil(t, total.Cost, "cost stays unknown once any usage is unpriced")
+	assert.Equal(t, int64(16), total.PromptTokens)
+}
+
+func TestPricingTableUnknownModel(t *testing.T) {
+	_, ok := PricingTable{}.Cost(&Usage{Model: "unknown"})
+	assert.False(t, ok)
+}
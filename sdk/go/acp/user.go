@@ -0,0 +1,129 @@
+package acp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrUserMessage = errors.New("user message structure error")
+
+type userMessage struct {
+	text      string
+	files     []*FileContent
+	data      []*DataContent
+	variables map[string]any
+	answers   []*QAContent
+	metadata  map[string]any
+}
+
+type UserMessageOption func(*userMessage)
+
+// WithFile 附加已上传的文件
+func WithFile(mimeType, fileID string) UserMessageOption {
+	return func(u *userMessage) { u.files = append(u.files, NewFileContent(mimeType, fileID)) }
+}
+
+// WithData 附加内联数据
+func WithData(mimeType string, data []byte) UserMessageOption {
+	return func(u *userMessage) { u.data = append(u.data, NewDataContent(mimeType, data)) }
+}
+
+// WithVariables 合并变量, 多次调用时后者覆盖同名变量
+func WithVariables(variables map[string]any) UserMessageOption {
+	return func(u *userMessage) {
+		if u.variables == nil {
+			u.variables = make(map[string]any, len(variables))
+		}
+		for k, v := range variables {
+			u.variables[k] = v
+		}
+	}
+}
+
+func WithVariable(key string, value any) UserMessageOption {
+	return WithVariables(map[string]any{key: value})
+}
+
+// WithQAAnswer 回答助手发起的 QA
+func WithQAAnswer(qaid string, answer map[string]any) UserMessageOption {
+	return func(u *userMessage) {
+		qa := NewQAContent(qaid, "", "", "", nil)
+		qa.Answer = answer
+		u.answers = append(u.answers, qa)
+	}
+}
+
+// WithUserMetadata 设置用户消息区块的元数据
+func WithUserMetadata(metadata map[string]any) UserMessageOption {
+	return func(u *userMessage) { u.metadata = metadata }
+}
+
+// NewUserMessage 构建单区块的用户消息, 内容顺序为 文本/文件/数据/变量/QA 回答,
+// 与 GetInputs/GetVariables 的读取方式保持一致
+func NewUserMessage(text string, opts ...UserMessageOption) (*Message, error) {
+	u := &userMessage{text: text}
+	for _, o := range opts {
+		o(u)
+	}
+	if err := u.validate(); err != nil {
+		return nil, err
+	}
+
+	contents := make([]Content, 0, 2+len(u.files)+len(u.data)+len(u.answers))
+	contents = append(contents, NewTextContent("", u.text))
+	for _, f := range u.files {
+		contents = append(contents, f)
+	}
+	for _, d := range u.data {
+		contents = append(contents, d)
+	}
+	if u.variables != nil {
+		contents = append(contents, NewVariableContent(u.variables))
+	}
+	for _, a := range u.answers {
+		contents = append(contents, a)
+	}
+
+	now := time.Now().UnixMicro()
+	return &Message{
+		ID:   uuid.NewString(),
+		Role: RoleUser,
+		Blocks: []Block{{
+			ID:       uuid.NewString(),
+			Contents: contents,
+			Metadata: u.metadata,
+		}},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+func (u *userMessage) validate() error {
+	if u.text == "" && len(u.files) == 0 && len(u.data) == 0 && len(u.answers) == 0 {
+		return fmt.Errorf("%w: empty input", ErrUserMessage)
+	}
+	for _, f := range u.files {
+		if f.FileID == "" || f.MimeType == "" {
+			return fmt.Errorf("%w: file requires id and mime type", ErrUserMessage)
+		}
+	}
+	for _, d := range u.data {
+		if len(d.Origin) == 0 || d.MimeType == "" {
+			return fmt.Errorf("%w: data requires content and mime type", ErrUserMessage)
+		}
+	}
+	for k := range u.variables {
+		if k == "" {
+			return fmt.Errorf("%w: empty variable name", ErrUserMessage)
+		}
+	}
+	for _, a := range u.answers {
+		if a.QAID == "" || a.Answer == nil {
+			return fmt.Errorf("%w: qa answer requires id and answer", ErrUserMessage)
+		}
+	}
+	return nil
+}
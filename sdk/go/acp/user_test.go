@@ -0,0 +1,67 @@
+package acp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUserMessage(t *testing.T) {
+	msg, err := NewUserMessage("summarize",
+		WithFile("application/pdf", "file_1"),
+		WithData("image/png", []byte{0x89, 0x50}),
+		WithVariables(map[string]any{"lang": "en"}),
+		WithVariable("tone", "formal"),
+		WithQAAnswer("qa_1", map[string]any{"choice": "A"}),
+		WithUserMetadata(map[string]any{"client": "web"}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, RoleUser, msg.Role)
+	require.Len(t, msg.Blocks, 1)
+	assert.Equal(t, "web", msg.Blocks[0].Metadata["client"])
+
+	text, files := msg.GetInputs()
+	require.NotNil(t, text)
+	assert.Equal(t, "summarize", text.Text)
+	require.Len(t, files, 1)
+	assert.Equal(t, "file_1", files[0].FileID)
+
+	_, variables := msg.GetVariables()
+	require.NotNil(t, variables)
+	assert.Equal(t, map[string]any{"lang": "en", "tone": "formal"}, variables.Variables)
+
+	data, err := json.Marshal(msg)
+	require.NoError(t, err)
+
+	var decoded Message
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Len(t, decoded.Blocks[0].Contents, 5)
+	qa, ok := decoded.Blocks[0].Contents[4].(*QAContent)
+	require.True(t, ok)
+	assert.Equal(t, "qa_1", qa.QAID)
+	assert.Equal(t, map[string]any{"choice": "A"}, qa.Answer)
+}
+
+func TestNewUserMessageValidation(t *testing.T) {
+	cases := map[string][]UserMessageOption{
+		"empty":        nil,
+		"file":         {WithFile("", "file_1")},
+		"data":         {WithData("image/png", nil)},
+		"variable":     {WithVariable("", 1)},
+		"qa":           {WithQAAnswer("", map[string]any{"a": 1})},
+		"qa_no_answer": {WithQAAnswer("qa_1", nil)},
+	}
+	for name, opts := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewUserMessage("", opts...)
+			assert.ErrorIs(t, err, ErrUserMessage)
+		})
+	}
+
+	msg, err := NewUserMessage("", WithFile("image/png", "file_1"))
+	require.NoError(t, err)
+	text, _ := msg.GetInputs()
+	assert.Equal(t, "", text.Text)
+}
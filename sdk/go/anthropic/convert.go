@@ -0,0 +1,292 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+)
+
+type options struct {
+	dropThinking bool
+	fileURL      func(mimeType, fileID string) (string, error)
+	toolUseID    func(msg, block, content int) string
+	fallback     func(c acp.Content) (string, bool)
+}
+
+type Option func(*options)
+
+// WithoutThinking 丢弃所有思考内容, 默认保留带签名或加密的思考内容
+func WithoutThinking() Option {
+	return func(o *options) { o.dropThinking = true }
+}
+
+// WithFileURL 将图片类 FileContent/ArtifactContent 解析为可访问的 URL, 未设置时忽略文件
+func WithFileURL(fn func(mimeType, fileID string) (string, error)) Option {
+	return func(o *options) { o.fileURL = fn }
+}
+
+// WithToolUseID 自定义 tool_use ID, 默认 toolu_{消息}_{区块}_{内容}
+func WithToolUseID(fn func(msg, block, content int) string) Option {
+	return func(o *options) { o.toolUseID = fn }
+}
+
+// WithFallback 处理未支持的内容类型, 返回 false 时丢弃
+func WithFallback(fn func(c acp.Content) (string, bool)) Option {
+	return func(o *options) { o.fallback = fn }
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		toolUseID: func(msg, block, content int) string {
+			return fmt.Sprintf("toolu_%d_%d_%d", msg, block, content)
+		},
+	}
+	for _, fn := range opts {
+		fn(o)
+	}
+	return o
+}
+
+// ConvertMessages 将 ACP 消息历史转换为 Messages API 请求, 系统消息合并为 system,
+// 工具结果以 tool_result 放入随后的用户消息, 相邻同角色消息合并
+func ConvertMessages(msgs []acp.Message, opts ...Option) (*Request, error) {
+	o := newOptions(opts)
+
+	req := &Request{Messages: make([]MessageParam, 0, len(msgs))}
+	var system []string
+	for mi := range msgs {
+		msg := &msgs[mi]
+		switch msg.Role {
+		case acp.RoleSystem:
+			for _, b := range msg.Blocks {
+				for _, c := range b.Contents {
+					if text, ok := c.(*acp.TextContent); ok {
+						system = append(system, text.Text)
+					}
+				}
+			}
+
+		case acp.RoleUser, acp.RoleAssistant:
+			params, err := o.convert(mi, msg, mi == len(msgs)-1)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range params {
+				req.Messages = appendMessage(req.Messages, p)
+			}
+
+		default:
+			return nil, fmt.Errorf("unsupported role: %s", msg.Role)
+		}
+	}
+	req.System = strings.Join(system, "\n")
+	return req, nil
+}
+
+// appendMessage 合并相邻同角色消息, tool_result 须位于用户消息开头
+func appendMessage(msgs []MessageParam, p MessageParam) []MessageParam {
+	if len(p.Content) == 0 {
+		return msgs
+	}
+
+	n := len(msgs)
+	if n == 0 || msgs[n-1].Role != p.Role {
+		return append(msgs, p)
+	}
+
+	last := &msgs[n-1]
+	if p.Role == RoleUser {
+		var results, others []ContentBlock
+		for _, b := range append(last.Content, p.Content...) {
+			if b.Type == BlockTypeToolResult {
+				results = append(results, b)
+			} else {
+				others = append(others, b)
+			}
+		}
+		last.Content = append(results, others...)
+		return msgs
+	}
+
+	last.Content = append(last.Content, p.Content...)
+	return msgs
+}
+
+// convert 转换单条消息. last 表示历史中的最后一条消息,
+// 其末尾尚无结果的调用视为仍在执行, 不生成 tool_result, 由调用方在执行后补充
+func (o *options) convert(mi int, msg *acp.Message, last bool) ([]MessageParam, error) {
+	var out []MessageParam
+	cur := MessageParam{Role: msg.Role}
+	var results []ContentBlock
+	pending := 0 // 末尾连续的无结果调用数
+
+	flush := func() {
+		out = append(out, cur)
+		if len(results) > 0 {
+			out = append(out, MessageParam{Role: RoleUser, Content: results})
+		}
+		cur = MessageParam{Role: msg.Role}
+		results = nil
+	}
+
+	for bi, b := range msg.Blocks {
+		for ci, c := range b.Contents {
+			block, result, err := o.convertContent(msg.Role, o.toolUseID(mi, bi, ci), c)
+			if err != nil {
+				return nil, err
+			}
+			if block == nil {
+				continue
+			}
+
+			// 工具结果之后的新输出开启新一轮助手消息
+			if len(results) > 0 && block.Type != BlockTypeToolUse {
+				flush()
+			}
+			cur.Content = append(cur.Content, *block)
+			if result != nil {
+				results = append(results, *result)
+			}
+			if result != nil && result.Content == "" && !result.IsError {
+				pending++
+			} else {
+				pending = 0
+			}
+		}
+	}
+	if last && pending > 0 {
+		results = results[:len(results)-pending]
+	}
+	flush()
+
+	return out, nil
+}
+
+func (o *options) convertContent(role, id string, c acp.Content) (*ContentBlock, *ContentBlock, error) {
+	switch c := c.(type) {
+	case *acp.TextContent:
+		if c.Text == "" {
+			return nil, nil, nil
+		}
+		return textBlock(c.Text), nil, nil
+
+	case *acp.ThinkingContent:
+		if o.dropThinking || role != RoleAssistant {
+			return nil, nil, nil
+		}
+		if c.RedactedData != "" {
+			return &ContentBlock{Type: BlockTypeRedactedThinking, Data: c.RedactedData}, nil, nil
+		}
+		if c.Signature == "" {
+			return nil, nil, nil
+		}
+		return &ContentBlock{Type: BlockTypeThinking, Thinking: c.Text, Signature: c.Signature}, nil, nil
+
+	case *acp.ToolCallContent:
+		return o.toolUse(role, id, c.ToolName, c.ToolArgs, c.ToolResult, c.Error)
+
+	case *acp.MCPContent:
+		return o.toolUse(role, id, c.Server+"__"+c.ToolName, c.ToolArgs, c.ToolResult, c.Error)
+
+	case *acp.CommandContent:
+		result := c.Result
+		if c.ExitCode != nil {
+			result = fmt.Sprintf("%s\n[exit code: %d]", result, *c.ExitCode)
+		}
+		args := marshal(map[string]any{"command": c.Command})
+		return o.toolUse(role, id, acp.ContentTypeCommandExecution, args, result, c.Error)
+
+	case *acp.CodeExecutionContent:
+		args := marshal(map[string]any{"lang": c.Lang, "code": c.Code})
+		return o.toolUse(role, id, acp.ContentTypeCodeExecution, args, c.Result, c.Error)
+
+	case *acp.QAContent:
+		text := "Q: " + c.Message
+		if c.Answer != nil {
+			text += "\nA: " + marshal(c.Answer)
+		}
+		return textBlock(text), nil, nil
+
+	case *acp.DataContent:
+		if !isImage(c.MimeType) {
+			return nil, nil, nil
+		}
+		return &ContentBlock{
+			Type:   BlockTypeImage,
+			Source: &ImageSource{Type: SourceTypeBase64, MediaType: c.MimeType, Data: c.Data},
+		}, nil, nil
+
+	case *acp.FileContent:
+		return o.fileBlock(c.MimeType, c.FileID)
+
+	case *acp.ArtifactContent:
+		return o.fileBlock(c.MimeType, c.FileID)
+
+	default:
+		if o.fallback != nil {
+			if text, ok := o.fallback(c); ok {
+				return textBlock(text), nil, nil
+			}
+		}
+		return nil, nil, nil
+	}
+}
+
+func (o *options) toolUse(role, id, name, args, result string, e *acp.Error) (*ContentBlock, *ContentBlock, error) {
+	if role != RoleAssistant {
+		text := fmt.Sprintf("[%s] %s", name, args)
+		if result != "" {
+			text += "\n" + result
+		}
+		return textBlock(text), nil, nil
+	}
+
+	input := json.RawMessage(args)
+	if strings.TrimSpace(args) == "" {
+		input = json.RawMessage("{}")
+	} else if !json.Valid(input) {
+		return nil, nil, fmt.Errorf("tool %s has invalid json args", name)
+	}
+
+	use := &ContentBlock{Type: BlockTypeToolUse, ID: id, Name: name, Input: input}
+	// 每个 tool_use 都需对应的 tool_result, 无结果时 content 为空
+	res := &ContentBlock{Type: BlockTypeToolResult, ToolUseID: id, Content: result}
+	if e != nil {
+		res.Content = fmt.Sprintf("%s: %s", e.Type, e.Message)
+		res.IsError = true
+	}
+	return use, res, nil
+}
+
+func (o *options) fileBlock(mimeType, fileID string) (*ContentBlock, *ContentBlock, error) {
+	if !isImage(mimeType) || o.fileURL == nil {
+		return nil, nil, nil
+	}
+
+	url, err := o.fileURL(mimeType, fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &ContentBlock{
+		Type:   BlockTypeImage,
+		Source: &ImageSource{Type: SourceTypeURL, URL: url},
+	}, nil, nil
+}
+
+func textBlock(text string) *ContentBlock {
+	return &ContentBlock{Type: BlockTypeText, Text: text}
+}
+
+func marshal(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func isImage(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "image/")
+}
@@ -0,0 +1,102 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func loadHistory(t *testing.T) []acp.Message {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", "history.json"))
+	require.NoError(t, err)
+
+	var msgs []acp.Message
+	require.NoError(t, json.Unmarshal(data, &msgs))
+	return msgs
+}
+
+func assertGolden(t *testing.T, name string, v any) {
+	t.Helper()
+
+	got, err := json.MarshalIndent(v, "", "  ")
+	require.NoError(t, err)
+	got = append(got, '\n')
+
+	path := filepath.Join("testdata", name+".golden.json")
+	if *update {
+		require.NoError(t, os.WriteFile(path, got, 0o644))
+	}
+
+	want, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(want), string(got))
+}
+
+func TestConvertMessagesGolden(t *testing.T) {
+	fileURL := func(mimeType, fileID string) (string, error) {
+		return "https://files.example.com/" + fileID, nil
+	}
+
+	req, err := ConvertMessages(loadHistory(t), WithFileURL(fileURL))
+	require.NoError(t, err)
+	assertGolden(t, "convert_default", req)
+
+	req, err = ConvertMessages(loadHistory(t), WithoutThinking())
+	require.NoError(t, err)
+	assertGolden(t, "convert_without_thinking", req)
+}
+
+func TestConvertMessagesAlternatesRoles(t *testing.T) {
+	req, err := ConvertMessages(loadHistory(t))
+	require.NoError(t, err)
+
+	for i := 1; i < len(req.Messages); i++ {
+		assert.NotEqual(t, req.Messages[i-1].Role, req.Messages[i].Role)
+	}
+}
+
+func TestConvertMessagesRejectsInvalidArgs(t *testing.T) {
+	msgs := []acp.Message{{
+		Role: acp.RoleAssistant,
+		Blocks: []acp.Block{{Contents: []acp.Content{
+			&acp.ToolCallContent{ToolName: "broken", ToolArgs: "{"},
+		}}},
+	}}
+	_, err := ConvertMessages(msgs)
+	assert.Error(t, err)
+}
+
+func TestConvertMessagesEmptyToolResult(t *testing.T) {
+	call := acp.NewToolCallContent("touch")
+	call.ToolArgs = `{"path":"/tmp/x"}`
+	pending := acp.NewToolCallContent("get_weather")
+	pending.ToolArgs = `{"city":"Paris"}`
+	msgs := []acp.Message{
+		{Role: acp.RoleAssistant, Blocks: []acp.Block{{Contents: []acp.Content{call}}}},
+		{Role: acp.RoleUser, Blocks: []acp.Block{{Contents: []acp.Content{acp.NewTextContent("", "weather?")}}}},
+		{Role: acp.RoleAssistant, Blocks: []acp.Block{{Contents: []acp.Content{pending}}}},
+	}
+
+	req, err := ConvertMessages(msgs)
+	require.NoError(t, err)
+	require.Len(t, req.Messages, 3)
+
+	// 已结束但输出为空的调用仍有对应的 tool_result, 位于随后用户消息的开头
+	user := req.Messages[1].Content
+	require.Len(t, user, 2)
+	assert.Equal(t, ContentBlock{Type: BlockTypeToolResult, ToolUseID: "toolu_0_0_0"}, user[0])
+	assert.Equal(t, BlockTypeText, user[1].Type)
+	// 末尾仍在执行的调用不生成 tool_result
+	assert.Equal(t, RoleAssistant, req.Messages[2].Role)
+	assert.Equal(t, BlockTypeToolUse, req.Messages[2].Content[0].Type)
+}
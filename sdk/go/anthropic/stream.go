@@ -0,0 +1,332 @@
+package anthropic
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/llm-infra/acp/sdk/go/acp"
+)
+
+// Stream Event Type
+const (
+	EventMessageStart      = "message_start"
+	EventMessageDelta      = "message_delta"
+	EventMessageStop       = "message_stop"
+	EventContentBlockStart = "content_block_start"
+	EventContentBlockDelta = "content_block_delta"
+	EventContentBlockStop  = "content_block_stop"
+	EventPing              = "ping"
+	EventError             = "error"
+)
+
+// Delta Type
+const (
+	DeltaTypeText      = "text_delta"
+	DeltaTypeThinking  = "thinking_delta"
+	DeltaTypeSignature = "signature_delta"
+	DeltaTypeInputJSON = "input_json_delta"
+)
+
+var ErrStream = errors.New("anthropic stream error")
+
+type StreamEvent struct {
+	Type         string         `json:"type"`
+	Index        int            `json:"index"`
+	Message      *StreamMessage `json:"message,omitempty"`
+	ContentBlock *ContentBlock  `json:"content_block,omitempty"`
+	Delta        *StreamDelta   `json:"delta,omitempty"`
+	Usage        *Usage         `json:"usage,omitempty"`
+	Error        *acp.Error     `json:"error,omitempty"`
+}
+
+type StreamMessage struct {
+	ID    string `json:"id"`
+	Model string `json:"model"`
+	Usage *Usage `json:"usage,omitempty"`
+}
+
+type StreamDelta struct {
+	Type        string `json:"type,omitempty"`
+	Text        string `json:"text,omitempty"`
+	Thinking    string `json:"thinking,omitempty"`
+	Signature   string `json:"signature,omitempty"`
+	PartialJSON string `json:"partial_json,omitempty"`
+	StopReason  string `json:"stop_reason,omitempty"`
+}
+
+// 将 Messages API 流式事件转换为 ACP 事件并写入 Creator, 每条助手消息对应一个区块:
+//   - message_start 开启区块, message_stop 以累计用量结束区块
+//   - content_block_start/delta/stop 对应 ContentStart/Delta/End
+//   - error 记录为区块错误, 并结束未完成内容与区块
+//   - text/thinking/tool_use 分别转为文本/思考/工具调用内容, redacted_thinking 转为仅含加密数据的思考内容
+//
+// 运行生命周期事件由调用方负责
+type StreamAdapter struct {
+	creator *acp.Creator
+	blockID string
+	opts    []acp.BlockOption
+
+	started   bool
+	closed    bool
+	startedAt time.Time
+	model     string
+	usage     Usage
+	contents  map[int]string // content block index -> content_id
+}
+
+func NewStreamAdapter(creator *acp.Creator, blockID string, opts ...acp.BlockOption) *StreamAdapter {
+	return &StreamAdapter{
+		creator:  creator,
+		blockID:  blockID,
+		opts:     opts,
+		contents: make(map[int]string),
+	}
+}
+
+// ReadStream 读取 SSE 格式的事件流直至 message_stop 或 EOF, 并结束区块
+func (a *StreamAdapter) ReadStream(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		data, ok := bytes.CutPrefix(line, []byte("data:"))
+		if !ok {
+			continue
+		}
+		if err := a.AddEventData(bytes.TrimSpace(data)); err != nil {
+			return err
+		}
+		if a.closed {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return a.Close()
+}
+
+// AddEventData 处理单个流式事件的 JSON
+func (a *StreamAdapter) AddEventData(data []byte) error {
+	var evt StreamEvent
+	if err := json.Unmarshal(data, &evt); err != nil {
+		return err
+	}
+	return a.Add(&evt)
+}
+
+func (a *StreamAdapter) Add(evt *StreamEvent) error {
+	if a.closed {
+		return errors.New("stream adapter already closed")
+	}
+
+	switch evt.Type {
+	case EventPing:
+		return nil
+
+	case EventError:
+		return a.fail(evt.Error)
+
+	case EventMessageStart:
+		if evt.Message != nil {
+			a.model = evt.Message.Model
+			a.addUsage(evt.Message.Usage)
+		}
+		return a.start()
+
+	case EventMessageDelta:
+		a.addUsage(evt.Usage)
+		return nil
+
+	case EventMessageStop:
+		return a.Close()
+
+	case EventContentBlockStart:
+		if err := a.start(); err != nil {
+			return err
+		}
+		return a.startContent(evt.Index, evt.ContentBlock)
+
+	case EventContentBlockDelta:
+		return a.delta(evt.Index, evt.Delta)
+
+	case EventContentBlockStop:
+		return a.endContent(evt.Index)
+
+	default:
+		return nil
+	}
+}
+
+// Close 结束所有未完成内容并结束区块
+func (a *StreamAdapter) Close() error {
+	if a.closed {
+		return nil
+	}
+	if err := a.start(); err != nil {
+		return err
+	}
+
+	indexes := make([]int, 0, len(a.contents))
+	for i := range a.contents {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	for _, i := range indexes {
+		if err := a.endContent(i); err != nil {
+			return err
+		}
+	}
+
+	a.closed = true
+	return a.creator.AddEvent(acp.NewBlockEndEvent(a.blockID, &acp.Usage{
+		PromptTokens:       a.usage.InputTokens + a.usage.CacheReadInputTokens + a.usage.CacheCreationInputTokens,
+		CompletionTokens:   a.usage.OutputTokens,
+		CachedPromptTokens: a.usage.CacheReadInputTokens,
+		Model:              a.model,
+		LatencyMs:          time.Since(a.startedAt).Milliseconds(),
+	}))
+}
+
+// fail 将流错误记录到区块上, 结束未完成内容与区块后返回错误
+func (a *StreamAdapter) fail(e *acp.Error) error {
+	re := streamError(e)
+	err := fmt.Errorf("%w: %w", ErrStream, re)
+	if e != nil {
+		err = fmt.Errorf("%w: %s: %w", ErrStream, e.Type, re)
+	}
+
+	if startErr := a.start(); startErr != nil {
+		return errors.Join(err, startErr)
+	}
+	if addErr := a.creator.AddEvent(acp.NewBlockErrorEvent(a.blockID, re)); addErr != nil {
+		return errors.Join(err, addErr)
+	}
+	if closeErr := a.Close(); closeErr != nil {
+		return errors.Join(err, closeErr)
+	}
+	return err
+}
+
+// streamError 按错误类型映射错误码, 过载与限流可重试
+func streamError(e *acp.Error) *acp.RunError {
+	if e == nil {
+		return &acp.RunError{Code: acp.ErrorCodeInternal, Message: "stream error"}
+	}
+
+	re := &acp.RunError{Code: acp.ErrorCodeInternal, Message: e.Message, Details: map[string]any{"type": e.Type}}
+	switch e.Type {
+	case "overloaded_error":
+		re.Code, re.Retryable = acp.ErrorCodeUnavailable, true
+	case "rate_limit_error":
+		re.Code, re.Retryable = acp.ErrorCodeRateLimited, true
+	case "invalid_request_error":
+		re.Code = acp.ErrorCodeInvalidInput
+	}
+	return re
+}
+
+func (a *StreamAdapter) start() error {
+	if a.started {
+		return nil
+	}
+
+	a.started = true
+	a.startedAt = time.Now()
+	return a.creator.AddEvent(acp.NewBlockStartEvent(a.blockID, a.opts...))
+}
+
+// addUsage message_delta 中的用量为累计值, 非零字段覆盖
+func (a *StreamAdapter) addUsage(u *Usage) {
+	if u == nil {
+		return
+	}
+	if u.InputTokens != 0 {
+		a.usage.InputTokens = u.InputTokens
+	}
+	if u.OutputTokens != 0 {
+		a.usage.OutputTokens = u.OutputTokens
+	}
+	if u.CacheReadInputTokens != 0 {
+		a.usage.CacheReadInputTokens = u.CacheReadInputTokens
+	}
+	if u.CacheCreationInputTokens != 0 {
+		a.usage.CacheCreationInputTokens = u.CacheCreationInputTokens
+	}
+}
+
+func (a *StreamAdapter) startContent(index int, block *ContentBlock) error {
+	if block == nil {
+		return fmt.Errorf("%w: content block %d missing", ErrStream, index)
+	}
+
+	id := uuid.NewString()
+	a.contents[index] = id
+	if err := a.creator.AddEvent(acp.NewContentStartEvent(id, a.blockID)); err != nil {
+		return err
+	}
+
+	var sc acp.StreamContent
+	switch block.Type {
+	case BlockTypeText:
+		if block.Text != "" {
+			sc = acp.NewStreamTextContent(block.Text)
+		}
+	case BlockTypeThinking:
+		if block.Thinking != "" {
+			sc = acp.NewStreamThinkingContent(block.Thinking)
+		}
+	case BlockTypeRedactedThinking:
+		// 无可展示内容, 保留加密数据以便回传
+		sc = acp.NewStreamRedactedThinkingContent(block.Data)
+	case BlockTypeToolUse:
+		sc = acp.NewStreamToolCallContent(block.Name)
+	}
+	if sc == nil {
+		return nil
+	}
+	return a.creator.AddEvent(acp.NewContentDeltaEvent(id, sc))
+}
+
+func (a *StreamAdapter) delta(index int, d *StreamDelta) error {
+	id, ok := a.contents[index]
+	if !ok || d == nil {
+		return fmt.Errorf("%w: delta for unknown content block %d", ErrStream, index)
+	}
+
+	var sc acp.StreamContent
+	switch d.Type {
+	case DeltaTypeText:
+		sc = acp.NewStreamTextContent(d.Text)
+	case DeltaTypeThinking:
+		sc = acp.NewStreamThinkingContent(d.Thinking)
+	case DeltaTypeSignature:
+		sc = acp.NewStreamThinkingSignatureContent(d.Signature)
+	case DeltaTypeInputJSON:
+		if d.PartialJSON == "" {
+			return nil
+		}
+		sc = acp.NewStreamToolArgsContent(d.PartialJSON)
+	default:
+		return nil
+	}
+	return a.creator.AddEvent(acp.NewContentDeltaEvent(id, sc))
+}
+
+func (a *StreamAdapter) endContent(index int) error {
+	id, ok := a.contents[index]
+	if !ok {
+		return nil
+	}
+
+	delete(a.contents, index)
+	return a.creator.AddEvent(acp.NewContentEndEvent(id))
+}
@@ -0,0 +1,115 @@
+package anthropic
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func replay(t *testing.T, name string) (*acp.Creator, error) {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	defer f.Close()
+
+	creator := acp.NewCreator(nil)
+	return creator, NewStreamAdapter(creator, "b1").ReadStream(f)
+}
+
+func TestStreamAdapterToolUse(t *testing.T) {
+	creator, err := replay(t, "stream_tool_use.sse")
+	require.NoError(t, err)
+
+	require.Len(t, creator.Blocks, 1)
+	block := creator.Blocks[0]
+	require.Len(t, block.Contents, 3)
+
+	thinking, ok := block.Contents[0].(*acp.ThinkingContent)
+	require.True(t, ok)
+	assert.Equal(t, "Need the weather for Paris.", thinking.Text)
+	assert.Equal(t, "EqQBCgIYAhIM", thinking.Signature)
+
+	text, ok := block.Contents[1].(*acp.TextContent)
+	require.True(t, ok)
+	assert.Equal(t, "Let me check.", text.Text)
+
+	call, ok := block.Contents[2].(*acp.ToolCallContent)
+	require.True(t, ok)
+	assert.Equal(t, "get_weather", call.ToolName)
+	assert.JSONEq(t, `{"city":"Paris"}`, call.ToolArgs)
+
+	require.NotNil(t, block.Usage)
+	assert.Equal(t, int64(140), block.Usage.PromptTokens)
+	assert.Equal(t, int64(100), block.Usage.CachedPromptTokens)
+	assert.Equal(t, int64(57), block.Usage.CompletionTokens)
+	assert.Equal(t, "claude-sonnet-4-5", block.Usage.Model)
+}
+
+func TestStreamAdapterError(t *testing.T) {
+	creator, err := replay(t, "stream_error.sse")
+	assert.ErrorIs(t, err, ErrStream)
+	assert.Contains(t, err.Error(), "overloaded_error")
+
+	re := acp.ToRunError(err)
+	assert.Equal(t, acp.ErrorCodeUnavailable, re.Code)
+	assert.True(t, re.Retryable)
+
+	// 错误记录在区块上, 区块与未完成内容均已结束
+	require.Len(t, creator.Blocks, 1)
+	require.Len(t, creator.Blocks[0].Errors, 1)
+	assert.Equal(t, acp.ErrorCodeUnavailable, creator.Blocks[0].Errors[0].Code)
+	assert.Equal(t, "Overloaded", creator.Blocks[0].Errors[0].Message)
+	require.Len(t, creator.Blocks[0].Contents, 1)
+	assert.Equal(t, "Partial", creator.Blocks[0].Contents[0].(*acp.TextContent).Text)
+	assert.NoError(t, creator.AddEvent(acp.NewRunFinishedEvent("")))
+}
+
+func TestStreamRoundTripThroughConverter(t *testing.T) {
+	creator, err := replay(t, "stream_tool_use.sse")
+	require.NoError(t, err)
+
+	req, err := ConvertMessages([]acp.Message{*creator.Message})
+	require.NoError(t, err)
+	// 流中的工具调用尚未执行, 不生成 tool_result
+	require.Len(t, req.Messages, 1)
+
+	blocks := req.Messages[0].Content
+	require.Len(t, blocks, 3)
+	assert.Equal(t, BlockTypeThinking, blocks[0].Type)
+	assert.Equal(t, "EqQBCgIYAhIM", blocks[0].Signature)
+	assert.Equal(t, BlockTypeToolUse, blocks[2].Type)
+}
+
+func TestStreamRedactedThinkingRoundTrip(t *testing.T) {
+	creator := acp.NewCreator(nil)
+	adapter := NewStreamAdapter(creator, "b1")
+	for _, data := range []string{
+		`{"type":"message_start","message":{"id":"msg_1","model":"claude-sonnet-4-5"}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"redacted_thinking","data":"EmwKAhgBEgy3va3pzix"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"text","text":"Done."}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"message_stop"}`,
+	} {
+		require.NoError(t, adapter.AddEventData([]byte(data)))
+	}
+
+	require.Len(t, creator.Blocks[0].Contents, 2)
+	thinking, ok := creator.Blocks[0].Contents[0].(*acp.ThinkingContent)
+	require.True(t, ok)
+	assert.Empty(t, thinking.Text)
+	assert.Equal(t, "EmwKAhgBEgy3va3pzix", thinking.RedactedData)
+
+	req, err := ConvertMessages([]acp.Message{*creator.Message})
+	require.NoError(t, err)
+	require.Len(t, req.Messages, 1)
+	blocks := req.Messages[0].Content
+	require.Len(t, blocks, 2)
+	assert.Equal(t, ContentBlock{Type: BlockTypeRedactedThinking, Data: "EmwKAhgBEgy3va3pzix"}, blocks[0])
+	assert.Equal(t, BlockTypeText, blocks[1].Type)
+}
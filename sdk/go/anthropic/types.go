@@ -0,0 +1,74 @@
+package anthropic
+
+import "encoding/json"
+
+// Messages API 角色
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Content Block Type
+const (
+	BlockTypeText             = "text"
+	BlockTypeImage            = "image"
+	BlockTypeToolUse          = "tool_use"
+	BlockTypeToolResult       = "tool_result"
+	BlockTypeThinking         = "thinking"
+	BlockTypeRedactedThinking = "redacted_thinking"
+)
+
+// Image Source Type
+const (
+	SourceTypeBase64 = "base64"
+	SourceTypeURL    = "url"
+)
+
+type Request struct {
+	System   string         `json:"system,omitempty"`
+	Messages []MessageParam `json:"messages"`
+}
+
+type MessageParam struct {
+	Role    string         `json:"role"`
+	Content []ContentBlock `json:"content"`
+}
+
+type ContentBlock struct {
+	Type string `json:"type"`
+
+	// text
+	Text string `json:"text,omitempty"`
+
+	// image
+	Source *ImageSource `json:"source,omitempty"`
+
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+	IsError   bool   `json:"is_error,omitempty"`
+
+	// thinking
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
+}
+
+type ImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type Usage struct {
+	InputTokens              int64 `json:"input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens,omitempty"`
+}
@@ -0,0 +1,238 @@
+// Package approval 以 QA 问题的形式对工具、MCP 和命令调用进行人工审批
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/llm-infra/acp/sdk/go/acp"
+	"github.com/llm-infra/acp/sdk/go/qa"
+)
+
+// 审批问题的 QAType
+const QAType = "approval"
+
+// 调用类型
+const (
+	KindTool    = "tool"
+	KindMCP     = "mcp"
+	KindCommand = "command"
+)
+
+// 拒绝执行时写入调用结果的错误类型
+const ErrorTypeDenied = "denied"
+
+var ErrInvalidAnswer = errors.New("invalid approval answer")
+
+// 待审批的调用, Args 为工具参数(JSON)或命令文本
+type Request struct {
+	Kind     string `json:"kind"`
+	ToolName string `json:"tool_name,omitempty"`
+	Server   string `json:"server,omitempty"`
+	Args     string `json:"args"`
+}
+
+// RequestOf 从工具/MCP/命令内容构造审批请求
+func RequestOf(c acp.Content) (Request, bool) {
+	switch c := c.(type) {
+	case *acp.ToolCallContent:
+		return Request{Kind: KindTool, ToolName: c.ToolName, Args: c.ToolArgs}, true
+	case *acp.MCPContent:
+		return Request{Kind: KindMCP, ToolName: c.ToolName, Server: c.Server, Args: c.ToolArgs}, true
+	case *acp.CommandContent:
+		return Request{Kind: KindCommand, Args: c.Command}, true
+	default:
+		return Request{}, false
+	}
+}
+
+func (r Request) options() map[string]any {
+	opts := map[string]any{"kind": r.Kind, "args": r.Args}
+	if r.ToolName != "" {
+		opts["tool_name"] = r.ToolName
+	}
+	if r.Server != "" {
+		opts["server"] = r.Server
+	}
+	return opts
+}
+
+func (r Request) message() string {
+	switch r.Kind {
+	case KindMCP:
+		return fmt.Sprintf("Allow MCP tool %s/%s to run?", r.Server, r.ToolName)
+	case KindCommand:
+		return "Allow command to run?"
+	default:
+		return fmt.Sprintf("Allow tool %s to run?", r.ToolName)
+	}
+}
+
+// 审批策略, 返回 true 表示需要人工审批
+type Policy func(Request) bool
+
+// Always 所有调用都需要审批
+func Always() Policy {
+	return func(Request) bool { return true }
+}
+
+// Tools 指定名称的工具(含 MCP 工具)需要审批
+func Tools(names ...string) Policy {
+	return func(r Request) bool {
+		return r.Kind != KindCommand && slices.Contains(names, r.ToolName)
+	}
+}
+
+// MCPServers 指定 MCP 服务的工具需要审批
+func MCPServers(servers ...string) Policy {
+	return func(r Request) bool {
+		return r.Kind == KindMCP && slices.Contains(servers, r.Server)
+	}
+}
+
+// Kinds 指定类型的调用需要审批
+func Kinds(kinds ...string) Policy {
+	return func(r Request) bool { return slices.Contains(kinds, r.Kind) }
+}
+
+// Any 任一策略要求审批即需要审批
+func Any(policies ...Policy) Policy {
+	return func(r Request) bool {
+		for _, p := range policies {
+			if p(r) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+type Option func(*Gate)
+
+// WithPolicy 设置审批策略, 默认 Always
+func WithPolicy(p Policy) Option {
+	return func(g *Gate) { g.policy = p }
+}
+
+// 工具执行审批, 以 QA 问题的形式向用户确认调用.
+// 审批问题与调用内容位于同一区块, 结果以 approval 增量写入调用内容, 最终记录在 Message 中.
+type Gate struct {
+	coord  *qa.Coordinator
+	policy Policy
+}
+
+// New 创建审批网关, coord 应以 qa.WithValidator(ValidateAnswer) 创建以便拒绝无效回答
+func New(coord *qa.Coordinator, opts ...Option) *Gate {
+	g := &Gate{coord: coord, policy: Always()}
+	for _, o := range opts {
+		o(g)
+	}
+	return g
+}
+
+// Review 审批 contentID 指向的调用, 须在写入 m 的协程中调用, 调用内容需已开始且未结束.
+// 策略无需审批时自动批准; 否则在 blockID 下发出审批问题并阻塞等待:
+//   - 回答 approve/deny/edit 按回答记录
+//   - 超时视为拒绝, 不返回错误
+//   - ctx 取消时记录拒绝并返回错误
+//
+// 未批准时同时以 ErrorTypeDenied 错误写入调用结果, 使每个调用都有结果.
+// 调用方按返回结果的 Approved 与 ArgsOr 决定是否及如何执行, 未批准时无需再写入结果.
+func (g *Gate) Review(ctx context.Context, m *acp.Creator, blockID, contentID string, req Request) (acp.Approval, error) {
+	if !g.policy(req) {
+		approval := acp.Approval{Decision: acp.ApprovalApprove, Auto: true}
+		return approval, record(ctx, m, contentID, req.Kind, approval)
+	}
+
+	qaid := uuid.NewString()
+	q := acp.NewStreamQAContent(qaid, QAType, req.ToolName, req.message(), req.options())
+	res, err := g.coord.Ask(ctx, m, blockID, uuid.NewString(), q)
+
+	var approval acp.Approval
+	switch {
+	case err == nil:
+		// 包含超时后使用 qa.WithTimeoutAnswer 默认回答的情况
+		approval, err = parseAnswer(req, res.Answer)
+		if err != nil {
+			approval, err = acp.Approval{Decision: acp.ApprovalDeny, Reason: err.Error()}, nil
+		}
+	case errors.Is(err, qa.ErrTimeout):
+		approval, err = acp.Approval{Decision: acp.ApprovalDeny, Reason: "approval timed out"}, nil
+	case errors.Is(err, qa.ErrCanceled):
+		approval = acp.Approval{Decision: acp.ApprovalDeny, Reason: "approval canceled"}
+	default:
+		return acp.Approval{}, err
+	}
+	approval.QAID = qaid
+
+	if recErr := record(ctx, m, contentID, req.Kind, approval); recErr != nil {
+		return approval, errors.Join(err, recErr)
+	}
+	return approval, err
+}
+
+// record 写入审批结果, 未批准时再写入拒绝错误作为调用结果
+func record(ctx context.Context, m *acp.Creator, contentID, kind string, approval acp.Approval) error {
+	ctx = context.WithoutCancel(ctx)
+	if err := m.AddEventContext(ctx, acp.NewContentDeltaEvent(contentID, acp.NewStreamApprovalContent(approval))); err != nil {
+		return err
+	}
+	if approval.Approved() {
+		return nil
+	}
+
+	reason := approval.Reason
+	if reason == "" {
+		reason = "approval denied"
+	}
+	denied := &acp.Error{Type: ErrorTypeDenied, Message: reason}
+	var result acp.StreamContent
+	switch kind {
+	case KindMCP:
+		result = acp.NewStreamMCPErrorContent(denied)
+	case KindCommand:
+		result = acp.NewStreamCommandErrorContent(denied)
+	default:
+		result = acp.NewStreamToolErrorContent(denied)
+	}
+	return m.AddEventContext(ctx, acp.NewContentDeltaEvent(contentID, result))
+}
+
+// ValidateAnswer 校验审批问题的回答, 可用于 qa.WithValidator; 非审批问题直接通过.
+// 回答格式为 {"decision": "approve|deny|edit", "args": "...", "reason": "..."},
+// edit 必须提供 args, 工具与 MCP 的 args 必须是合法 JSON.
+func ValidateAnswer(q qa.Question, answer map[string]any) error {
+	if q.QAType != QAType {
+		return nil
+	}
+	kind, _ := q.Options["kind"].(string)
+	_, err := parseAnswer(Request{Kind: kind}, answer)
+	return err
+}
+
+func parseAnswer(req Request, answer map[string]any) (acp.Approval, error) {
+	var a acp.Approval
+	a.Decision, _ = answer["decision"].(string)
+	a.Reason, _ = answer["reason"].(string)
+
+	switch a.Decision {
+	case acp.ApprovalApprove, acp.ApprovalDeny:
+		return a, nil
+	case acp.ApprovalEdit:
+		args, ok := answer["args"].(string)
+		if !ok || args == "" {
+			return a, fmt.Errorf("%w: edit requires args", ErrInvalidAnswer)
+		}
+		if req.Kind != KindCommand && !json.Valid([]byte(args)) {
+			return a, fmt.Errorf("%w: args must be valid JSON", ErrInvalidAnswer)
+		}
+		a.Args = args
+		return a, nil
+	default:
+		return a, fmt.Errorf("%w: unknown decision %q", ErrInvalidAnswer, a.Decision)
+	}
+}
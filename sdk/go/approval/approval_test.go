@@ -0,0 +1,215 @@
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+	"github.com/llm-infra/acp/sdk/go/qa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRun(t *testing.T, c *qa.Coordinator) *acp.Creator {
+	t.Helper()
+	m := acp.NewCreator(nil, acp.WithInterceptors(c.Intercept))
+	require.NoError(t, m.AddEvent(acp.NewRunStartedEvent("s1", "r1")))
+	require.NoError(t, m.AddEvent(acp.NewBlockStartEvent("b1")))
+	return m
+}
+
+func startTool(t *testing.T, m *acp.Creator, contentID, name, args string) {
+	t.Helper()
+	require.NoError(t, m.AddEvent(acp.NewContentStartEvent(contentID, "b1")))
+	require.NoError(t, m.AddEvent(acp.NewContentDeltaEvent(contentID, acp.NewStreamToolCallContent(name))))
+	require.NoError(t, m.AddEvent(acp.NewContentDeltaEvent(contentID, acp.NewStreamToolArgsContent(args))))
+}
+
+// answer 等审批问题登记后提交回答
+func answer(c *qa.Coordinator, payload map[string]any) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		for range 1000 {
+			if pending := c.Pending(); len(pending) > 0 {
+				done <- c.Deliver(pending[0].QAID, payload)
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		done <- errors.New("question never registered")
+	}()
+	return done
+}
+
+func finish(t *testing.T, m *acp.Creator, contentID string) *acp.Message {
+	t.Helper()
+	require.NoError(t, m.AddEvent(acp.NewContentEndEvent(contentID)))
+	require.NoError(t, m.AddEvent(acp.NewBlockEndEvent("b1", nil)))
+	require.NoError(t, m.AddEvent(acp.NewRunFinishedEvent("r1")))
+	return m.Message
+}
+
+func TestReviewApprove(t *testing.T) {
+	c := qa.New(qa.WithValidator(ValidateAnswer))
+	m := newRun(t, c)
+	g := New(c)
+
+	startTool(t, m, "tool", "delete_file", `{"path":"/tmp/x"}`)
+	req := Request{Kind: KindTool, ToolName: "delete_file", Args: `{"path":"/tmp/x"}`}
+
+	done := answer(c, map[string]any{"decision": "approve"})
+	approval, err := g.Review(context.Background(), m, "b1", "tool", req)
+	require.NoError(t, err)
+	require.NoError(t, <-done)
+
+	assert.True(t, approval.Approved())
+	assert.Equal(t, req.Args, approval.ArgsOr(req.Args))
+	assert.NotEmpty(t, approval.QAID)
+
+	msg := finish(t, m, "tool")
+	require.Len(t, msg.Blocks[0].Contents, 2)
+	question := msg.Blocks[0].Contents[0].(*acp.QAContent)
+	assert.Equal(t, QAType, question.QAType)
+	assert.Equal(t, "delete_file", question.Options["tool_name"])
+	assert.Equal(t, map[string]any{"decision": "approve"}, question.Answer)
+
+	call := msg.Blocks[0].Contents[1].(*acp.ToolCallContent)
+	assert.Equal(t, &approval, call.Approval)
+}
+
+func TestReviewEditAndDeny(t *testing.T) {
+	c := qa.New(qa.WithValidator(ValidateAnswer))
+	m := newRun(t, c)
+	g := New(c)
+
+	startTool(t, m, "tool", "write", `{"path":"/etc/passwd"}`)
+	req := Request{Kind: KindTool, ToolName: "write", Args: `{"path":"/etc/passwd"}`}
+
+	done := make(chan error, 1)
+	go func() {
+		for len(c.Pending()) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		qaid := c.Pending()[0].QAID
+		// 无效回答被拒绝, 问题仍在等待
+		if err := c.Deliver(qaid, map[string]any{"decision": "edit", "args": "not json"}); !errors.Is(err, ErrInvalidAnswer) {
+			done <- err
+			return
+		}
+		done <- c.Deliver(qaid, map[string]any{"decision": "edit", "args": `{"path":"/tmp/passwd"}`})
+	}()
+	approval, err := g.Review(context.Background(), m, "b1", "tool", req)
+	require.NoError(t, err)
+	require.NoError(t, <-done)
+	assert.Equal(t, acp.ApprovalEdit, approval.Decision)
+	assert.Equal(t, `{"path":"/tmp/passwd"}`, approval.ArgsOr(req.Args))
+
+	require.NoError(t, m.AddEvent(acp.NewContentStartEvent("cmd", "b1")))
+	require.NoError(t, m.AddEvent(acp.NewContentDeltaEvent("cmd", acp.NewStreamCommandContent("rm -rf /"))))
+	denyDone := answer(c, map[string]any{"decision": "deny", "reason": "too dangerous"})
+	denied, err := g.Review(context.Background(), m, "b1", "cmd", Request{Kind: KindCommand, Args: "rm -rf /"})
+	require.NoError(t, err)
+	require.NoError(t, <-denyDone)
+	assert.False(t, denied.Approved())
+	require.NoError(t, m.AddEvent(acp.NewContentEndEvent("cmd")))
+
+	msg := finish(t, m, "tool")
+	var call *acp.ToolCallContent
+	var cmd *acp.CommandContent
+	for _, content := range msg.Blocks[0].Contents {
+		switch content := content.(type) {
+		case *acp.ToolCallContent:
+			call = content
+		case *acp.CommandContent:
+			cmd = content
+		}
+	}
+	// edit 替换记录中的参数, 原参数保留在审批结果中
+	assert.Equal(t, `{"path":"/tmp/passwd"}`, call.ToolArgs)
+	assert.Equal(t, acp.ApprovalEdit, call.Approval.Decision)
+	assert.Equal(t, req.Args, call.Approval.OriginalArgs)
+	assert.Equal(t, "rm -rf /", cmd.Command)
+	assert.Equal(t, &acp.Approval{Decision: acp.ApprovalDeny, Reason: "too dangerous", QAID: denied.QAID}, cmd.Approval)
+	// 拒绝同时写入调用结果
+	assert.Equal(t, &acp.Error{Type: ErrorTypeDenied, Message: "too dangerous"}, cmd.Error)
+	assert.Nil(t, call.Error)
+
+	data, err := json.Marshal(msg)
+	require.NoError(t, err)
+	var decoded acp.Message
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, msg.Blocks[0].Contents, decoded.Blocks[0].Contents)
+}
+
+func TestReviewPolicy(t *testing.T) {
+	policy := Any(Tools("delete_file"), MCPServers("prod-db"), Kinds(KindCommand))
+	assert.True(t, policy(Request{Kind: KindTool, ToolName: "delete_file"}))
+	assert.True(t, policy(Request{Kind: KindMCP, Server: "prod-db", ToolName: "query"}))
+	assert.True(t, policy(Request{Kind: KindCommand, Args: "ls"}))
+	assert.False(t, policy(Request{Kind: KindTool, ToolName: "search"}))
+	assert.False(t, policy(Request{Kind: KindMCP, Server: "docs", ToolName: "query"}))
+
+	c := qa.New()
+	m := newRun(t, c)
+	startTool(t, m, "tool", "search", `{}`)
+	approval, err := New(c, WithPolicy(policy)).Review(context.Background(), m, "b1", "tool", Request{Kind: KindTool, ToolName: "search", Args: `{}`})
+	require.NoError(t, err)
+	assert.Equal(t, acp.Approval{Decision: acp.ApprovalApprove, Auto: true}, approval)
+
+	msg := finish(t, m, "tool")
+	// 自动批准不发出审批问题
+	require.Len(t, msg.Blocks[0].Contents, 1)
+	assert.True(t, msg.Blocks[0].Contents[0].(*acp.ToolCallContent).Approval.Auto)
+}
+
+func TestReviewTimeoutAndCancel(t *testing.T) {
+	c := qa.New(qa.WithTimeout(10 * time.Millisecond))
+	m := newRun(t, c)
+	g := New(c)
+
+	startTool(t, m, "tool", "deploy", `{}`)
+	approval, err := g.Review(context.Background(), m, "b1", "tool", Request{Kind: KindTool, ToolName: "deploy", Args: `{}`})
+	require.NoError(t, err)
+	assert.Equal(t, acp.ApprovalDeny, approval.Decision)
+	assert.Equal(t, "approval timed out", approval.Reason)
+
+	c = qa.New()
+	m = newRun(t, c)
+	g = New(c)
+	startTool(t, m, "tool", "deploy", `{}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for len(c.Pending()) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+	approval, err = g.Review(ctx, m, "b1", "tool", Request{Kind: KindTool, ToolName: "deploy", Args: `{}`})
+	assert.ErrorIs(t, err, qa.ErrCanceled)
+	assert.Equal(t, acp.ApprovalDeny, approval.Decision)
+
+	msg := finish(t, m, "tool")
+	call := msg.Blocks[0].Contents[1].(*acp.ToolCallContent)
+	assert.Equal(t, "approval canceled", call.Approval.Reason)
+	assert.Equal(t, &acp.Error{Type: ErrorTypeDenied, Message: "approval canceled"}, call.Error)
+}
+
+func TestValidateAnswer(t *testing.T) {
+	q := qa.Question{QAType: QAType, Options: map[string]any{"kind": KindCommand}}
+	assert.NoError(t, ValidateAnswer(q, map[string]any{"decision": "edit", "args": "ls -la"}))
+	assert.ErrorIs(t, ValidateAnswer(q, map[string]any{"decision": "edit"}), ErrInvalidAnswer)
+	assert.ErrorIs(t, ValidateAnswer(q, map[string]any{"decision": "maybe"}), ErrInvalidAnswer)
+	assert.NoError(t, ValidateAnswer(qa.Question{QAType: "select"}, map[string]any{}))
+
+	content := acp.NewMCPContent("fs", "read")
+	content.ToolArgs = `{"path":"a"}`
+	req, ok := RequestOf(content)
+	require.True(t, ok)
+	assert.Equal(t, Request{Kind: KindMCP, ToolName: "read", Server: "fs", Args: `{"path":"a"}`}, req)
+	_, ok = RequestOf(acp.NewTextContent("", "hi"))
+	assert.False(t, ok)
+}
@@ -0,0 +1,258 @@
+// Package compact 按 token 预算压缩消息历史
+package compact
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+)
+
+// 所有可压缩手段用尽后仍超出预算, 此时仍返回尽力压缩的结果
+var ErrBudgetExceeded = errors.New("history exceeds token budget")
+
+// 压缩统计
+type Report struct {
+	Before             int `json:"before"`
+	After              int `json:"after"`
+	ThinkingDropped    int `json:"thinking_dropped"`
+	ThinkingSummarized int `json:"thinking_summarized"`
+	PayloadsTruncated  int `json:"payloads_truncated"`
+	MessagesDropped    int `json:"messages_dropped"`
+}
+
+type options struct {
+	estimator  Estimator
+	pinned     func(*acp.Message) bool
+	keepRecent int
+	maxPayload int
+	summarize  func(text string) string
+}
+
+type Option func(*options)
+
+// WithEstimator 替换默认的启发式估算
+func WithEstimator(est Estimator) Option {
+	return func(o *options) { o.estimator = est }
+}
+
+// WithPinned 保护指定 ID 的消息不被压缩
+func WithPinned(ids ...string) Option {
+	return WithPinFunc(func(m *acp.Message) bool { return slices.Contains(ids, m.ID) })
+}
+
+// WithPinFunc 以函数判定受保护的消息, 可多次设置, 任一命中即保护
+func WithPinFunc(fn func(*acp.Message) bool) Option {
+	return func(o *options) {
+		prev := o.pinned
+		o.pinned = func(m *acp.Message) bool { return prev(m) || fn(m) }
+	}
+}
+
+// WithKeepRecent 最近 n 条消息不被压缩, 默认 2
+func WithKeepRecent(n int) Option {
+	return func(o *options) { o.keepRecent = max(n, 0) }
+}
+
+// WithMaxPayloadTokens 工具结果等载荷超过 n 个 token 时截断, 默认 256
+func WithMaxPayloadTokens(n int) Option {
+	return func(o *options) { o.maxPayload = max(n, 1) }
+}
+
+// WithThinkingSummary 以摘要替换旧的思考内容, 返回空串时直接丢弃
+func WithThinkingSummary(fn func(text string) string) Option {
+	return func(o *options) { o.summarize = fn }
+}
+
+// Compact 在 budget 内压缩消息历史, 不修改入参.
+// 依次执行: 丢弃或摘要旧的思考内容, 截断大载荷, 按轮次丢弃最早的消息.
+// 系统消息、受保护消息和最近的消息始终保留; 工具调用与结果位于同一内容中,
+// 只会截断结果或随整轮一起丢弃, 不会被拆开.
+func Compact(msgs []acp.Message, budget int, opts ...Option) ([]acp.Message, *Report, error) {
+	o := &options{
+		estimator:  Heuristic,
+		pinned:     func(*acp.Message) bool { return false },
+		keepRecent: 2,
+		maxPayload: 256,
+	}
+	for _, fn := range opts {
+		fn(o)
+	}
+
+	out, err := clone(msgs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c := &compactor{o: o, msgs: out, report: &Report{}}
+	c.init()
+	c.report.Before = c.total()
+
+	for _, stage := range []func(int) bool{c.compactThinking, c.truncatePayloads} {
+		for i := range c.msgs {
+			if c.total() <= budget {
+				break
+			}
+			if c.protected[i] {
+				continue
+			}
+			if stage(i) {
+				c.tokens[i] = MessageTokens(o.estimator, &c.msgs[i])
+			}
+		}
+	}
+	c.dropTurns(budget)
+
+	c.report.After = c.total()
+	if c.report.After > budget {
+		return c.msgs, c.report, fmt.Errorf("%w: %d > %d", ErrBudgetExceeded, c.report.After, budget)
+	}
+	return c.msgs, c.report, nil
+}
+
+type compactor struct {
+	o         *options
+	msgs      []acp.Message
+	tokens    []int
+	protected []bool
+	report    *Report
+}
+
+func (c *compactor) init() {
+	c.tokens = make([]int, len(c.msgs))
+	c.protected = make([]bool, len(c.msgs))
+	for i := range c.msgs {
+		m := &c.msgs[i]
+		c.tokens[i] = MessageTokens(c.o.estimator, m)
+		c.protected[i] = m.Role == acp.RoleSystem || c.o.pinned(m) || i >= len(c.msgs)-c.o.keepRecent
+	}
+}
+
+func (c *compactor) total() int {
+	sum := 0
+	for _, n := range c.tokens {
+		sum += n
+	}
+	return sum
+}
+
+func (c *compactor) compactThinking(i int) bool {
+	changed := false
+	for b := range c.msgs[i].Blocks {
+		block := &c.msgs[i].Blocks[b]
+		block.Contents = slices.DeleteFunc(block.Contents, func(content acp.Content) bool {
+			thinking, ok := content.(*acp.ThinkingContent)
+			if !ok {
+				return false
+			}
+			changed = true
+			if c.o.summarize != nil {
+				if summary := c.o.summarize(thinking.Text); summary != "" {
+					thinking.Text = summary
+					thinking.Signature = ""
+					c.report.ThinkingSummarized++
+					return false
+				}
+			}
+			c.report.ThinkingDropped++
+			return true
+		})
+	}
+	return changed
+}
+
+func (c *compactor) truncatePayloads(i int) bool {
+	changed := false
+	for b := range c.msgs[i].Blocks {
+		contents := c.msgs[i].Blocks[b].Contents
+		for j, content := range contents {
+			switch content := content.(type) {
+			case *acp.ToolCallContent:
+				changed = c.truncate(&content.ToolResult) || changed
+			case *acp.MCPContent:
+				changed = c.truncate(&content.ToolResult) || changed
+			case *acp.CommandContent:
+				changed = c.truncate(&content.Result) || changed
+			case *acp.CodeExecutionContent:
+				changed = c.truncate(&content.Result) || changed
+			case *acp.DataContent:
+				if c.o.estimator.Estimate(content.Data) <= c.o.maxPayload {
+					continue
+				}
+				size := base64.StdEncoding.DecodedLen(len(content.Data)) - strings.Count(content.Data[max(len(content.Data)-2, 0):], "=")
+				contents[j] = acp.NewTextContent("", fmt.Sprintf("[data omitted: %s, %d bytes]", content.MimeType, size))
+				c.report.PayloadsTruncated++
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// truncate 保留载荷开头约 maxPayload 个 token, 并追加截断标记
+func (c *compactor) truncate(s *string) bool {
+	n := c.o.estimator.Estimate(*s)
+	if n <= c.o.maxPayload {
+		return false
+	}
+
+	keep := len(*s) * c.o.maxPayload / n
+	for keep > 0 && !utf8.RuneStart((*s)[keep]) {
+		keep--
+	}
+	*s = (*s)[:keep] + fmt.Sprintf("\n...[truncated ~%d tokens]", n-c.o.estimator.Estimate((*s)[:keep]))
+	c.report.PayloadsTruncated++
+	return true
+}
+
+// dropTurns 从最早的轮次开始整轮丢弃, 一轮以用户消息开头, 含受保护消息的轮次保留
+func (c *compactor) dropTurns(budget int) {
+	drop := make([]bool, len(c.msgs))
+	total := c.total()
+	for start := 0; start < len(c.msgs) && total > budget; {
+		end := start + 1
+		for end < len(c.msgs) && c.msgs[end].Role != acp.RoleUser {
+			end++
+		}
+
+		if !slices.Contains(c.protected[start:end], true) {
+			for i := start; i < end; i++ {
+				drop[i] = true
+				total -= c.tokens[i]
+			}
+		}
+		start = end
+	}
+
+	msgs := make([]acp.Message, 0, len(c.msgs))
+	tokens := make([]int, 0, len(c.msgs))
+	protected := make([]bool, 0, len(c.msgs))
+	for i := range c.msgs {
+		if drop[i] {
+			c.report.MessagesDropped++
+			continue
+		}
+		msgs = append(msgs, c.msgs[i])
+		tokens = append(tokens, c.tokens[i])
+		protected = append(protected, c.protected[i])
+	}
+	c.msgs, c.tokens, c.protected = msgs, tokens, protected
+}
+
+func clone(msgs []acp.Message) ([]acp.Message, error) {
+	data, err := json.Marshal(msgs)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []acp.Message
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,148 @@
+package compact
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func message(id, role string, contents ...acp.Content) acp.Message {
+	return acp.Message{ID: id, Role: role, Blocks: []acp.Block{{ID: id + "-b", Contents: contents}}}
+}
+
+func toolCall(name, args, result string) *acp.ToolCallContent {
+	c := acp.NewToolCallContent(name)
+	c.ToolArgs = args
+	c.ToolResult = result
+	return c
+}
+
+func history() []acp.Message {
+	return []acp.Message{
+		message("sys", acp.RoleSystem, acp.NewTextContent("", "You are helpful.")),
+		message("u1", acp.RoleUser, acp.NewTextContent("", "list files")),
+		message("a1", acp.RoleAssistant,
+			acp.NewThinkingContent("", strings.Repeat("thinking hard ", 200)),
+			toolCall("ls", `{"dir":"/"}`, strings.Repeat("file.txt\n", 1000)),
+			acp.NewTextContent("", "here they are"),
+		),
+		message("u2", acp.RoleUser, acp.NewTextContent("", "show the image")),
+		message("a2", acp.RoleAssistant, acp.NewDataContent("image/png", make([]byte, 8192))),
+		message("u3", acp.RoleUser, acp.NewTextContent("", "thanks")),
+		message("a3", acp.RoleAssistant,
+			acp.NewThinkingContent("", "recent thinking"),
+			acp.NewTextContent("", "you're welcome"),
+		),
+	}
+}
+
+func total(msgs []acp.Message) int {
+	sum := 0
+	for i := range msgs {
+		sum += MessageTokens(Heuristic, &msgs[i])
+	}
+	return sum
+}
+
+func TestHeuristic(t *testing.T) {
+	assert.Equal(t, 0, Heuristic.Estimate(""))
+	assert.Equal(t, 1, Heuristic.Estimate("abcd"))
+	assert.Equal(t, 2, Heuristic.Estimate("abcde"))
+	assert.Equal(t, 4, Heuristic.Estimate("你好世界"))
+}
+
+func TestCompactWithinBudget(t *testing.T) {
+	msgs := history()
+	out, report, err := Compact(msgs, 1<<20)
+	require.NoError(t, err)
+	assert.Equal(t, report.Before, report.After)
+	assert.Len(t, out, len(msgs))
+	assert.Zero(t, report.ThinkingDropped+report.PayloadsTruncated+report.MessagesDropped)
+}
+
+func TestCompactDropsOldThinking(t *testing.T) {
+	msgs := history()
+	budget := total(msgs) - 100
+
+	out, report, err := Compact(msgs, budget)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.ThinkingDropped)
+	assert.Zero(t, report.PayloadsTruncated)
+	assert.LessOrEqual(t, report.After, budget)
+
+	require.Len(t, out[2].Blocks[0].Contents, 2)
+	assert.IsType(t, &acp.ToolCallContent{}, out[2].Blocks[0].Contents[0])
+	// 最近的思考内容保留
+	assert.IsType(t, &acp.ThinkingContent{}, out[6].Blocks[0].Contents[0])
+	// 入参不被修改
+	assert.Len(t, msgs[2].Blocks[0].Contents, 3)
+}
+
+func TestCompactSummarizesThinking(t *testing.T) {
+	msgs := history()
+	out, report, err := Compact(msgs, total(msgs)-100, WithThinkingSummary(func(string) string { return "considered ls" }))
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.ThinkingSummarized)
+	assert.Equal(t, "considered ls", out[2].Blocks[0].Contents[0].(*acp.ThinkingContent).Text)
+}
+
+func TestCompactTruncatesPayloads(t *testing.T) {
+	msgs := history()
+	out, report, err := Compact(msgs, 700, WithMaxPayloadTokens(50))
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.PayloadsTruncated)
+	assert.Zero(t, report.MessagesDropped)
+	assert.LessOrEqual(t, report.After, 700)
+
+	call := out[2].Blocks[0].Contents[0].(*acp.ToolCallContent)
+	assert.Equal(t, `{"dir":"/"}`, call.ToolArgs, "tool call args are kept with the result")
+	assert.True(t, strings.HasPrefix(call.ToolResult, "file.txt\n"))
+	assert.Contains(t, call.ToolResult, "...[truncated ~")
+
+	data := out[4].Blocks[0].Contents[0].(*acp.TextContent)
+	assert.Equal(t, "[data omitted: image/png, 8192 bytes]", data.Text)
+}
+
+func TestCompactDropsTurns(t *testing.T) {
+	msgs := history()
+	out, report, err := Compact(msgs, 40, WithMaxPayloadTokens(50))
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.MessagesDropped)
+
+	ids := make([]string, 0, len(out))
+	for _, m := range out {
+		ids = append(ids, m.ID)
+	}
+	assert.Equal(t, []string{"sys", "u3", "a3"}, ids)
+}
+
+func TestCompactPinned(t *testing.T) {
+	msgs := history()
+	out, report, err := Compact(msgs, 40, WithMaxPayloadTokens(50), WithPinned("u2"))
+	assert.ErrorIs(t, err, ErrBudgetExceeded)
+	assert.Greater(t, report.After, 40)
+
+	ids := make([]string, 0, len(out))
+	for _, m := range out {
+		ids = append(ids, m.ID)
+	}
+	// 受保护消息所在整轮保留, 图片仍被截断
+	assert.Equal(t, []string{"sys", "u2", "a2", "u3", "a3"}, ids)
+	assert.IsType(t, &acp.TextContent{}, out[2].Blocks[0].Contents[0])
+}
+
+func TestCompactCustomEstimator(t *testing.T) {
+	msgs := history()
+	words := EstimatorFunc(func(s string) int { return len(strings.Fields(s)) })
+	_, report, err := Compact(msgs, 1<<20, WithEstimator(words))
+	require.NoError(t, err)
+
+	expected := 0
+	for i := range msgs {
+		expected += MessageTokens(words, &msgs[i])
+	}
+	assert.Equal(t, expected, report.Before)
+}
@@ -0,0 +1,95 @@
+package compact
+
+import (
+	"encoding/json"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+)
+
+// token 数估算
+type Estimator interface {
+	Estimate(text string) int
+}
+
+type EstimatorFunc func(text string) int
+
+func (f EstimatorFunc) Estimate(text string) int {
+	return f(text)
+}
+
+// 每条消息与每个内容的固定开销(角色、分隔符等)
+const (
+	messageOverhead = 4
+	contentOverhead = 2
+)
+
+// Heuristic 离线估算: ASCII 约 4 字符一个 token, CJK 等宽字符每字一个 token
+var Heuristic Estimator = EstimatorFunc(heuristic)
+
+func heuristic(text string) int {
+	ascii, wide := 0, 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		switch {
+		case r < utf8.RuneSelf:
+			ascii++
+		case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+			unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r):
+			wide++
+		default:
+			// 其他多字节字符按约两字符一个 token
+			ascii += 2
+		}
+	}
+	return (ascii+3)/4 + wide
+}
+
+// MessageTokens 估算单条消息的 token 数
+func MessageTokens(est Estimator, msg *acp.Message) int {
+	total := messageOverhead
+	for _, b := range msg.Blocks {
+		for _, c := range b.Contents {
+			if c != nil {
+				total += ContentTokens(est, c)
+			}
+		}
+	}
+	return total
+}
+
+// ContentTokens 估算单个内容的 token 数
+func ContentTokens(est Estimator, c acp.Content) int {
+	n := contentOverhead
+	switch c := c.(type) {
+	case *acp.TextContent:
+		n += est.Estimate(c.Text)
+	case *acp.ThinkingContent:
+		n += est.Estimate(c.Text)
+	case *acp.ToolCallContent:
+		n += est.Estimate(c.ToolName) + est.Estimate(c.ToolArgs) + est.Estimate(c.ToolResult) + errorTokens(est, c.Error)
+	case *acp.MCPContent:
+		n += est.Estimate(c.Server) + est.Estimate(c.ToolName) + est.Estimate(c.ToolArgs) + est.Estimate(c.ToolResult) + errorTokens(est, c.Error)
+	case *acp.CommandContent:
+		n += est.Estimate(c.Command) + est.Estimate(c.Result) + errorTokens(est, c.Error)
+	case *acp.CodeExecutionContent:
+		n += est.Estimate(c.Code) + est.Estimate(c.Result) + errorTokens(est, c.Error)
+	case *acp.DataContent:
+		n += est.Estimate(c.Data)
+	default:
+		data, err := json.Marshal(c)
+		if err == nil {
+			n += est.Estimate(string(data))
+		}
+	}
+	return n
+}
+
+func errorTokens(est Estimator, e *acp.Error) int {
+	if e == nil {
+		return 0
+	}
+	return est.Estimate(e.Type) + est.Estimate(e.Message)
+}
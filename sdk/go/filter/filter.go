@@ -0,0 +1,460 @@
+// Package filter 按受众过滤事件流, 过滤后的事件流保持结构完整
+package filter
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+)
+
+type Action int
+
+const (
+	Allow Action = iota
+	Drop
+	// Mask 以占位文本替换内容, 保留其位置
+	Mask
+)
+
+// 默认的遮蔽占位文本
+const DefaultMask = "[hidden]"
+
+// 过滤规则, 非空条件需全部满足, 同一条件内任一值命中即可.
+// 不含内容条件(ContentTypes/ToolNames/MCPServers)的规则作用于区块:
+// Drop 移除整个区块子树, Mask 遮蔽子树内的全部内容.
+type Rule struct {
+	ContentTypes []string
+	ToolNames    []string
+	MCPServers   []string
+	// 区块元数据, 值为 nil 时只要求键存在; 子区块继承祖先区块的元数据
+	BlockMetadata map[string]any
+
+	Action Action
+	Mask   string
+}
+
+func (r *Rule) blockLevel() bool {
+	return len(r.ContentTypes) == 0 && len(r.ToolNames) == 0 && len(r.MCPServers) == 0
+}
+
+func (r *Rule) matchBlock(metadata map[string]any) bool {
+	for k, want := range r.BlockMetadata {
+		got, ok := metadata[k]
+		if !ok || (want != nil && fmt.Sprint(got) != fmt.Sprint(want)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Rule) matchContent(ci contentInfo, metadata map[string]any) bool {
+	if len(r.ContentTypes) > 0 && !slices.Contains(r.ContentTypes, ci.contentType) {
+		return false
+	}
+	if len(r.ToolNames) > 0 && !slices.Contains(r.ToolNames, ci.toolName) {
+		return false
+	}
+	if len(r.MCPServers) > 0 && !slices.Contains(r.MCPServers, ci.server) {
+		return false
+	}
+	return r.matchBlock(metadata)
+}
+
+func (r *Rule) mask() string {
+	if r.Mask == "" {
+		return DefaultMask
+	}
+	return r.Mask
+}
+
+// 过滤策略, 规则按顺序匹配, 首条命中的规则生效, 未命中则放行
+type Policy struct {
+	Rules []Rule
+	// 保留过滤后没有任何内容的区块
+	KeepEmptyBlocks bool
+}
+
+// ByContentType 按内容类型匹配, 类型取内容首个增量的类型, 如 acp.ContentTypeThinking
+func ByContentType(action Action, types ...string) Rule {
+	return Rule{ContentTypes: types, Action: action}
+}
+
+// ByToolName 按工具名匹配工具调用和 MCP 调用
+func ByToolName(action Action, names ...string) Rule {
+	return Rule{ToolNames: names, Action: action}
+}
+
+func ByMCPServer(action Action, servers ...string) Rule {
+	return Rule{MCPServers: servers, Action: action}
+}
+
+// ByBlockTag 按区块元数据匹配整个区块子树, value 为 nil 时只要求键存在
+func ByBlockTag(action Action, key string, value any) Rule {
+	return Rule{BlockMetadata: map[string]any{key: value}, Action: action}
+}
+
+// EndUser 面向终端用户的默认策略: 隐藏思考内容和命令执行
+func EndUser() Policy {
+	return Policy{Rules: []Rule{
+		ByContentType(Drop, acp.ContentTypeThinking),
+		ByContentType(Drop, acp.ContentTypeCommandExecution),
+	}}
+}
+
+type contentInfo struct {
+	contentType string
+	toolName    string
+	server      string
+}
+
+type blockState struct {
+	start    acp.BlockStartEvent
+	metadata map[string]any
+	action   Action
+	mask     string
+	emitted  bool
+}
+
+// 被丢弃或屏蔽的区块/内容的处理方式, 用于过滤指向它们的错误与通知
+type decision struct {
+	action Action
+	mask   string
+}
+
+type contentState struct {
+	start   acp.ContentStartEvent
+	decided bool
+	action  Action
+	mask    string
+}
+
+// Filter 按策略过滤单条事件流, 有状态, 每条流使用一个实例, 非并发安全
+type Filter struct {
+	policy   Policy
+	blocks   map[string]*blockState
+	dropped  map[string]bool // 整体丢弃的区块
+	contents map[string]*contentState
+	// 运行内被丢弃或屏蔽的区块与内容, 结束后仍保留直到运行结束
+	hiddenBlocks   map[string]decision
+	hiddenContents map[string]decision
+}
+
+func New(policy Policy) *Filter {
+	return &Filter{
+		policy:   policy,
+		blocks:   make(map[string]*blockState),
+		dropped:  make(map[string]bool),
+		contents: make(map[string]*contentState),
+
+		hiddenBlocks:   make(map[string]decision),
+		hiddenContents: make(map[string]decision),
+	}
+}
+
+// Apply 返回过滤后应输出的事件, 可能为空, 也可能包含之前暂存的事件
+func (f *Filter) Apply(e acp.Event) []acp.Event {
+	switch evt := e.(type) {
+	case acp.BlockStartEvent:
+		return f.blockStart(evt)
+	case acp.BlockEndEvent:
+		return f.blockEnd(evt)
+	case acp.BlockErrorEvent:
+		return f.blockError(evt)
+	case acp.ContentStartEvent:
+		if _, ok := f.blocks[evt.RelatedBlockID]; ok {
+			f.contents[evt.ContentID] = &contentState{start: evt}
+		} else if f.dropped[evt.RelatedBlockID] {
+			f.hiddenContents[evt.ContentID] = decision{action: Drop}
+		}
+		return nil
+	case acp.ContentDeltaEvent:
+		return f.contentDelta(evt)
+	case acp.ContentEndEvent:
+		return f.contentEnd(evt)
+	case acp.RunErrorEvent:
+		out := f.runError(evt)
+		f.reset()
+		return out
+	case acp.RunFinishedEvent:
+		f.reset()
+		return []acp.Event{e}
+	case acp.NoticeEvent:
+		return f.notice(evt)
+	default:
+		return []acp.Event{e}
+	}
+}
+
+func (f *Filter) reset() {
+	clear(f.blocks)
+	clear(f.dropped)
+	clear(f.contents)
+	clear(f.hiddenBlocks)
+	clear(f.hiddenContents)
+}
+
+func (f *Filter) blockStart(evt acp.BlockStartEvent) []acp.Event {
+	if f.dropped[evt.ParentBlockID] {
+		f.dropped[evt.BlockID] = true
+		f.hiddenBlocks[evt.BlockID] = decision{action: Drop}
+		return nil
+	}
+
+	b := &blockState{start: evt, metadata: make(map[string]any)}
+	if parent, ok := f.blocks[evt.ParentBlockID]; ok {
+		for k, v := range parent.metadata {
+			b.metadata[k] = v
+		}
+		b.action, b.mask = parent.action, parent.mask
+	}
+	for k, v := range evt.Metadata {
+		b.metadata[k] = v
+	}
+
+	if b.action == Allow {
+		for i := range f.policy.Rules {
+			r := &f.policy.Rules[i]
+			if r.blockLevel() && r.matchBlock(b.metadata) {
+				b.action, b.mask = r.Action, r.mask()
+				break
+			}
+		}
+	}
+	if b.action != Allow {
+		f.hiddenBlocks[evt.BlockID] = decision{action: b.action, mask: b.mask}
+	}
+	if b.action == Drop {
+		f.dropped[evt.BlockID] = true
+		return nil
+	}
+
+	f.blocks[evt.BlockID] = b
+	if f.policy.KeepEmptyBlocks {
+		return f.emitBlock(b)
+	}
+	return nil
+}
+
+func (f *Filter) blockEnd(evt acp.BlockEndEvent) []acp.Event {
+	if f.dropped[evt.BlockID] {
+		delete(f.dropped, evt.BlockID)
+		return nil
+	}
+
+	b, ok := f.blocks[evt.BlockID]
+	if !ok {
+		return nil
+	}
+	delete(f.blocks, evt.BlockID)
+	if !b.emitted {
+		return nil
+	}
+	return []acp.Event{evt}
+}
+
+// blockError 按所属区块或内容的处理方式透传区块错误, 屏蔽时只保留错误码
+func (f *Filter) blockError(evt acp.BlockErrorEvent) []acp.Event {
+	b, ok := f.blocks[evt.BlockID]
+	if !ok {
+		return nil
+	}
+
+	action, mask := b.action, b.mask
+	if c, ok := f.contents[evt.Error.ContentID]; ok && c.decided {
+		action, mask = c.action, c.mask
+	}
+	switch action {
+	case Drop:
+		return nil
+	case Mask:
+		evt.Error = maskError(evt.Error, mask)
+		evt.Error.BlockID = evt.BlockID
+	}
+	return append(f.emitBlock(b), evt)
+}
+
+// scope 返回错误或通知所指向的区块/内容的处理方式, 内容优先
+func (f *Filter) scope(blockID, contentID string) decision {
+	if d, ok := f.hiddenContents[contentID]; ok && contentID != "" {
+		return d
+	}
+	if d, ok := f.hiddenBlocks[blockID]; ok && blockID != "" {
+		return d
+	}
+	return decision{action: Allow}
+}
+
+// runError 运行错误必须输出, 指向被屏蔽的区块/内容时只保留错误码与遮蔽文本,
+// 指向被丢弃的区块/内容时只保留错误码
+func (f *Filter) runError(evt acp.RunErrorEvent) []acp.Event {
+	if evt.Info == nil {
+		return []acp.Event{evt}
+	}
+
+	d := f.scope(evt.Info.BlockID, evt.Info.ContentID)
+	switch d.action {
+	case Drop:
+		evt.Info = &acp.RunError{Code: evt.Info.Code, Retryable: evt.Info.Retryable}
+		evt.Error = evt.Info.Code
+	case Mask:
+		info := maskError(*evt.Info, d.mask)
+		evt.Info = &info
+		evt.Error = d.mask
+	}
+	return []acp.Event{evt}
+}
+
+// notice 丢弃指向被丢弃区块/内容的通知, 遮蔽指向被屏蔽区块/内容的通知文本
+func (f *Filter) notice(evt acp.NoticeEvent) []acp.Event {
+	d := f.scope(evt.BlockID, evt.ContentID)
+	switch d.action {
+	case Drop:
+		return nil
+	case Mask:
+		evt.Message = d.mask
+	}
+	return []acp.Event{evt}
+}
+
+// maskError 只保留错误码、可重试标记和所属区块, 消息替换为遮蔽文本
+func maskError(e acp.RunError, mask string) acp.RunError {
+	return acp.RunError{
+		Code:      e.Code,
+		Message:   mask,
+		Retryable: e.Retryable,
+		BlockID:   e.BlockID,
+	}
+}
+
+// emitBlock 输出暂存的区块开始事件, 先补齐尚未输出的祖先区块
+func (f *Filter) emitBlock(b *blockState) []acp.Event {
+	if b.emitted {
+		return nil
+	}
+
+	var out []acp.Event
+	if parent, ok := f.blocks[b.start.ParentBlockID]; ok {
+		out = f.emitBlock(parent)
+	}
+	b.emitted = true
+	return append(out, b.start)
+}
+
+func (f *Filter) contentDelta(evt acp.ContentDeltaEvent) []acp.Event {
+	c, ok := f.contents[evt.ContentID]
+	if !ok {
+		return nil
+	}
+	if c.decided {
+		if c.action == Allow {
+			return []acp.Event{evt}
+		}
+		return nil
+	}
+
+	b := f.blocks[c.start.RelatedBlockID]
+	c.decided = true
+	c.action, c.mask = f.decide(b, info(evt.Content))
+	if c.action != Allow {
+		f.hiddenContents[evt.ContentID] = decision{action: c.action, mask: c.mask}
+	}
+
+	switch c.action {
+	case Allow:
+		return append(f.emitBlock(b), c.start, evt)
+	case Mask:
+		masked := acp.NewContentDeltaEvent(evt.ContentID, acp.NewStreamTextContent(c.mask))
+		masked.TimestampMs = evt.TimestampMs
+		return append(f.emitBlock(b), c.start, masked)
+	default:
+		delete(f.contents, evt.ContentID)
+		return nil
+	}
+}
+
+func (f *Filter) decide(b *blockState, ci contentInfo) (Action, string) {
+	if b.action == Mask {
+		return Mask, b.mask
+	}
+	for i := range f.policy.Rules {
+		r := &f.policy.Rules[i]
+		if !r.blockLevel() && r.matchContent(ci, b.metadata) {
+			return r.Action, r.mask()
+		}
+	}
+	return Allow, ""
+}
+
+func (f *Filter) contentEnd(evt acp.ContentEndEvent) []acp.Event {
+	c, ok := f.contents[evt.ContentID]
+	if !ok {
+		return nil
+	}
+	delete(f.contents, evt.ContentID)
+	if !c.decided {
+		// 没有任何增量的内容不输出
+		return nil
+	}
+	return []acp.Event{evt}
+}
+
+func info(sc acp.StreamContent) contentInfo {
+	ci := contentInfo{contentType: sc.SType()}
+	switch sc := sc.(type) {
+	case acp.StreamTextAnnotationContent:
+		ci.contentType = acp.ContentTypeText
+	case acp.StreamToolCallContent:
+		ci.toolName = sc.ToolName
+	case acp.StreamMCPCallContent:
+		ci.toolName = sc.ToolName
+		ci.server = sc.Server
+	}
+	return ci
+}
+
+// Writer 过滤后再发送的 SSE 写入器, 每个订阅者一个
+type Writer struct {
+	filter *Filter
+	writer *acp.SSEWriter
+}
+
+func NewWriter(w *acp.SSEWriter, policy Policy) *Writer {
+	return &Writer{filter: New(policy), writer: w}
+}
+
+func (w *Writer) Send(e acp.Event) error {
+	for _, out := range w.filter.Apply(e) {
+		if err := w.writer.Send(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Interceptor 过滤 Creator 自身的事件流, 聚合结果和发送的事件都会被过滤.
+// 返回的拦截器带有单条流的过滤状态, 每个 Creator 需各自调用 Interceptor 创建.
+func Interceptor(policy Policy) acp.Interceptor {
+	f := New(policy)
+	return func(ctx context.Context, e acp.Event, next acp.Handler) error {
+		for _, out := range f.Apply(e) {
+			if err := next(ctx, out); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// Tee 在 Creator 处理成功后, 将过滤后的副本发送给另一个订阅者.
+// 与 Interceptor 相同, 每个 Creator 需各自创建.
+func Tee(w *acp.SSEWriter, policy Policy) acp.Interceptor {
+	writer := NewWriter(w, policy)
+	return func(ctx context.Context, e acp.Event, next acp.Handler) error {
+		if err := next(ctx, e); err != nil {
+			return err
+		}
+		return writer.Send(e)
+	}
+}
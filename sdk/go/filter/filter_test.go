@@ -0,0 +1,336 @@
+package filter
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func content(id, blockID string, deltas ...acp.StreamContent) []acp.Event {
+	events := []acp.Event{acp.NewContentStartEvent(id, blockID)}
+	for _, d := range deltas {
+		events = append(events, acp.NewContentDeltaEvent(id, d))
+	}
+	return append(events, acp.NewContentEndEvent(id))
+}
+
+func stream() []acp.Event {
+	var events []acp.Event
+	add := func(e ...acp.Event) { events = append(events, e...) }
+
+	add(acp.NewRunStartedEvent("s1", "r1"))
+	add(acp.NewBlockStartEvent("b1"))
+	add(content("think", "b1", acp.NewStreamThinkingContent("private reasoning"))...)
+	add(content("text", "b1", acp.NewStreamTextContent("hello"))...)
+	add(content("cmd", "b1", acp.NewStreamCommandContent("rm -rf /tmp/x"), acp.NewStreamCommandResultContent("", 0))...)
+	add(content("tool", "b1", acp.NewStreamToolCallContent("lookup"), acp.NewStreamToolArgsContent(`{"q":1}`))...)
+	add(content("mcp", "b1", acp.NewStreamMCPCallContent("internal-db", "query"), acp.NewStreamMCPArgsContent("select"))...)
+	add(content("empty", "b1")...)
+	add(acp.NewBlockEndEvent("b1", nil))
+
+	// 只含思考内容的区块
+	add(acp.NewBlockStartEvent("b2"))
+	add(content("think2", "b2", acp.NewStreamThinkingContent("more"))...)
+	add(acp.NewBlockEndEvent("b2", nil))
+
+	// 内部子智能体及其子区块
+	add(acp.NewBlockStartEvent("b3", acp.WithIsSubagent(), acp.WithMetadata(map[string]any{"audience": "internal"})))
+	add(acp.NewBlockStartEvent("b4", acp.WithParentBlockID("b3")))
+	add(content("sub", "b4", acp.NewStreamTextContent("subagent output"))...)
+	add(acp.NewBlockEndEvent("b4", nil))
+	add(acp.NewBlockEndEvent("b3", nil))
+
+	// 父区块无内容、子区块有内容
+	add(acp.NewBlockStartEvent("b5"))
+	add(acp.NewBlockStartEvent("b6", acp.WithParentBlockID("b5")))
+	add(content("late", "b6", acp.NewStreamTextContent("late text"))...)
+	add(acp.NewBlockEndEvent("b6", nil))
+	add(acp.NewBlockEndEvent("b5", nil))
+
+	add(acp.NewContentEndEvent("orphan"))
+	add(acp.NewRunFinishedEvent("r1"))
+	return events
+}
+
+func apply(policy Policy, events []acp.Event) []acp.Event {
+	f := New(policy)
+	var out []acp.Event
+	for _, e := range events {
+		out = append(out, f.Apply(e)...)
+	}
+	return out
+}
+
+// assertValid 校验事件流结构: 无孤立的内容/区块事件, 除非允许否则无空区块(含子区块视为非空)
+func assertValid(t *testing.T, events []acp.Event, allowEmpty bool) {
+	t.Helper()
+	blocks := map[string]int{}
+	contents := map[string]string{}
+	for i, e := range events {
+		switch evt := e.(type) {
+		case acp.BlockStartEvent:
+			if evt.ParentBlockID != "" {
+				_, ok := blocks[evt.ParentBlockID]
+				assert.True(t, ok, "event %d: block %s has no open parent", i, evt.BlockID)
+				blocks[evt.ParentBlockID]++
+			}
+			blocks[evt.BlockID] = 0
+		case acp.BlockEndEvent:
+			n, ok := blocks[evt.BlockID]
+			require.True(t, ok, "event %d: orphan block end %s", i, evt.BlockID)
+			if !allowEmpty {
+				assert.NotZero(t, n, "event %d: empty block %s", i, evt.BlockID)
+			}
+			delete(blocks, evt.BlockID)
+		case acp.ContentStartEvent:
+			_, ok := blocks[evt.RelatedBlockID]
+			require.True(t, ok, "event %d: content %s in closed block", i, evt.ContentID)
+			contents[evt.ContentID] = evt.RelatedBlockID
+			blocks[evt.RelatedBlockID]++
+		case acp.ContentDeltaEvent:
+			_, ok := contents[evt.ContentID]
+			assert.True(t, ok, "event %d: orphan delta %s", i, evt.ContentID)
+		case acp.ContentEndEvent:
+			_, ok := contents[evt.ContentID]
+			require.True(t, ok, "event %d: orphan content end %s", i, evt.ContentID)
+			delete(contents, evt.ContentID)
+		}
+	}
+	assert.Empty(t, blocks)
+	assert.Empty(t, contents)
+}
+
+func contentIDs(events []acp.Event) []string {
+	var ids []string
+	for _, e := range events {
+		if evt, ok := e.(acp.ContentStartEvent); ok {
+			ids = append(ids, evt.ContentID)
+		}
+	}
+	return ids
+}
+
+func blockIDs(events []acp.Event) []string {
+	var ids []string
+	for _, e := range events {
+		if evt, ok := e.(acp.BlockStartEvent); ok {
+			ids = append(ids, evt.BlockID)
+		}
+	}
+	return ids
+}
+
+func TestFilterAllowAll(t *testing.T) {
+	out := apply(Policy{}, stream())
+	assert.Equal(t, []string{"think", "text", "cmd", "tool", "mcp", "think2", "sub", "late"}, contentIDs(out))
+	assert.Equal(t, []string{"b1", "b2", "b3", "b4", "b5", "b6"}, blockIDs(out))
+}
+
+func TestFilterEndUser(t *testing.T) {
+	policy := EndUser()
+	policy.Rules = append(policy.Rules,
+		ByMCPServer(Drop, "internal-db"),
+		ByBlockTag(Drop, "audience", "internal"),
+	)
+	out := apply(policy, stream())
+
+	assert.Equal(t, []string{"text", "tool", "late"}, contentIDs(out))
+	assert.Equal(t, []string{"b1", "b5", "b6"}, blockIDs(out))
+	assert.Equal(t, acp.EventTypeRunStarted, out[0].Type())
+	assert.Equal(t, acp.EventTypeRunFinished, out[len(out)-1].Type())
+
+	for _, e := range out {
+		if evt, ok := e.(acp.ContentDeltaEvent); ok {
+			assert.NotEqual(t, acp.ContentTypeThinking, evt.Content.SType())
+		}
+	}
+}
+
+func TestFilterKeepEmptyBlocks(t *testing.T) {
+	policy := EndUser()
+	policy.KeepEmptyBlocks = true
+	out := apply(policy, stream())
+
+	assert.Equal(t, []string{"b1", "b2", "b3", "b4", "b5", "b6"}, blockIDs(out))
+	assertValid(t, out, true)
+}
+
+func TestFilterMask(t *testing.T) {
+	policy := Policy{Rules: []Rule{
+		{ToolNames: []string{"lookup"}, Action: Mask, Mask: "[tool call hidden]"},
+		ByBlockTag(Mask, "audience", nil),
+	}}
+	out := apply(policy, stream())
+	assertValid(t, out, false)
+
+	m := acp.NewCreator(nil)
+	for _, e := range out {
+		require.NoError(t, m.AddEvent(e))
+	}
+	require.Len(t, m.Blocks, 6)
+
+	tool := m.Blocks[0].Contents[3]
+	assert.Equal(t, "[tool call hidden]", tool.(*acp.TextContent).Text)
+	assert.Equal(t, DefaultMask, m.Blocks[3].Contents[0].(*acp.TextContent).Text)
+}
+
+func TestFilterStructure(t *testing.T) {
+	policies := map[string]Policy{
+		"end user":  EndUser(),
+		"drop text": {Rules: []Rule{ByContentType(Drop, acp.ContentTypeText)}},
+		"drop subtree": {Rules: []Rule{
+			ByBlockTag(Drop, "audience", "internal"),
+			ByContentType(Drop, acp.ContentTypeThinking, acp.ContentTypeText),
+		}},
+		"drop in tagged": {Rules: []Rule{
+			{ContentTypes: []string{acp.ContentTypeText}, BlockMetadata: map[string]any{"audience": "internal"}, Action: Drop},
+		}},
+	}
+	for name, policy := range policies {
+		t.Run(name, func(t *testing.T) {
+			assertValid(t, apply(policy, stream()), false)
+		})
+	}
+
+	out := apply(policies["drop in tagged"], stream())
+	assert.NotContains(t, contentIDs(out), "sub")
+	assert.Contains(t, contentIDs(out), "text")
+}
+
+func TestWriterAndTee(t *testing.T) {
+	var full, user bytes.Buffer
+	m := acp.NewCreator(acp.NewSSEWriter(&full),
+		acp.WithInterceptors(Tee(acp.NewSSEWriter(&user), EndUser())))
+	for _, e := range stream() {
+		require.NoError(t, m.AddEvent(e))
+	}
+
+	assert.Contains(t, full.String(), "private reasoning")
+	assert.NotContains(t, user.String(), "private reasoning")
+	assert.NotContains(t, user.String(), "rm -rf")
+	assert.Contains(t, user.String(), "hello")
+	assert.Equal(t, 0, strings.Count(user.String(), `"block_id":"b2"`))
+}
+
+func TestInterceptor(t *testing.T) {
+	m := acp.NewCreator(nil, acp.WithInterceptors(Interceptor(EndUser())))
+	for _, e := range stream() {
+		require.NoError(t, m.AddEvent(e))
+	}
+
+	for _, b := range m.Blocks {
+		for _, c := range b.Contents {
+			assert.NotEqual(t, acp.ContentTypeThinking, c.Type())
+		}
+	}
+	assert.Len(t, m.Blocks, 5)
+}
+
+func TestFilterBlockError(t *testing.T) {
+	policy := Policy{Rules: []Rule{
+		ByBlockTag(Drop, "audience", "internal"),
+		ByToolName(Mask, "lookup"),
+	}}
+	events := []acp.Event{
+		acp.NewBlockStartEvent("b1"),
+		acp.NewBlockErrorEvent("b1", &acp.RunError{Code: acp.ErrorCodeInternal, Message: "visible"}),
+		acp.NewContentStartEvent("tool", "b1"),
+		acp.NewContentDeltaEvent("tool", acp.NewStreamToolCallContent("lookup")),
+		acp.NewBlockErrorEvent("b1", &acp.RunError{Code: acp.ErrorCodeToolFailed, Message: "db password wrong", ContentID: "tool", Retryable: true}),
+		acp.NewContentEndEvent("tool"),
+		acp.NewBlockEndEvent("b1", nil),
+		acp.NewBlockStartEvent("b2", acp.WithMetadata(map[string]any{"audience": "internal"})),
+		acp.NewBlockErrorEvent("b2", acp.NewRunError(acp.ErrorCodeInternal, "hidden")),
+		acp.NewBlockEndEvent("b2", nil),
+	}
+
+	var errs []acp.RunError
+	out := apply(policy, events)
+	for _, e := range out {
+		if evt, ok := e.(acp.BlockErrorEvent); ok {
+			errs = append(errs, evt.Error)
+		}
+	}
+	assert.Equal(t, []acp.RunError{
+		{Code: acp.ErrorCodeInternal, Message: "visible", BlockID: "b1"},
+		{Code: acp.ErrorCodeToolFailed, Message: DefaultMask, BlockID: "b1", Retryable: true},
+	}, errs)
+	// 区块错误使区块先于内容发出
+	assert.Equal(t, []string{"b1"}, blockIDs(out))
+}
+
+func TestFilterRunError(t *testing.T) {
+	policy := Policy{Rules: []Rule{
+		ByBlockTag(Drop, "audience", "internal"),
+		ByToolName(Mask, "lookup"),
+	}}
+	prefix := []acp.Event{
+		acp.NewBlockStartEvent("b1"),
+		acp.NewContentStartEvent("tool", "b1"),
+		acp.NewContentDeltaEvent("tool", acp.NewStreamToolCallContent("lookup")),
+		acp.NewContentEndEvent("tool"),
+		acp.NewBlockStartEvent("b2", acp.WithMetadata(map[string]any{"audience": "internal"})),
+		acp.NewContentStartEvent("c2", "b2"),
+		acp.NewContentEndEvent("c2"),
+		acp.NewBlockEndEvent("b2", nil),
+		acp.NewBlockEndEvent("b1", nil),
+	}
+	runError := func(info *acp.RunError) acp.RunErrorEvent {
+		out := apply(policy, append(slices.Clone(prefix), acp.RunErrorEvent{
+			BaseEvent: acp.NewBaseEvent(acp.EventTypeRunError),
+			RunID:     "r1",
+			Error:     info.Message,
+			Info:      info,
+		}))
+		evt, ok := out[len(out)-1].(acp.RunErrorEvent)
+		require.True(t, ok, "run error must always be emitted")
+		return evt
+	}
+
+	evt := runError(&acp.RunError{Code: acp.ErrorCodeInternal, Message: "boom"})
+	assert.Equal(t, "boom", evt.Error)
+	assert.Equal(t, "boom", evt.Info.Message)
+
+	evt = runError(&acp.RunError{Code: acp.ErrorCodeToolFailed, Message: "db password wrong", BlockID: "b1", ContentID: "tool", Retryable: true})
+	assert.Equal(t, DefaultMask, evt.Error)
+	assert.Equal(t, &acp.RunError{Code: acp.ErrorCodeToolFailed, Message: DefaultMask, BlockID: "b1", Retryable: true}, evt.Info)
+
+	evt = runError(&acp.RunError{Code: acp.ErrorCodeInternal, Message: "internal trace", BlockID: "b2", ContentID: "c2", Details: map[string]any{"k": "v"}})
+	assert.Equal(t, acp.ErrorCodeInternal, evt.Error)
+	assert.Equal(t, &acp.RunError{Code: acp.ErrorCodeInternal}, evt.Info)
+}
+
+func TestFilterNotice(t *testing.T) {
+	policy := Policy{Rules: []Rule{
+		ByBlockTag(Drop, "audience", "internal"),
+		ByContentType(Mask, acp.ContentTypeThinking),
+	}}
+	events := []acp.Event{
+		acp.NewBlockStartEvent("b1"),
+		acp.NewContentStartEvent("think", "b1"),
+		acp.NewContentDeltaEvent("think", acp.NewStreamThinkingContent("secret")),
+		acp.NewNoticeEvent(acp.Notice{Code: "truncated", Message: "thinking truncated", ContentID: "think"}),
+		acp.NewContentEndEvent("think"),
+		acp.NewBlockStartEvent("b2", acp.WithMetadata(map[string]any{"audience": "internal"})),
+		acp.NewNoticeEvent(acp.Notice{Code: "slow", Message: "internal block slow", BlockID: "b2"}),
+		acp.NewBlockEndEvent("b2", nil),
+		acp.NewBlockEndEvent("b1", nil),
+		acp.NewNoticeEvent(acp.Notice{Code: "info", Message: "visible"}),
+	}
+
+	var notices []acp.Notice
+	for _, e := range apply(policy, events) {
+		if evt, ok := e.(acp.NoticeEvent); ok {
+			notices = append(notices, evt.Notice)
+		}
+	}
+	assert.Equal(t, []acp.Notice{
+		{Code: "truncated", Message: DefaultMask, ContentID: "think"},
+		{Code: "info", Message: "visible"},
+	}, notices)
+}
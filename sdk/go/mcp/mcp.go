@@ -0,0 +1,234 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/llm-infra/acp/sdk/go/acp"
+)
+
+// JSON-RPC 错误码
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// 映射到 acp.Error 的错误类型
+const (
+	ErrorTypeParse          = "parse_error"
+	ErrorTypeInvalidRequest = "invalid_request"
+	ErrorTypeMethodNotFound = "method_not_found"
+	ErrorTypeInvalidParams  = "invalid_params"
+	ErrorTypeInternal       = "internal_error"
+	ErrorTypeServer         = "server_error"
+	ErrorTypeTool           = "tool_error"
+	ErrorTypeTimeout        = "timeout"
+	ErrorTypeCanceled       = "canceled"
+	ErrorTypeClient         = "client_error"
+)
+
+// Model Context Protocol 客户端, 由调用方适配具体实现
+type Client interface {
+	CallTool(ctx context.Context, name string, args json.RawMessage) (*CallToolResult, error)
+}
+
+// tools/call 结果
+type CallToolResult struct {
+	Content []ContentItem `json:"content"`
+	IsError bool          `json:"isError,omitempty"`
+}
+
+type ContentItem struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+	URI      string `json:"uri,omitempty"`
+}
+
+// Text 全部为文本时按行拼接, 否则返回内容的 JSON
+func (r *CallToolResult) Text() string {
+	texts := make([]string, 0, len(r.Content))
+	for _, c := range r.Content {
+		if c.Type != "text" {
+			data, _ := json.Marshal(r.Content)
+			return string(data)
+		}
+		texts = append(texts, c.Text)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// JSON-RPC 错误对象
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("mcp error %d: %s", e.Code, e.Message)
+}
+
+// ToError 将调用错误映射为 acp.Error
+func ToError(err error) *acp.Error {
+	if err == nil {
+		return nil
+	}
+
+	var rpcErr *RPCError
+	switch {
+	case errors.As(err, &rpcErr):
+		return &acp.Error{Type: rpcErrorType(rpcErr.Code), Message: rpcErr.Message}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &acp.Error{Type: ErrorTypeTimeout, Message: err.Error()}
+	case errors.Is(err, context.Canceled):
+		return &acp.Error{Type: ErrorTypeCanceled, Message: err.Error()}
+	default:
+		return &acp.Error{Type: ErrorTypeClient, Message: err.Error()}
+	}
+}
+
+func rpcErrorType(code int) string {
+	switch code {
+	case CodeParseError:
+		return ErrorTypeParse
+	case CodeInvalidRequest:
+		return ErrorTypeInvalidRequest
+	case CodeMethodNotFound:
+		return ErrorTypeMethodNotFound
+	case CodeInvalidParams:
+		return ErrorTypeInvalidParams
+	case CodeInternalError:
+		return ErrorTypeInternal
+	default:
+		return ErrorTypeServer
+	}
+}
+
+// 将 MCP 工具调用自动转换为 MCPContent 事件写入 Creator
+// 同一 Bridge 上的并发调用会串行写入事件
+type Bridge struct {
+	creator *acp.Creator
+	server  string
+	client  Client
+	mux     sync.Mutex
+}
+
+func NewBridge(creator *acp.Creator, server string, client Client) *Bridge {
+	return &Bridge{
+		creator: creator,
+		server:  server,
+		client:  client,
+	}
+}
+
+// CallTool 在 blockID 区块下开启内容, 写入参数, 调用工具并写入结果后结束内容
+func (b *Bridge) CallTool(ctx context.Context, blockID, name string, args json.RawMessage) (*CallToolResult, error) {
+	call, err := b.Start(blockID, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := call.AppendArgs(string(args)); err != nil {
+		return nil, err
+	}
+	return call.Invoke(ctx)
+}
+
+// Start 开启一次工具调用, 参数可随模型输出分片写入
+func (b *Bridge) Start(blockID, name string) (*Call, error) {
+	call := &Call{
+		bridge:    b,
+		contentID: uuid.NewString(),
+		name:      name,
+	}
+
+	if err := b.emit(
+		acp.NewContentStartEvent(call.contentID, blockID),
+		acp.NewContentDeltaEvent(call.contentID, acp.NewStreamMCPCallContent(b.server, name)),
+	); err != nil {
+		return nil, err
+	}
+	return call, nil
+}
+
+func (b *Bridge) emit(events ...acp.Event) error {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	for _, e := range events {
+		if err := b.creator.AddEvent(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type Call struct {
+	bridge    *Bridge
+	contentID string
+	name      string
+	args      strings.Builder
+	done      bool
+}
+
+// ContentID 返回该调用对应的 ACP 内容 ID
+func (c *Call) ContentID() string {
+	return c.contentID
+}
+
+func (c *Call) AppendArgs(delta string) error {
+	if c.done {
+		return errors.New("mcp call already finished")
+	}
+	if delta == "" {
+		return nil
+	}
+
+	c.args.WriteString(delta)
+	return c.bridge.emit(acp.NewContentDeltaEvent(c.contentID, acp.NewStreamMCPArgsContent(delta)))
+}
+
+// Invoke 调用工具, 工具返回 isError 或调用失败时写入错误并结束内容
+// 调用失败时返回原始错误, isError 结果不视为 Go 错误
+func (c *Call) Invoke(ctx context.Context) (*CallToolResult, error) {
+	if c.done {
+		return nil, errors.New("mcp call already finished")
+	}
+	c.done = true
+
+	args := json.RawMessage(c.args.String())
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+
+	result, callErr := c.bridge.client.CallTool(ctx, c.name, args)
+
+	var delta acp.StreamMCPResultContent
+	switch {
+	case callErr != nil:
+		delta = acp.NewStreamMCPErrorContent(ToError(callErr))
+	case result == nil:
+		delta = acp.NewStreamMCPResultContent("")
+	case result.IsError:
+		delta = acp.NewStreamMCPErrorContent(&acp.Error{Type: ErrorTypeTool, Message: result.Text()})
+	default:
+		delta = acp.NewStreamMCPResultContent(result.Text())
+	}
+
+	if err := c.bridge.emit(
+		acp.NewContentDeltaEvent(c.contentID, delta),
+		acp.NewContentEndEvent(c.contentID),
+	); err != nil {
+		return result, err
+	}
+	return result, callErr
+}
@@ -0,0 +1,134 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type toolFunc func(ctx context.Context, args map[string]any) (*CallToolResult, error)
+
+// 进程内 MCP 服务端桩
+type stubServer struct {
+	tools map[string]toolFunc
+}
+
+func (s *stubServer) CallTool(ctx context.Context, name string, args json.RawMessage) (*CallToolResult, error) {
+	tool, ok := s.tools[name]
+	if !ok {
+		return nil, &RPCError{Code: CodeMethodNotFound, Message: fmt.Sprintf("tool %s not found", name)}
+	}
+
+	var params map[string]any
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, &RPCError{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	return tool(ctx, params)
+}
+
+func newStubServer() *stubServer {
+	return &stubServer{tools: map[string]toolFunc{
+		"echo": func(_ context.Context, args map[string]any) (*CallToolResult, error) {
+			return &CallToolResult{Content: []ContentItem{{Type: "text", Text: fmt.Sprint(args["text"])}}}, nil
+		},
+		"fail": func(_ context.Context, _ map[string]any) (*CallToolResult, error) {
+			return &CallToolResult{IsError: true, Content: []ContentItem{{Type: "text", Text: "disk full"}}}, nil
+		},
+		"slow": func(ctx context.Context, _ map[string]any) (*CallToolResult, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}}
+}
+
+func newBridge(t *testing.T) (*acp.Creator, *Bridge) {
+	t.Helper()
+
+	creator := acp.NewCreator(nil)
+	require.NoError(t, creator.AddEvent(acp.NewBlockStartEvent("b1")))
+	return creator, NewBridge(creator, "fs", newStubServer())
+}
+
+func mcpContent(t *testing.T, creator *acp.Creator, i int) *acp.MCPContent {
+	t.Helper()
+
+	require.Greater(t, len(creator.Blocks[0].Contents), i)
+	c, ok := creator.Blocks[0].Contents[i].(*acp.MCPContent)
+	require.True(t, ok)
+	return c
+}
+
+func TestBridgeCallTool(t *testing.T) {
+	creator, bridge := newBridge(t)
+
+	result, err := bridge.CallTool(context.Background(), "b1", "echo", json.RawMessage(`{"text":"hi"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "hi", result.Text())
+
+	c := mcpContent(t, creator, 0)
+	assert.Equal(t, "fs", c.Server)
+	assert.Equal(t, "echo", c.ToolName)
+	assert.Equal(t, `{"text":"hi"}`, c.ToolArgs)
+	assert.Equal(t, "hi", c.ToolResult)
+	assert.Nil(t, c.Error)
+}
+
+func TestBridgeStreamsArgs(t *testing.T) {
+	creator, bridge := newBridge(t)
+
+	call, err := bridge.Start("b1", "echo")
+	require.NoError(t, err)
+	require.NoError(t, call.AppendArgs(`{"text":`))
+	require.NoError(t, call.AppendArgs(`"streamed"}`))
+
+	_, err = call.Invoke(context.Background())
+	require.NoError(t, err)
+	assert.Error(t, call.AppendArgs("x"))
+
+	c := mcpContent(t, creator, 0)
+	assert.Equal(t, `{"text":"streamed"}`, c.ToolArgs)
+	assert.Equal(t, "streamed", c.ToolResult)
+}
+
+func TestBridgeErrors(t *testing.T) {
+	creator, bridge := newBridge(t)
+
+	_, err := bridge.CallTool(context.Background(), "b1", "fail", nil)
+	require.NoError(t, err)
+
+	_, err = bridge.CallTool(context.Background(), "b1", "missing", nil)
+	var rpcErr *RPCError
+	require.True(t, errors.As(err, &rpcErr))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = bridge.CallTool(ctx, "b1", "slow", nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.Equal(t, &acp.Error{Type: ErrorTypeTool, Message: "disk full"}, mcpContent(t, creator, 0).Error)
+	assert.Equal(t, &acp.Error{Type: ErrorTypeMethodNotFound, Message: "tool missing not found"}, mcpContent(t, creator, 1).Error)
+	assert.Equal(t, ErrorTypeTimeout, mcpContent(t, creator, 2).Error.Type)
+}
+
+func TestToError(t *testing.T) {
+	assert.Nil(t, ToError(nil))
+	assert.Equal(t, ErrorTypeInvalidParams, ToError(fmt.Errorf("wrap: %w", &RPCError{Code: CodeInvalidParams})).Type)
+	assert.Equal(t, ErrorTypeServer, ToError(&RPCError{Code: -32000}).Type)
+	assert.Equal(t, ErrorTypeCanceled, ToError(context.Canceled).Type)
+	assert.Equal(t, ErrorTypeClient, ToError(errors.New("boom")).Type)
+}
+
+func TestCallToolResultText(t *testing.T) {
+	r := &CallToolResult{Content: []ContentItem{{Type: "text", Text: "a"}, {Type: "text", Text: "b"}}}
+	assert.Equal(t, "a\nb", r.Text())
+
+	r = &CallToolResult{Content: []ContentItem{{Type: "image", Data: "AA==", MimeType: "image/png"}}}
+	assert.JSONEq(t, `[{"type":"image","data":"AA==","mimeType":"image/png"}]`, r.Text())
+}
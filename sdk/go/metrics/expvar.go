@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"sync"
+	"time"
+)
+
+// 计数与均值, 以 JSON 形式暴露
+type summaryVar struct {
+	mux   sync.Mutex
+	count int64
+	sum   float64
+}
+
+func (s *summaryVar) observe(v float64) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.count++
+	s.sum += v
+}
+
+func (s *summaryVar) String() string {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	avg := 0.0
+	if s.count > 0 {
+		avg = s.sum / float64(s.count)
+	}
+	data, _ := json.Marshal(map[string]any{"count": s.count, "sum": s.sum, "avg": avg})
+	return string(data)
+}
+
+// Expvar 将指标发布到 expvar, 耗时单位为毫秒
+type Expvar struct {
+	vars *expvar.Map
+}
+
+// NewExpvar 以 name 发布 expvar.Map, 与 expvar.Publish 一样同名重复发布会 panic
+func NewExpvar(name string) *Expvar {
+	vars := expvar.NewMap(name)
+	for _, key := range []string{"ttft_ms", "inter_token_ms", "tokens_per_second", "run_duration_ms", "tool_duration_ms"} {
+		vars.Set(key, &summaryVar{})
+	}
+	return &Expvar{vars: vars}
+}
+
+func (e *Expvar) ObserveTTFT(d time.Duration) {
+	e.observe("ttft_ms", ms(d))
+}
+
+func (e *Expvar) ObserveInterToken(d time.Duration) {
+	e.observe("inter_token_ms", ms(d))
+}
+
+func (e *Expvar) ObserveTokensPerSecond(v float64) {
+	e.observe("tokens_per_second", v)
+}
+
+func (e *Expvar) ObserveRun(d time.Duration, status string) {
+	e.observe("run_duration_ms", ms(d))
+	e.vars.Add("runs_total", 1)
+	if status == StatusError {
+		e.vars.Add("run_errors_total", 1)
+	}
+}
+
+func (e *Expvar) ObserveToolCall(kind, name string, d time.Duration, status string) {
+	e.observe("tool_duration_ms", ms(d))
+	e.vars.Add("tool_calls_total", 1)
+	if status == StatusError {
+		e.vars.Add("tool_errors_total", 1)
+	}
+}
+
+func (e *Expvar) observe(key string, v float64) {
+	e.vars.Get(key).(*summaryVar).observe(v)
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
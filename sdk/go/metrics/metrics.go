@@ -0,0 +1,230 @@
+// Package metrics 根据 Creator 的事件流统计首 token 延迟、吞吐和工具耗时
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+)
+
+// 运行状态标签
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+// 工具类别标签
+const (
+	KindTool    = "tool"
+	KindMCP     = "mcp"
+	KindCommand = "command"
+	KindCode    = "code"
+)
+
+// 指标后端
+type Metrics interface {
+	ObserveTTFT(d time.Duration)
+	ObserveInterToken(d time.Duration)
+	ObserveTokensPerSecond(v float64)
+	ObserveRun(d time.Duration, status string)
+	ObserveToolCall(kind, name string, d time.Duration, status string)
+}
+
+type toolCall struct {
+	kind   string
+	name   string
+	start  int64
+	failed bool
+}
+
+// Collector 以 Intercept 作为拦截器统计单次运行的指标, 每个 Creator 使用一个实例,
+// 只统计被成功处理的事件. 运行结束时上报到 Metrics, 并把摘要写入 Message.Metrics.
+type Collector struct {
+	mux     sync.Mutex
+	sink    Metrics
+	running bool
+	started int64
+	tokens  int
+	first   int64
+	last    int64
+	gaps    int
+	gapSum  int64
+	pending map[string]int64
+	tools   map[string]*toolCall
+	summary acp.RunMetrics
+}
+
+// nopMetrics 丢弃所有指标, 只保留 Message.Metrics 摘要
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveTTFT(time.Duration)                             {}
+func (nopMetrics) ObserveInterToken(time.Duration)                       {}
+func (nopMetrics) ObserveTokensPerSecond(float64)                        {}
+func (nopMetrics) ObserveRun(time.Duration, string)                      {}
+func (nopMetrics) ObserveToolCall(string, string, time.Duration, string) {}
+
+// NewCollector sink 为 nil 时只写入 Message.Metrics, 不上报指标后端
+func NewCollector(sink Metrics) *Collector {
+	if sink == nil {
+		sink = nopMetrics{}
+	}
+	return &Collector{
+		sink:    sink,
+		pending: make(map[string]int64),
+		tools:   make(map[string]*toolCall),
+	}
+}
+
+// Intercept 实现 acp.Interceptor
+func (c *Collector) Intercept(ctx context.Context, e acp.Event, next acp.Handler) error {
+	if err := next(ctx, e); err != nil {
+		return err
+	}
+	m, _ := acp.CreatorFrom(ctx)
+	c.observe(m, e)
+	return nil
+}
+
+func (c *Collector) observe(m *acp.Creator, e acp.Event) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	ts := e.Timestamp()
+	switch evt := e.(type) {
+	case acp.RunStartedEvent:
+		// 重复的 RunStarted 会被 Creator 忽略
+		if c.running {
+			return
+		}
+		c.running = true
+		c.started = ts
+
+	case acp.RunFinishedEvent:
+		c.finish(m, ts, StatusOK)
+
+	case acp.RunErrorEvent:
+		c.finish(m, ts, StatusError)
+
+	case acp.ContentStartEvent:
+		c.pending[evt.ContentID] = ts
+
+	case acp.ContentDeltaEvent:
+		c.onDelta(evt)
+
+	case acp.ContentEndEvent:
+		delete(c.pending, evt.ContentID)
+		if call, ok := c.tools[evt.ContentID]; ok {
+			c.endTool(call, ts)
+			delete(c.tools, evt.ContentID)
+		}
+	}
+}
+
+func (c *Collector) onDelta(evt acp.ContentDeltaEvent) {
+	ts := evt.Timestamp()
+	switch sc := evt.Content.(type) {
+	case acp.StreamTextContent:
+		// 首 token 与间隔只统计文本, 思考内容不计入
+		c.onToken(ts)
+	case acp.StreamToolCallContent:
+		c.startTool(evt.ContentID, KindTool, sc.ToolName)
+	case acp.StreamMCPCallContent:
+		c.startTool(evt.ContentID, KindMCP, sc.Server+"/"+sc.ToolName)
+	case acp.StreamCommandContent:
+		c.startTool(evt.ContentID, KindCommand, "")
+	case acp.StreamCodeExecutionContent:
+		c.startTool(evt.ContentID, KindCode, sc.Lang)
+	case acp.StreamToolResultContent:
+		c.markFailed(evt.ContentID, sc.Error != nil)
+	case acp.StreamMCPResultContent:
+		c.markFailed(evt.ContentID, sc.Error != nil)
+	case acp.StreamCodeExecutionResultContent:
+		c.markFailed(evt.ContentID, sc.Error != nil)
+	case acp.StreamCommandResultContent:
+		c.markFailed(evt.ContentID, sc.Error != nil || (sc.ExitCode != nil && *sc.ExitCode != 0))
+	}
+}
+
+func (c *Collector) onToken(ts int64) {
+	c.tokens++
+	if c.tokens == 1 {
+		c.first = ts
+		if c.running {
+			ttft := ts - c.started
+			c.summary.TTFTMs = &ttft
+			c.sink.ObserveTTFT(time.Duration(ttft) * time.Millisecond)
+		}
+	} else {
+		gap := ts - c.last
+		c.gaps++
+		c.gapSum += gap
+		c.sink.ObserveInterToken(time.Duration(gap) * time.Millisecond)
+	}
+	c.last = ts
+}
+
+func (c *Collector) startTool(id, kind, name string) {
+	start, ok := c.pending[id]
+	if !ok {
+		return
+	}
+	if _, ok := c.tools[id]; !ok {
+		c.tools[id] = &toolCall{kind: kind, name: name, start: start}
+	}
+}
+
+func (c *Collector) markFailed(id string, failed bool) {
+	if call, ok := c.tools[id]; ok && failed {
+		call.failed = true
+	}
+}
+
+func (c *Collector) endTool(call *toolCall, ts int64) {
+	d := ts - call.start
+	status := StatusOK
+	if call.failed {
+		status = StatusError
+		c.summary.ToolErrors++
+	}
+	c.summary.ToolCalls++
+	c.summary.ToolDurationMs += d
+	c.sink.ObserveToolCall(call.kind, call.name, time.Duration(d)*time.Millisecond, status)
+}
+
+func (c *Collector) finish(m *acp.Creator, ts int64, status string) {
+	// 未结束的工具调用视为失败
+	for id, call := range c.tools {
+		call.failed = true
+		c.endTool(call, ts)
+		delete(c.tools, id)
+	}
+
+	if c.running {
+		c.summary.DurationMs = ts - c.started
+	}
+	if c.gaps > 0 {
+		c.summary.InterTokenMs = float64(c.gapSum) / float64(c.gaps)
+	}
+	if m == nil {
+		return
+	}
+	if m.Usage != nil && m.Usage.CompletionTokens > 0 && c.tokens > 0 && ts > c.first {
+		c.summary.TokensPerSecond = float64(m.Usage.CompletionTokens) / (float64(ts-c.first) / 1000)
+		c.sink.ObserveTokensPerSecond(c.summary.TokensPerSecond)
+	}
+	c.summary.Failed = status == StatusError
+	c.sink.ObserveRun(time.Duration(c.summary.DurationMs)*time.Millisecond, status)
+
+	summary := c.summary
+	m.Metrics = &summary
+}
+
+// Summary 返回当前统计的摘要副本
+func (c *Collector) Summary() acp.RunMetrics {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return c.summary
+}
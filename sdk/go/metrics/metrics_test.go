@@ -0,0 +1,211 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// at 以固定时间戳(毫秒)构造事件, 使计算结果可断言
+func at(ms int64, e acp.Event) acp.Event {
+	switch evt := e.(type) {
+	case acp.RunStartedEvent:
+		evt.TimestampMs = ms
+		return evt
+	case acp.RunFinishedEvent:
+		evt.TimestampMs = ms
+		return evt
+	case acp.RunErrorEvent:
+		evt.TimestampMs = ms
+		return evt
+	case acp.BlockStartEvent:
+		evt.TimestampMs = ms
+		return evt
+	case acp.BlockEndEvent:
+		evt.TimestampMs = ms
+		return evt
+	case acp.ContentStartEvent:
+		evt.TimestampMs = ms
+		return evt
+	case acp.ContentDeltaEvent:
+		evt.TimestampMs = ms
+		return evt
+	case acp.ContentEndEvent:
+		evt.TimestampMs = ms
+		return evt
+	}
+	return e
+}
+
+func run(t *testing.T, sinks ...Metrics) *acp.Creator {
+	interceptors := make([]acp.Interceptor, 0, len(sinks))
+	for _, s := range sinks {
+		interceptors = append(interceptors, NewCollector(s).Intercept)
+	}
+	m := acp.NewCreator(nil, acp.WithInterceptors(interceptors...))
+
+	events := []acp.Event{
+		at(1000, acp.NewRunStartedEvent("s1", "r1")),
+		at(1000, acp.NewBlockStartEvent("b1")),
+		at(1100, acp.NewContentStartEvent("c1", "b1")),
+		at(1250, acp.NewContentDeltaEvent("c1", acp.NewStreamTextContent("a"))),
+		at(1300, acp.NewContentDeltaEvent("c1", acp.NewStreamTextContent("b"))),
+		at(1400, acp.NewContentDeltaEvent("c1", acp.NewStreamTextContent("c"))),
+		at(1400, acp.NewContentEndEvent("c1")),
+		at(1500, acp.NewContentStartEvent("c2", "b1")),
+		at(1500, acp.NewContentDeltaEvent("c2", acp.NewStreamToolCallContent("search"))),
+		at(1700, acp.NewContentDeltaEvent("c2", acp.NewStreamToolResultContent("ok"))),
+		at(1800, acp.NewContentEndEvent("c2")),
+		at(1800, acp.NewContentStartEvent("c3", "b1")),
+		at(1800, acp.NewContentDeltaEvent("c3", acp.NewStreamCommandContent("false"))),
+		at(1900, acp.NewContentDeltaEvent("c3", acp.NewStreamCommandResultContent("", 1))),
+		at(2000, acp.NewContentEndEvent("c3")),
+		at(2250, acp.NewBlockEndEvent("b1", &acp.Usage{CompletionTokens: 50})),
+		at(2250, acp.NewRunFinishedEvent("r1")),
+	}
+	for _, e := range events {
+		require.NoError(t, m.AddEvent(e))
+	}
+	return m
+}
+
+type recorder struct {
+	ttft       []time.Duration
+	interToken []time.Duration
+	tps        []float64
+	runs       []string
+	tools      []string
+}
+
+func (r *recorder) ObserveTTFT(d time.Duration)       { r.ttft = append(r.ttft, d) }
+func (r *recorder) ObserveInterToken(d time.Duration) { r.interToken = append(r.interToken, d) }
+func (r *recorder) ObserveTokensPerSecond(v float64)  { r.tps = append(r.tps, v) }
+func (r *recorder) ObserveRun(d time.Duration, status string) {
+	r.runs = append(r.runs, status+" "+d.String())
+}
+func (r *recorder) ObserveToolCall(kind, name string, d time.Duration, status string) {
+	r.tools = append(r.tools, strings.Join([]string{kind, name, d.String(), status}, " "))
+}
+
+func TestCollector(t *testing.T) {
+	rec := &recorder{}
+	m := run(t, rec)
+
+	assert.Equal(t, []time.Duration{250 * time.Millisecond}, rec.ttft)
+	assert.Equal(t, []time.Duration{50 * time.Millisecond, 100 * time.Millisecond}, rec.interToken)
+	assert.Equal(t, []float64{50}, rec.tps)
+	assert.Equal(t, []string{"ok 1.25s"}, rec.runs)
+	assert.Equal(t, []string{"tool search 300ms ok", "command  200ms error"}, rec.tools)
+
+	require.NotNil(t, m.Metrics)
+	require.NotNil(t, m.Metrics.TTFTMs)
+	assert.Equal(t, int64(250), *m.Metrics.TTFTMs)
+	assert.Equal(t, acp.RunMetrics{
+		DurationMs:      1250,
+		TTFTMs:          m.Metrics.TTFTMs,
+		InterTokenMs:    75,
+		TokensPerSecond: 50,
+		ToolCalls:       2,
+		ToolErrors:      1,
+		ToolDurationMs:  500,
+	}, *m.Metrics)
+}
+
+func TestCollectorRunError(t *testing.T) {
+	rec := &recorder{}
+	m := acp.NewCreator(nil, acp.WithInterceptors(NewCollector(rec).Intercept))
+
+	require.NoError(t, m.AddEvent(at(0, acp.NewRunStartedEvent("s1", "r1"))))
+	require.NoError(t, m.AddEvent(at(0, acp.NewBlockStartEvent("b1"))))
+	require.NoError(t, m.AddEvent(at(10, acp.NewContentStartEvent("c1", "b1"))))
+	require.NoError(t, m.AddEvent(at(10, acp.NewContentDeltaEvent("c1", acp.NewStreamMCPCallContent("fs", "read")))))
+	require.NoError(t, m.AddEvent(at(40, acp.NewRunErrorEvent("r1", "boom"))))
+
+	assert.Empty(t, rec.ttft)
+	assert.Equal(t, []string{"error 40ms"}, rec.runs)
+	assert.Equal(t, []string{"mcp fs/read 30ms error"}, rec.tools)
+	assert.True(t, m.Metrics.Failed)
+	assert.Nil(t, m.Metrics.TTFTMs)
+}
+
+func TestCollectorIgnoresThinking(t *testing.T) {
+	rec := &recorder{}
+	m := acp.NewCreator(nil, acp.WithInterceptors(NewCollector(rec).Intercept))
+
+	require.NoError(t, m.AddEvent(at(0, acp.NewRunStartedEvent("s1", "r1"))))
+	require.NoError(t, m.AddEvent(at(0, acp.NewBlockStartEvent("b1"))))
+	require.NoError(t, m.AddEvent(at(0, acp.NewContentStartEvent("t1", "b1"))))
+	require.NoError(t, m.AddEvent(at(100, acp.NewContentDeltaEvent("t1", acp.NewStreamThinkingContent("hmm")))))
+	require.NoError(t, m.AddEvent(at(200, acp.NewContentDeltaEvent("t1", acp.NewStreamThinkingContent("...")))))
+	require.NoError(t, m.AddEvent(at(200, acp.NewContentEndEvent("t1"))))
+	require.NoError(t, m.AddEvent(at(300, acp.NewContentStartEvent("c1", "b1"))))
+	require.NoError(t, m.AddEvent(at(400, acp.NewContentDeltaEvent("c1", acp.NewStreamTextContent("a")))))
+	require.NoError(t, m.AddEvent(at(400, acp.NewContentEndEvent("c1"))))
+	require.NoError(t, m.AddEvent(at(500, acp.NewRunFinishedEvent("r1"))))
+
+	assert.Equal(t, []time.Duration{400 * time.Millisecond}, rec.ttft)
+	assert.Empty(t, rec.interToken)
+}
+
+func TestCollectorNilSink(t *testing.T) {
+	m := acp.NewCreator(nil, acp.WithInterceptors(NewCollector(nil).Intercept))
+
+	require.NoError(t, m.AddEvent(at(0, acp.NewRunStartedEvent("s1", "r1"))))
+	require.NoError(t, m.AddEvent(at(0, acp.NewBlockStartEvent("b1"))))
+	require.NoError(t, m.AddEvent(at(0, acp.NewContentStartEvent("c1", "b1"))))
+	require.NoError(t, m.AddEvent(at(50, acp.NewContentDeltaEvent("c1", acp.NewStreamTextContent("a")))))
+	require.NoError(t, m.AddEvent(at(100, acp.NewRunFinishedEvent("r1"))))
+
+	require.NotNil(t, m.Metrics)
+	require.NotNil(t, m.Metrics.TTFTMs)
+	assert.Equal(t, int64(50), *m.Metrics.TTFTMs)
+	assert.Equal(t, int64(100), m.Metrics.DurationMs)
+}
+
+func TestPrometheus(t *testing.T) {
+	p := NewPrometheus("acp", WithBuckets(0.1, 1))
+	run(t, p)
+
+	rw := httptest.NewRecorder()
+	p.ServeHTTP(rw, httptest.NewRequest("GET", "/metrics", nil))
+	out := rw.Body.String()
+
+	assert.Contains(t, rw.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, out, "# TYPE acp_time_to_first_token_seconds histogram\n")
+	assert.Contains(t, out, "acp_time_to_first_token_seconds_bucket{le=\"0.1\"} 0\n")
+	assert.Contains(t, out, "acp_time_to_first_token_seconds_bucket{le=\"1\"} 1\n")
+	assert.Contains(t, out, "acp_time_to_first_token_seconds_count 1\n")
+	assert.Contains(t, out, "acp_run_duration_seconds_bucket{status=\"ok\",le=\"+Inf\"} 1\n")
+	assert.Contains(t, out, "acp_run_duration_seconds_sum{status=\"ok\"} 1.25\n")
+	assert.Contains(t, out, "acp_tool_call_duration_seconds_count{kind=\"command\",tool=\"\",status=\"error\"} 1\n")
+	assert.Contains(t, out, "acp_tool_call_duration_seconds_count{kind=\"tool\",tool=\"search\",status=\"ok\"} 1\n")
+
+	var buf bytes.Buffer
+	require.NoError(t, p.Write(&buf))
+	assert.Equal(t, out, buf.String())
+}
+
+func TestPrometheusLabelEscaping(t *testing.T) {
+	assert.Equal(t, `tool="天气\\查询\"v2\"\n"`, labels("tool", "天气\\查询\"v2\"\n"))
+}
+
+func TestExpvar(t *testing.T) {
+	e := NewExpvar("acp_metrics_test")
+	run(t, e)
+
+	var vars map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get("acp_metrics_test").String()), &vars))
+	assert.JSONEq(t, "1", string(vars["runs_total"]))
+	assert.JSONEq(t, "2", string(vars["tool_calls_total"]))
+	assert.JSONEq(t, "1", string(vars["tool_errors_total"]))
+	assert.JSONEq(t, `{"count":1,"sum":250,"avg":250}`, string(vars["ttft_ms"]))
+	assert.JSONEq(t, `{"count":2,"sum":150,"avg":75}`, string(vars["inter_token_ms"]))
+}
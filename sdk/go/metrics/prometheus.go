@@ -0,0 +1,208 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 默认分桶, 单位秒
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, b := range h.buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// Prometheus 以文本暴露格式输出指标, 不依赖 Prometheus 客户端库
+type Prometheus struct {
+	mux        sync.Mutex
+	namespace  string
+	buckets    []float64
+	ttft       *histogram
+	interToken *histogram
+	tps        *histogram
+	runs       map[string]*histogram // status -> duration
+	tools      map[[3]string]*histogram
+}
+
+type PrometheusOption func(*Prometheus)
+
+// WithBuckets 自定义耗时分桶(秒)
+func WithBuckets(buckets ...float64) PrometheusOption {
+	return func(p *Prometheus) { p.buckets = slices.Sorted(slices.Values(buckets)) }
+}
+
+func NewPrometheus(namespace string, opts ...PrometheusOption) *Prometheus {
+	p := &Prometheus{
+		namespace: namespace,
+		buckets:   DefaultBuckets,
+		runs:      make(map[string]*histogram),
+		tools:     make(map[[3]string]*histogram),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	p.ttft = newHistogram(p.buckets)
+	p.interToken = newHistogram(p.buckets)
+	p.tps = newHistogram([]float64{1, 5, 10, 25, 50, 100, 200, 500})
+	return p
+}
+
+func (p *Prometheus) ObserveTTFT(d time.Duration) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.ttft.observe(d.Seconds())
+}
+
+func (p *Prometheus) ObserveInterToken(d time.Duration) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.interToken.observe(d.Seconds())
+}
+
+func (p *Prometheus) ObserveTokensPerSecond(v float64) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.tps.observe(v)
+}
+
+func (p *Prometheus) ObserveRun(d time.Duration, status string) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	h, ok := p.runs[status]
+	if !ok {
+		h = newHistogram(p.buckets)
+		p.runs[status] = h
+	}
+	h.observe(d.Seconds())
+}
+
+func (p *Prometheus) ObserveToolCall(kind, name string, d time.Duration, status string) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	key := [3]string{kind, name, status}
+	h, ok := p.tools[key]
+	if !ok {
+		h = newHistogram(p.buckets)
+		p.tools[key] = h
+	}
+	h.observe(d.Seconds())
+}
+
+// Write 按 Prometheus 文本格式输出全部指标
+func (p *Prometheus) Write(w io.Writer) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	var sb strings.Builder
+	p.writeHistogram(&sb, "time_to_first_token_seconds", "Time from run start to the first text delta.", p.ttft)
+	p.writeHistogram(&sb, "inter_token_latency_seconds", "Gap between consecutive text deltas.", p.interToken)
+	p.writeHistogram(&sb, "tokens_per_second", "Completion tokens per second after the first token.", p.tps)
+
+	p.header(&sb, "run_duration_seconds", "Run duration by status.", "histogram")
+	for _, status := range sortedKeys(p.runs) {
+		p.writeSeries(&sb, "run_duration_seconds", labels("status", status), p.runs[status])
+	}
+
+	p.header(&sb, "tool_call_duration_seconds", "Tool call duration by kind, tool and status.", "histogram")
+	keys := make([][3]string, 0, len(p.tools))
+	for k := range p.tools {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b [3]string) int {
+		return strings.Compare(strings.Join(a[:], "\x00"), strings.Join(b[:], "\x00"))
+	})
+	for _, k := range keys {
+		p.writeSeries(&sb, "tool_call_duration_seconds", labels("kind", k[0], "tool", k[1], "status", k[2]), p.tools[k])
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.Write(w)
+}
+
+func (p *Prometheus) name(metric string) string {
+	if p.namespace == "" {
+		return metric
+	}
+	return p.namespace + "_" + metric
+}
+
+func (p *Prometheus) header(sb *strings.Builder, metric, help, typ string) {
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s %s\n", p.name(metric), help, p.name(metric), typ)
+}
+
+func (p *Prometheus) writeHistogram(sb *strings.Builder, metric, help string, h *histogram) {
+	p.header(sb, metric, help, "histogram")
+	p.writeSeries(sb, metric, "", h)
+}
+
+func (p *Prometheus) writeSeries(sb *strings.Builder, metric, lbls string, h *histogram) {
+	name := p.name(metric)
+	sep := ""
+	if lbls != "" {
+		sep = ","
+	}
+	for i, b := range h.buckets {
+		fmt.Fprintf(sb, "%s_bucket{%s%sle=\"%s\"} %d\n", name, lbls, sep, formatFloat(b), h.counts[i])
+	}
+	fmt.Fprintf(sb, "%s_bucket{%s%sle=\"+Inf\"} %d\n", name, lbls, sep, h.count)
+	if lbls != "" {
+		lbls = "{" + lbls + "}"
+	}
+	fmt.Fprintf(sb, "%s_sum%s %s\n", name, lbls, formatFloat(h.sum))
+	fmt.Fprintf(sb, "%s_count%s %d\n", name, lbls, h.count)
+}
+
+func labels(kv ...string) string {
+	parts := make([]string, 0, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		parts = append(parts, fmt.Sprintf("%s=\"%s\"", kv[i], labelEscaper.Replace(kv[i+1])))
+	}
+	return strings.Join(parts, ",")
+}
+
+// 文本格式的标签值只转义反斜杠、双引号和换行, 其余字符(含非 ASCII)原样输出
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,365 @@
+package openai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+)
+
+// 思考内容处理策略
+type ThinkingPolicy int
+
+const (
+	ThinkingDrop      ThinkingPolicy = iota // 丢弃
+	ThinkingReasoning                       // 写入 reasoning_content
+)
+
+// 非文本工具类内容(MCP/命令/代码/QA)处理策略
+type ContentPolicy int
+
+const (
+	ContentDrop     ContentPolicy = iota // 丢弃
+	ContentText                          // 渲染为文本
+	ContentToolCall                      // 转换为 tool_calls 与 tool 消息
+)
+
+type options struct {
+	thinking    ThinkingPolicy
+	mcp         ContentPolicy
+	command     ContentPolicy
+	code        ContentPolicy
+	qa          ContentPolicy
+	fileURL     func(mimeType, fileID string) (string, error)
+	toolCallID  func(msg, block, content int) string
+	fallback    func(c acp.Content) (string, bool)
+	imageDetail string
+}
+
+type Option func(*options)
+
+func WithThinkingPolicy(p ThinkingPolicy) Option {
+	return func(o *options) { o.thinking = p }
+}
+
+func WithMCPPolicy(p ContentPolicy) Option {
+	return func(o *options) { o.mcp = p }
+}
+
+func WithCommandPolicy(p ContentPolicy) Option {
+	return func(o *options) { o.command = p }
+}
+
+func WithCodePolicy(p ContentPolicy) Option {
+	return func(o *options) { o.code = p }
+}
+
+func WithQAPolicy(p ContentPolicy) Option {
+	return func(o *options) { o.qa = p }
+}
+
+// WithFileURL 将图片类 FileContent/ArtifactContent 解析为可访问的 URL, 未设置时忽略文件
+func WithFileURL(fn func(mimeType, fileID string) (string, error)) Option {
+	return func(o *options) { o.fileURL = fn }
+}
+
+// WithToolCallID 自定义工具调用 ID, 默认 call_{消息}_{区块}_{内容}.
+// 工具调用内容记录了供应商的调用 ID 时优先使用记录的 ID
+func WithToolCallID(fn func(msg, block, content int) string) Option {
+	return func(o *options) { o.toolCallID = fn }
+}
+
+// WithFallback 处理未支持的内容类型, 返回 false 时丢弃
+func WithFallback(fn func(c acp.Content) (string, bool)) Option {
+	return func(o *options) { o.fallback = fn }
+}
+
+func WithImageDetail(detail string) Option {
+	return func(o *options) { o.imageDetail = detail }
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		thinking: ThinkingDrop,
+		mcp:      ContentToolCall,
+		command:  ContentToolCall,
+		code:     ContentToolCall,
+		qa:       ContentText,
+		toolCallID: func(msg, block, content int) string {
+			return fmt.Sprintf("call_%d_%d_%d", msg, block, content)
+		},
+	}
+	for _, fn := range opts {
+		fn(o)
+	}
+	return o
+}
+
+// ConvertMessages 将 ACP 消息历史转换为 Chat Completions 消息
+func ConvertMessages(msgs []acp.Message, opts ...Option) ([]ChatMessage, error) {
+	o := newOptions(opts)
+
+	out := make([]ChatMessage, 0, len(msgs))
+	for i := range msgs {
+		converted, err := o.convert(i, &msgs[i], i == len(msgs)-1)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, converted...)
+	}
+	return out, nil
+}
+
+// turn 聚合同一轮的助手输出, 遇到工具调用后的新文本时切分
+type turn struct {
+	role      string
+	parts     []ContentPart
+	reasoning strings.Builder
+	toolCalls []ToolCall
+	results   []ChatMessage
+	out       []ChatMessage
+	pending   int // 末尾连续的无结果调用数
+}
+
+func (t *turn) addPart(p ContentPart) {
+	if len(t.toolCalls) > 0 {
+		t.flush()
+	}
+	t.parts = append(t.parts, p)
+	t.pending = 0
+}
+
+func (t *turn) addReasoning(text string) {
+	if len(t.toolCalls) > 0 {
+		t.flush()
+	}
+	t.reasoning.WriteString(text)
+	t.pending = 0
+}
+
+// addToolCall 仅助手消息可携带工具调用, 其他角色渲染为文本.
+// 每个调用都生成 tool 消息, 无结果时内容为空, API 要求 tool_calls 都有对应的 tool 消息
+func (t *turn) addToolCall(id, name, args, result string) {
+	if t.role != RoleAssistant {
+		t.addPart(ContentPart{Type: PartTypeText, Text: toolText(name, args, result)})
+		return
+	}
+
+	t.toolCalls = append(t.toolCalls, ToolCall{
+		ID:   id,
+		Type: "function",
+		Function: FunctionCall{
+			Name:      name,
+			Arguments: args,
+		},
+	})
+	t.results = append(t.results, ChatMessage{
+		Role:       RoleTool,
+		Content:    result,
+		ToolCallID: id,
+	})
+	if result == "" {
+		t.pending++
+	} else {
+		t.pending = 0
+	}
+}
+
+func (t *turn) flush() {
+	if len(t.parts) == 0 && t.reasoning.Len() == 0 && len(t.toolCalls) == 0 {
+		return
+	}
+
+	msg := ChatMessage{
+		Role:             t.role,
+		Content:          partsContent(t.parts),
+		ReasoningContent: t.reasoning.String(),
+		ToolCalls:        t.toolCalls,
+	}
+	t.out = append(t.out, msg)
+	t.out = append(t.out, t.results...)
+
+	t.parts = nil
+	t.reasoning.Reset()
+	t.toolCalls = nil
+	t.results = nil
+}
+
+// partsContent 纯文本时合并为字符串, 含图片时保留 parts
+func partsContent(parts []ContentPart) any {
+	if len(parts) == 0 {
+		return nil
+	}
+
+	texts := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p.Type != PartTypeText {
+			return parts
+		}
+		texts = append(texts, p.Text)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// convert 转换单条消息. last 表示历史中的最后一条消息,
+// 其末尾尚无结果的调用视为仍在执行, 不生成 tool 消息, 由调用方在执行后补充
+func (o *options) convert(mi int, msg *acp.Message, last bool) ([]ChatMessage, error) {
+	role := msg.Role
+	switch role {
+	case acp.RoleUser, acp.RoleAssistant, acp.RoleSystem:
+	default:
+		return nil, fmt.Errorf("unsupported role: %s", role)
+	}
+
+	t := &turn{role: role}
+	for bi, b := range msg.Blocks {
+		for ci, c := range b.Contents {
+			id := o.toolCallID(mi, bi, ci)
+			if err := o.convertContent(t, id, c); err != nil {
+				return nil, err
+			}
+		}
+	}
+	t.flush()
+
+	if last {
+		return t.out[:len(t.out)-t.pending], nil
+	}
+	return t.out, nil
+}
+
+func (o *options) convertContent(t *turn, id string, c acp.Content) error {
+	switch c := c.(type) {
+	case *acp.TextContent:
+		t.addPart(ContentPart{Type: PartTypeText, Text: c.Text})
+
+	case *acp.ThinkingContent:
+		if o.thinking == ThinkingReasoning {
+			t.addReasoning(c.Text)
+		}
+
+	case *acp.ToolCallContent:
+		if c.ToolCallID != "" {
+			id = c.ToolCallID
+		}
+		t.addToolCall(id, c.ToolName, c.ToolArgs, toolResult(c.ToolResult, c.Error))
+
+	case *acp.FileContent:
+		return o.convertFile(t, c.MimeType, c.FileID)
+
+	case *acp.ArtifactContent:
+		return o.convertFile(t, c.MimeType, c.FileID)
+
+	case *acp.DataContent:
+		if isImage(c.MimeType) {
+			t.addPart(o.imagePart(fmt.Sprintf("data:%s;base64,%s", c.MimeType, c.Data)))
+		}
+
+	case *acp.MCPContent:
+		o.applyPolicy(t, o.mcp, id, c.Server+"__"+c.ToolName, c.ToolArgs, toolResult(c.ToolResult, c.Error))
+
+	case *acp.CommandContent:
+		args := marshalArgs(map[string]any{"command": c.Command})
+		result := c.Result
+		if c.ExitCode != nil {
+			result = fmt.Sprintf("%s\n[exit code: %d]", result, *c.ExitCode)
+		}
+		o.applyPolicy(t, o.command, id, acp.ContentTypeCommandExecution, args, toolResult(result, c.Error))
+
+	case *acp.CodeExecutionContent:
+		args := marshalArgs(map[string]any{"lang": c.Lang, "code": c.Code})
+		o.applyPolicy(t, o.code, id, acp.ContentTypeCodeExecution, args, toolResult(c.Result, c.Error))
+
+	case *acp.QAContent:
+		qargs := map[string]any{"message": c.Message}
+		if c.Options != nil {
+			qargs["options"] = c.Options
+		}
+		args := marshalArgs(qargs)
+		answer := ""
+		if c.Answer != nil {
+			answer = marshalArgs(c.Answer)
+		}
+		if o.qa == ContentText {
+			text := "Q: " + c.Message
+			if answer != "" {
+				text += "\nA: " + answer
+			}
+			t.addPart(ContentPart{Type: PartTypeText, Text: text})
+			return nil
+		}
+		name := c.QAName
+		if name == "" {
+			name = acp.ContentTypeQA
+		}
+		o.applyPolicy(t, o.qa, id, name, args, answer)
+
+	default:
+		if o.fallback != nil {
+			if text, ok := o.fallback(c); ok {
+				t.addPart(ContentPart{Type: PartTypeText, Text: text})
+			}
+		}
+	}
+	return nil
+}
+
+func (o *options) applyPolicy(t *turn, p ContentPolicy, id, name, args, result string) {
+	switch p {
+	case ContentToolCall:
+		t.addToolCall(id, name, args, result)
+	case ContentText:
+		t.addPart(ContentPart{Type: PartTypeText, Text: toolText(name, args, result)})
+	}
+}
+
+func toolText(name, args, result string) string {
+	text := fmt.Sprintf("[%s] %s", name, args)
+	if result != "" {
+		text += "\n" + result
+	}
+	return text
+}
+
+func (o *options) convertFile(t *turn, mimeType, fileID string) error {
+	if !isImage(mimeType) || o.fileURL == nil {
+		return nil
+	}
+
+	url, err := o.fileURL(mimeType, fileID)
+	if err != nil {
+		return err
+	}
+	t.addPart(o.imagePart(url))
+	return nil
+}
+
+func (o *options) imagePart(url string) ContentPart {
+	return ContentPart{
+		Type: PartTypeImageURL,
+		ImageURL: &ImageURL{
+			URL:    url,
+			Detail: o.imageDetail,
+		},
+	}
+}
+
+func toolResult(result string, e *acp.Error) string {
+	if e == nil {
+		return result
+	}
+	return marshalArgs(map[string]any{"error": e})
+}
+
+func marshalArgs(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func isImage(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "image/")
+}
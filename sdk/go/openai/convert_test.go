@@ -0,0 +1,149 @@
+package openai
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func loadHistory(t *testing.T) []acp.Message {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", "history.json"))
+	require.NoError(t, err)
+
+	var msgs []acp.Message
+	require.NoError(t, json.Unmarshal(data, &msgs))
+	return msgs
+}
+
+func assertGolden(t *testing.T, name string, v any) {
+	t.Helper()
+
+	got, err := json.MarshalIndent(v, "", "  ")
+	require.NoError(t, err)
+	got = append(got, '\n')
+
+	path := filepath.Join("testdata", name+".golden.json")
+	if *update {
+		require.NoError(t, os.WriteFile(path, got, 0o644))
+	}
+
+	want, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(want), string(got))
+}
+
+func TestConvertMessagesGolden(t *testing.T) {
+	fileURL := func(mimeType, fileID string) (string, error) {
+		return "https://files.example.com/" + fileID, nil
+	}
+	todoText := func(c acp.Content) (string, bool) {
+		todo, ok := c.(*acp.TodoListContent)
+		if !ok {
+			return "", false
+		}
+		return "todos: " + todo.Todos[0].Content, true
+	}
+
+	cases := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "default"},
+		{
+			name: "reasoning",
+			opts: []Option{
+				WithThinkingPolicy(ThinkingReasoning),
+				WithFileURL(fileURL),
+				WithFallback(todoText),
+			},
+		},
+		{
+			name: "text_tools",
+			opts: []Option{
+				WithMCPPolicy(ContentText),
+				WithCommandPolicy(ContentDrop),
+				WithCodePolicy(ContentText),
+				WithQAPolicy(ContentToolCall),
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := ConvertMessages(loadHistory(t), tc.opts...)
+			require.NoError(t, err)
+			assertGolden(t, "convert_"+tc.name, out)
+		})
+	}
+}
+
+func TestConvertMessagesToolCallPairs(t *testing.T) {
+	out, err := ConvertMessages(loadHistory(t))
+	require.NoError(t, err)
+
+	pending := map[string]bool{}
+	for _, m := range out {
+		for _, tc := range m.ToolCalls {
+			pending[tc.ID] = true
+		}
+		if m.Role == RoleTool {
+			assert.True(t, pending[m.ToolCallID], "tool message without call: %s", m.ToolCallID)
+			delete(pending, m.ToolCallID)
+		}
+	}
+	assert.Empty(t, pending)
+}
+
+func TestConvertMessagesUnsupportedRole(t *testing.T) {
+	_, err := ConvertMessages([]acp.Message{{Role: "robot"}})
+	assert.Error(t, err)
+}
+
+func TestConvertMessagesPendingToolCall(t *testing.T) {
+	call := acp.NewToolCallContent("get_weather")
+	call.ToolArgs = `{"city":"Paris"}`
+	msgs := []acp.Message{{
+		Role:   acp.RoleAssistant,
+		Blocks: []acp.Block{{Contents: []acp.Content{call}}},
+	}}
+
+	out, err := ConvertMessages(msgs)
+	require.NoError(t, err)
+	require.Len(t, out, 1)
+	require.Len(t, out[0].ToolCalls, 1)
+	assert.Equal(t, "get_weather", out[0].ToolCalls[0].Function.Name)
+}
+
+func TestConvertMessagesEmptyToolResult(t *testing.T) {
+	call := acp.NewToolCallContent("touch")
+	call.ToolArgs = `{"path":"/tmp/x"}`
+	pending := acp.NewToolCallContent("get_weather")
+	pending.ToolArgs = `{"city":"Paris"}`
+	msgs := []acp.Message{
+		{Role: acp.RoleAssistant, Blocks: []acp.Block{{Contents: []acp.Content{call, acp.NewTextContent("", "done")}}}},
+		{Role: acp.RoleUser, Blocks: []acp.Block{{Contents: []acp.Content{acp.NewTextContent("", "weather?")}}}},
+		{Role: acp.RoleAssistant, Blocks: []acp.Block{{Contents: []acp.Content{pending}}}},
+	}
+
+	out, err := ConvertMessages(msgs)
+	require.NoError(t, err)
+	require.Len(t, out, 5)
+
+	// 已结束但输出为空的调用仍有对应的 tool 消息
+	assert.Equal(t, "call_0_0_0", out[0].ToolCalls[0].ID)
+	assert.Equal(t, ChatMessage{Role: RoleTool, Content: "", ToolCallID: "call_0_0_0"}, out[1])
+	assert.Equal(t, "done", out[2].Content)
+	assert.Equal(t, RoleUser, out[3].Role)
+	// 末尾仍在执行的调用不生成 tool 消息
+	require.Len(t, out[4].ToolCalls, 1)
+}
@@ -0,0 +1,356 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/llm-infra/acp/sdk/go/acp"
+)
+
+var ErrStream = errors.New("openai stream error")
+
+// chat.completion.chunk, 流中出错时只有 Error
+type ChatCompletionChunk struct {
+	ID      string           `json:"id"`
+	Object  string           `json:"object"`
+	Created int64            `json:"created"`
+	Model   string           `json:"model"`
+	Choices []ChunkChoice    `json:"choices"`
+	Usage   *CompletionUsage `json:"usage,omitempty"`
+	Error   *StreamError     `json:"error,omitempty"`
+}
+
+type StreamError struct {
+	Message string `json:"message"`
+	Type    string `json:"type,omitempty"`
+	Code    any    `json:"code,omitempty"` // 字符串或数字
+}
+
+type ChunkChoice struct {
+	Index        int        `json:"index"`
+	Delta        ChunkDelta `json:"delta"`
+	FinishReason *string    `json:"finish_reason"`
+}
+
+type ChunkDelta struct {
+	Role             string     `json:"role,omitempty"`
+	Content          string     `json:"content,omitempty"`
+	ReasoningContent string     `json:"reasoning_content,omitempty"`
+	ToolCalls        []ToolCall `json:"tool_calls,omitempty"`
+}
+
+type CompletionUsage struct {
+	PromptTokens        int64 `json:"prompt_tokens"`
+	CompletionTokens    int64 `json:"completion_tokens"`
+	TotalTokens         int64 `json:"total_tokens"`
+	PromptTokensDetails *struct {
+		CachedTokens int64 `json:"cached_tokens"`
+	} `json:"prompt_tokens_details,omitempty"`
+	CompletionTokensDetails *struct {
+		ReasoningTokens int64 `json:"reasoning_tokens"`
+	} `json:"completion_tokens_details,omitempty"`
+}
+
+var doneMarker = []byte("[DONE]")
+
+// 将 OpenAI 流式 chunk 转换为 ACP 事件并写入 Creator, 每个流对应一个区块:
+//   - delta.content 转为文本内容, delta.reasoning_content 转为思考内容
+//   - delta.tool_calls 按 index 分别转为工具调用内容, arguments 片段转为参数增量
+//   - usage 在区块结束时写入 BlockEndEvent
+//   - error 记录为区块错误并结束区块, 返回包装 ErrStream 的错误
+//
+// 运行生命周期事件由调用方负责
+type StreamAdapter struct {
+	creator *acp.Creator
+	blockID string
+	opts    []acp.BlockOption
+
+	started    bool
+	closed     bool
+	startedAt  time.Time
+	model      string
+	usage      *acp.Usage
+	textID     string
+	thinkingID string
+	tools      map[int]string // tool_call index -> content_id
+}
+
+func NewStreamAdapter(creator *acp.Creator, blockID string, opts ...acp.BlockOption) *StreamAdapter {
+	return &StreamAdapter{
+		creator: creator,
+		blockID: blockID,
+		opts:    opts,
+		tools:   make(map[int]string),
+	}
+}
+
+// ReadStream 读取 SSE 格式的 chunk 流直至 [DONE] 或 EOF, 并结束区块
+func (a *StreamAdapter) ReadStream(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		data, ok := bytes.CutPrefix(line, []byte("data:"))
+		if !ok {
+			continue
+		}
+		data = bytes.TrimSpace(data)
+		if bytes.Equal(data, doneMarker) {
+			break
+		}
+		if err := a.AddChunk(data); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return a.Close()
+}
+
+// AddChunk 处理单个 chunk 的 JSON
+func (a *StreamAdapter) AddChunk(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), doneMarker) {
+		return a.Close()
+	}
+
+	var chunk ChatCompletionChunk
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return err
+	}
+	return a.Add(&chunk)
+}
+
+func (a *StreamAdapter) Add(chunk *ChatCompletionChunk) error {
+	if a.closed {
+		return errors.New("stream adapter already closed")
+	}
+	if chunk.Error != nil {
+		return a.fail(chunk.Error)
+	}
+	if err := a.start(); err != nil {
+		return err
+	}
+
+	if chunk.Model != "" {
+		a.model = chunk.Model
+	}
+	if chunk.Usage != nil {
+		a.usage = convertUsage(chunk.Usage)
+	}
+
+	for _, choice := range chunk.Choices {
+		// 仅处理首个候选
+		if choice.Index != 0 {
+			continue
+		}
+		if err := a.addDelta(&choice.Delta); err != nil {
+			return err
+		}
+		if choice.FinishReason != nil {
+			if err := a.endAll(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (a *StreamAdapter) addDelta(d *ChunkDelta) error {
+	if d.ReasoningContent != "" {
+		if err := a.endContent(&a.textID); err != nil {
+			return err
+		}
+		if err := a.delta(&a.thinkingID, acp.NewStreamThinkingContent(d.ReasoningContent)); err != nil {
+			return err
+		}
+	}
+
+	if d.Content != "" {
+		if err := a.endContent(&a.thinkingID); err != nil {
+			return err
+		}
+		if err := a.delta(&a.textID, acp.NewStreamTextContent(d.Content)); err != nil {
+			return err
+		}
+	}
+
+	for _, tc := range d.ToolCalls {
+		if err := a.addToolCall(&tc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *StreamAdapter) addToolCall(tc *ToolCall) error {
+	index := 0
+	if tc.Index != nil {
+		index = *tc.Index
+	}
+
+	id, ok := a.tools[index]
+	if !ok {
+		if err := a.endContent(&a.textID); err != nil {
+			return err
+		}
+		if err := a.endContent(&a.thinkingID); err != nil {
+			return err
+		}
+
+		id = uuid.NewString()
+		a.tools[index] = id
+		if err := a.creator.AddEvent(acp.NewContentStartEvent(id, a.blockID)); err != nil {
+			return err
+		}
+		sc := acp.NewStreamToolCallContent(tc.Function.Name)
+		sc.ToolCallID = tc.ID
+		if err := a.creator.AddEvent(acp.NewContentDeltaEvent(id, sc)); err != nil {
+			return err
+		}
+	}
+
+	if tc.Function.Arguments == "" {
+		return nil
+	}
+	return a.creator.AddEvent(acp.NewContentDeltaEvent(id, acp.NewStreamToolArgsContent(tc.Function.Arguments)))
+}
+
+// Close 结束所有未完成内容并结束区块
+func (a *StreamAdapter) Close() error {
+	if a.closed {
+		return nil
+	}
+	if err := a.start(); err != nil {
+		return err
+	}
+	if err := a.endAll(); err != nil {
+		return err
+	}
+
+	a.closed = true
+
+	usage := a.usage
+	if usage == nil {
+		usage = &acp.Usage{}
+	}
+	if usage.Model == "" {
+		usage.Model = a.model
+	}
+	if usage.LatencyMs == 0 {
+		usage.LatencyMs = time.Since(a.startedAt).Milliseconds()
+	}
+	return a.creator.AddEvent(acp.NewBlockEndEvent(a.blockID, usage))
+}
+
+// fail 记录区块错误, 结束未完成的内容和区块
+func (a *StreamAdapter) fail(e *StreamError) error {
+	re := streamError(e)
+	err := fmt.Errorf("%w: %w", ErrStream, re)
+
+	if startErr := a.start(); startErr != nil {
+		return errors.Join(err, startErr)
+	}
+	if addErr := a.creator.AddEvent(acp.NewBlockErrorEvent(a.blockID, re)); addErr != nil {
+		return errors.Join(err, addErr)
+	}
+	if closeErr := a.Close(); closeErr != nil {
+		return errors.Join(err, closeErr)
+	}
+	return err
+}
+
+// streamError 按错误类型或错误码映射, 限流与服务端错误可重试
+func streamError(e *StreamError) *acp.RunError {
+	re := &acp.RunError{Code: acp.ErrorCodeInternal, Message: e.Message, Details: map[string]any{"type": e.Type}}
+	if e.Code != nil {
+		re.Details["code"] = e.Code
+	}
+
+	code, _ := e.Code.(string)
+	switch {
+	case e.Type == "rate_limit_error" || code == "rate_limit_exceeded":
+		re.Code, re.Retryable = acp.ErrorCodeRateLimited, true
+	case e.Type == "server_error" || code == "server_error":
+		re.Code, re.Retryable = acp.ErrorCodeUnavailable, true
+	case e.Type == "invalid_request_error":
+		re.Code = acp.ErrorCodeInvalidInput
+	}
+	return re
+}
+
+func (a *StreamAdapter) start() error {
+	if a.started {
+		return nil
+	}
+
+	a.started = true
+	a.startedAt = time.Now()
+	return a.creator.AddEvent(acp.NewBlockStartEvent(a.blockID, a.opts...))
+}
+
+func (a *StreamAdapter) delta(id *string, sc acp.StreamContent) error {
+	if *id == "" {
+		*id = uuid.NewString()
+		if err := a.creator.AddEvent(acp.NewContentStartEvent(*id, a.blockID)); err != nil {
+			return err
+		}
+	}
+	return a.creator.AddEvent(acp.NewContentDeltaEvent(*id, sc))
+}
+
+func (a *StreamAdapter) endContent(id *string) error {
+	if *id == "" {
+		return nil
+	}
+
+	contentID := *id
+	*id = ""
+	return a.creator.AddEvent(acp.NewContentEndEvent(contentID))
+}
+
+func (a *StreamAdapter) endAll() error {
+	if err := a.endContent(&a.thinkingID); err != nil {
+		return err
+	}
+	if err := a.endContent(&a.textID); err != nil {
+		return err
+	}
+
+	indexes := make([]int, 0, len(a.tools))
+	for i := range a.tools {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	for _, i := range indexes {
+		id := a.tools[i]
+		delete(a.tools, i)
+		if err := a.creator.AddEvent(acp.NewContentEndEvent(id)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func convertUsage(u *CompletionUsage) *acp.Usage {
+	usage := &acp.Usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+	}
+	if u.PromptTokensDetails != nil {
+		usage.CachedPromptTokens = u.PromptTokensDetails.CachedTokens
+	}
+	if u.CompletionTokensDetails != nil {
+		usage.ReasoningTokens = u.CompletionTokensDetails.ReasoningTokens
+	}
+	return usage
+}
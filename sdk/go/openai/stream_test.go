@@ -0,0 +1,106 @@
+package openai
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func replay(t *testing.T, name string) *acp.Creator {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	defer f.Close()
+
+	creator := acp.NewCreator(nil)
+	require.NoError(t, NewStreamAdapter(creator, "b1").ReadStream(f))
+	return creator
+}
+
+func TestStreamAdapterTextAndReasoning(t *testing.T) {
+	creator := replay(t, "stream_text.sse")
+
+	require.Len(t, creator.Blocks, 1)
+	block := creator.Blocks[0]
+	require.Len(t, block.Contents, 2)
+
+	thinking, ok := block.Contents[0].(*acp.ThinkingContent)
+	require.True(t, ok)
+	assert.Equal(t, "The user greets me. Reply politely.", thinking.Text)
+
+	text, ok := block.Contents[1].(*acp.TextContent)
+	require.True(t, ok)
+	assert.Equal(t, "Hello! How can I help?", text.Text)
+
+	require.NotNil(t, block.Usage)
+	assert.Equal(t, int64(12), block.Usage.PromptTokens)
+	assert.Equal(t, int64(20), block.Usage.CompletionTokens)
+	assert.Equal(t, int64(4), block.Usage.CachedPromptTokens)
+	assert.Equal(t, int64(9), block.Usage.ReasoningTokens)
+	assert.Equal(t, "deepseek-reasoner", block.Usage.Model)
+}
+
+func TestStreamAdapterParallelToolCalls(t *testing.T) {
+	creator := replay(t, "stream_tools.sse")
+
+	require.Len(t, creator.Blocks, 1)
+	block := creator.Blocks[0]
+	require.Len(t, block.Contents, 3)
+
+	text, ok := block.Contents[0].(*acp.TextContent)
+	require.True(t, ok)
+	assert.Equal(t, "Checking both cities.", text.Text)
+
+	call0, ok := block.Contents[1].(*acp.ToolCallContent)
+	require.True(t, ok)
+	assert.Equal(t, "get_weather", call0.ToolName)
+	assert.Equal(t, "call_a", call0.ToolCallID)
+	assert.Equal(t, `{"city":"Paris"}`, call0.ToolArgs)
+
+	call1, ok := block.Contents[2].(*acp.ToolCallContent)
+	require.True(t, ok)
+	assert.Equal(t, "call_b", call1.ToolCallID)
+	assert.Equal(t, `{"city":"Tokyo"}`, call1.ToolArgs)
+
+	assert.Equal(t, int64(80), block.Usage.TotalTokens())
+	assert.Equal(t, "gpt-4o", block.Usage.Model)
+
+	// 转换回请求时沿用供应商的调用 ID
+	msgs, err := ConvertMessages([]acp.Message{*creator.Message})
+	require.NoError(t, err)
+	assert.Equal(t, "call_a", msgs[0].ToolCalls[0].ID)
+}
+
+func TestStreamAdapterError(t *testing.T) {
+	creator := acp.NewCreator(nil)
+	stream := strings.Join([]string{
+		`data: {"model":"gpt-4o","choices":[{"index":0,"delta":{"content":"Partial"}}]}`,
+		`data: {"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`,
+		`data: [DONE]`,
+	}, "\n\n")
+
+	err := NewStreamAdapter(creator, "b1").ReadStream(strings.NewReader(stream))
+	require.ErrorIs(t, err, ErrStream)
+	assert.Equal(t, acp.ErrorCodeRateLimited, acp.ToRunError(err).Code)
+
+	assert.Empty(t, creator.OpenContents())
+	assert.Empty(t, creator.OpenBlocks())
+	block := creator.Blocks[0]
+	assert.Equal(t, "Partial", block.Contents[0].(*acp.TextContent).Text)
+	require.Len(t, block.Errors, 1)
+	assert.Equal(t, acp.ErrorCodeRateLimited, block.Errors[0].Code)
+	assert.True(t, block.Errors[0].Retryable)
+	assert.Equal(t, "Rate limit reached", block.Errors[0].Message)
+}
+
+func TestStreamAdapterRejectsAfterClose(t *testing.T) {
+	adapter := NewStreamAdapter(acp.NewCreator(nil), "b1")
+	require.NoError(t, adapter.AddChunk([]byte("[DONE]")))
+	assert.Error(t, adapter.AddChunk([]byte(`{"choices":[]}`)))
+}
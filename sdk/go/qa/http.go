@@ -0,0 +1,64 @@
+package qa
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// 回答请求体的最大字节数
+const maxAnswerBytes = 1 << 20
+
+// HandleMessage 处理传输层收到的 JSON 回答消息
+func (c *Coordinator) HandleMessage(data []byte) error {
+	var a Answer
+	if err := json.Unmarshal(data, &a); err != nil {
+		return fmt.Errorf("invalid answer: %w", err)
+	}
+	if a.QAID == "" {
+		return errors.New("invalid answer: empty qa_id")
+	}
+	return c.Deliver(a.QAID, a.Answer)
+}
+
+// ServeHTTP 提供问答接口:
+//   - GET 返回等待回答的问题列表
+//   - POST 提交 Answer, 成功返回 204; 问题不存在 404, 已回答 409, 请求或回答校验失败 400
+//
+// 设置 WithAuthorizer 时只列出和接受该请求有权访问的问题.
+func (c *Coordinator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var allow func(Question) bool
+	if c.authorize != nil {
+		allow = func(q Question) bool { return c.authorize(r, q) }
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(c.pendingFor(allow))
+
+	case http.MethodPost:
+		var a Answer
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAnswerBytes)).Decode(&a); err != nil || a.QAID == "" {
+			http.Error(w, "invalid answer", http.StatusBadRequest)
+			return
+		}
+
+		err := c.deliver(a.QAID, a.Answer, allow)
+		switch {
+		case err == nil:
+			w.WriteHeader(http.StatusNoContent)
+		case errors.Is(err, ErrUnknownQuestion):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrAlreadyAnswered):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,415 @@
+// Package qa 协调 QA 内容的提问与回答, 支持阻塞等待、超时默认回答以及 HTTP 和传输层提交
+package qa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+)
+
+var (
+	ErrUnknownQuestion = errors.New("unknown question")
+	ErrDuplicate       = errors.New("duplicate question")
+	ErrAlreadyAnswered = errors.New("question already answered")
+	ErrTimeout         = errors.New("question timed out")
+	ErrCanceled        = errors.New("question canceled")
+)
+
+// 问题的结束方式
+type Outcome string
+
+const (
+	OutcomeAnswered Outcome = "answered"
+	OutcomeTimeout  Outcome = "timeout"
+	OutcomeCanceled Outcome = "canceled"
+)
+
+// 等待回答中的问题
+type Question struct {
+	QAID      string         `json:"qa_id"`
+	SessionID string         `json:"session_id,omitempty"`
+	RunID     string         `json:"run_id,omitempty"`
+	ContentID string         `json:"content_id"`
+	QAType    string         `json:"qa_type"`
+	QAName    string         `json:"qa_name"`
+	Message   string         `json:"message,omitempty"`
+	Options   map[string]any `json:"options,omitempty"`
+	AskedAt   int64          `json:"asked_at"`
+}
+
+// 一次提问的结果, 超时未回答时 Answer 为超时默认回答(可能为 nil)
+type Result struct {
+	QAID    string
+	Outcome Outcome
+	Answer  map[string]any
+}
+
+// 客户端提交的回答, 同时用作 HTTP 请求体和传输层消息
+type Answer struct {
+	QAID   string         `json:"qa_id"`
+	Answer map[string]any `json:"answer"`
+}
+
+type Option func(*Coordinator)
+
+// WithTimeout 设置等待回答的超时时间, 0 表示只受 ctx 控制
+func WithTimeout(d time.Duration) Option {
+	return func(c *Coordinator) { c.timeout = d }
+}
+
+// WithTimeoutAnswer 超时后写入内容的默认回答, 设置后超时不再返回错误
+func WithTimeoutAnswer(answer map[string]any) Option {
+	return func(c *Coordinator) { c.timeoutAnswer = answer }
+}
+
+// WithValidator 校验提交的回答, 返回错误时拒绝该回答, 问题仍保持等待
+func WithValidator(fn func(q Question, answer map[string]any) error) Option {
+	return func(c *Coordinator) { c.validate = fn }
+}
+
+// WithAuthorizer 限制 HTTP 请求可见和可回答的问题, 未授权的问题对该请求视为不存在.
+// 未设置时 ServeHTTP 暴露所有运行的问题, 须由外层完成鉴权.
+func WithAuthorizer(fn func(r *http.Request, q Question) bool) Option {
+	return func(c *Coordinator) { c.authorize = fn }
+}
+
+type delivery struct {
+	answer  map[string]any
+	emitted bool // qa_result 已由生产方写入
+}
+
+type pending struct {
+	question Question
+	creator  *acp.Creator
+	ch       chan delivery
+	answered bool
+}
+
+// 人机问答协调器, 跟踪已发出但未回答的问题.
+// Intercept 在 QA 内容发出时按 QAID 登记问题, 生产方通过 Wait 阻塞等待回答,
+// 回答通过 Deliver/HandleMessage 或 HTTP 接口提交后作为 qa_result 写回同一内容.
+// 可同时服务多个运行, 并发安全.
+type Coordinator struct {
+	timeout       time.Duration
+	timeoutAnswer map[string]any
+	validate      func(Question, map[string]any) error
+	authorize     func(*http.Request, Question) bool
+
+	mu        sync.Mutex
+	pending   map[string]*pending
+	byContent map[contentKey]string // 内容 -> QAID
+}
+
+// 同一 Coordinator 可服务多个运行, 内容 ID 只在所属 Creator 内唯一
+type contentKey struct {
+	creator   *acp.Creator
+	contentID string
+}
+
+func New(opts ...Option) *Coordinator {
+	c := &Coordinator{
+		pending:   make(map[string]*pending),
+		byContent: make(map[contentKey]string),
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// Intercept 登记 QA 内容中的问题, 并在生产方自行写入 qa_result 时结束等待.
+// 问题所在内容结束后不再等待回答.
+func (c *Coordinator) Intercept(ctx context.Context, e acp.Event, next acp.Handler) error {
+	m, _ := acp.CreatorFrom(ctx)
+	if end, ok := e.(acp.ContentEndEvent); ok {
+		if err := next(ctx, e); err != nil {
+			return err
+		}
+		c.endContent(m, end.ContentID)
+		return nil
+	}
+
+	evt, ok := e.(acp.ContentDeltaEvent)
+	if !ok {
+		return next(ctx, e)
+	}
+
+	switch sc := evt.Content.(type) {
+	case acp.StreamQAContent:
+		registered, err := c.register(m, evt.ContentID, sc, evt.Timestamp())
+		if err != nil {
+			return err
+		}
+		if err := next(ctx, e); err != nil {
+			if registered {
+				c.remove(sc.QAID)
+			}
+			return err
+		}
+		return nil
+
+	case acp.StreamQAResultContent:
+		if err := next(ctx, e); err != nil {
+			return err
+		}
+		c.resolveContent(m, evt.ContentID, sc.Answer)
+		return nil
+
+	default:
+		return next(ctx, e)
+	}
+}
+
+// register 返回 false 表示问题已由 Ask 登记
+func (c *Coordinator) register(m *acp.Creator, contentID string, sc acp.StreamQAContent, ts int64) (bool, error) {
+	if sc.QAID == "" {
+		return false, fmt.Errorf("%w: empty qa_id", acp.ErrContentEvent)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if p, ok := c.pending[sc.QAID]; ok {
+		if p.creator == m && p.question.ContentID == contentID {
+			return false, nil
+		}
+		return false, fmt.Errorf("%w: %s", ErrDuplicate, sc.QAID)
+	}
+
+	q := Question{
+		QAID:      sc.QAID,
+		ContentID: contentID,
+		QAType:    sc.QAType,
+		QAName:    sc.QAName,
+		Message:   sc.Message,
+		Options:   sc.Options,
+		AskedAt:   ts,
+	}
+	if m != nil {
+		q.SessionID, q.RunID = m.SessionID, m.RunID
+	}
+	c.pending[sc.QAID] = &pending{question: q, creator: m, ch: make(chan delivery, 1)}
+	c.byContent[contentKey{m, contentID}] = sc.QAID
+	return true, nil
+}
+
+func (c *Coordinator) remove(qaid string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.removeLocked(qaid)
+}
+
+func (c *Coordinator) removeLocked(qaid string) {
+	if p, ok := c.pending[qaid]; ok {
+		delete(c.byContent, contentKey{p.creator, p.question.ContentID})
+		delete(c.pending, qaid)
+	}
+}
+
+func (c *Coordinator) resolveContent(m *acp.Creator, contentID string, answer map[string]any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.pending[c.byContent[contentKey{m, contentID}]]
+	if ok && !p.answered {
+		p.answered = true
+		p.ch <- delivery{answer: answer, emitted: true}
+	}
+}
+
+func (c *Coordinator) endContent(m *acp.Creator, contentID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if qaid, ok := c.byContent[contentKey{m, contentID}]; ok {
+		c.removeLocked(qaid)
+	}
+}
+
+// Deliver 提交回答, 问题不存在或已结束时返回 ErrUnknownQuestion
+func (c *Coordinator) Deliver(qaid string, answer map[string]any) error {
+	return c.deliver(qaid, answer, nil)
+}
+
+// deliver 提交回答, allow 不为 nil 时未授权的问题视为不存在.
+// allow 和校验函数在锁外调用, 可以再调用 Coordinator 的方法
+func (c *Coordinator) deliver(qaid string, answer map[string]any, allow func(Question) bool) error {
+	c.mu.Lock()
+	p, ok := c.pending[qaid]
+	var q Question
+	answered := false
+	if ok {
+		q, answered = p.question, p.answered
+	}
+	c.mu.Unlock()
+
+	if !ok || (allow != nil && !allow(q)) {
+		return fmt.Errorf("%w: %s", ErrUnknownQuestion, qaid)
+	}
+	if answered {
+		return fmt.Errorf("%w: %s", ErrAlreadyAnswered, qaid)
+	}
+	if c.validate != nil {
+		if err := c.validate(q, answer); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 回调期间问题可能已结束或被回答
+	if c.pending[qaid] != p {
+		return fmt.Errorf("%w: %s", ErrUnknownQuestion, qaid)
+	}
+	if p.answered {
+		return fmt.Errorf("%w: %s", ErrAlreadyAnswered, qaid)
+	}
+	p.answered = true
+	p.ch <- delivery{answer: answer}
+	return nil
+}
+
+// Pending 返回等待回答的问题, 按提问时间排序
+func (c *Coordinator) Pending() []Question {
+	return c.pendingFor(nil)
+}
+
+// pendingFor 在锁外调用 allow
+func (c *Coordinator) pendingFor(allow func(Question) bool) []Question {
+	c.mu.Lock()
+	out := make([]Question, 0, len(c.pending))
+	for _, p := range c.pending {
+		if !p.answered {
+			out = append(out, p.question)
+		}
+	}
+	c.mu.Unlock()
+
+	if allow != nil {
+		out = slices.DeleteFunc(out, func(q Question) bool { return !allow(q) })
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].AskedAt != out[j].AskedAt {
+			return out[i].AskedAt < out[j].AskedAt
+		}
+		return out[i].QAID < out[j].QAID
+	})
+	return out
+}
+
+// Wait 阻塞直到问题被回答、超时或 ctx 结束, 须在写入 m 的协程中调用:
+//   - 回答: 以 qa_result 写入问题所在内容, 返回 OutcomeAnswered
+//   - 超时: 设置了 WithTimeoutAnswer 时写入默认回答并返回 OutcomeTimeout, 否则同时返回 ErrTimeout
+//   - ctx 取消: 返回 OutcomeCanceled 与 ErrCanceled
+//
+// ErrTimeout/ErrCanceled 同时包装 context 错误, acp.ToRunError 会映射为 timeout/canceled.
+// 无论结果如何问题都不再等待, 之后提交的回答返回 ErrUnknownQuestion.
+// 与超时或取消同时到达的回答仍按回答处理.
+func (c *Coordinator) Wait(ctx context.Context, m *acp.Creator, qaid string) (*Result, error) {
+	c.mu.Lock()
+	p, ok := c.pending[qaid]
+	c.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownQuestion, qaid)
+	}
+	defer c.remove(qaid)
+
+	var timeout <-chan time.Time
+	if c.timeout > 0 {
+		timer := time.NewTimer(c.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	res := &Result{QAID: qaid}
+	select {
+	case d := <-p.ch:
+		res.Outcome, res.Answer = OutcomeAnswered, d.answer
+		if d.emitted {
+			return res, nil
+		}
+		return res, c.emit(ctx, m, p.question.ContentID, d.answer)
+
+	case <-timeout:
+		return c.expire(ctx, m, p, res, context.DeadlineExceeded)
+
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return c.expire(ctx, m, p, res, ctx.Err())
+		}
+		if ok, err := c.drain(ctx, m, p, res); ok {
+			return res, err
+		}
+		res.Outcome = OutcomeCanceled
+		return res, fmt.Errorf("%w: %s: %w", ErrCanceled, qaid, ctx.Err())
+	}
+}
+
+func (c *Coordinator) expire(ctx context.Context, m *acp.Creator, p *pending, res *Result, cause error) (*Result, error) {
+	if ok, err := c.drain(ctx, m, p, res); ok {
+		return res, err
+	}
+
+	res.Outcome = OutcomeTimeout
+	if c.timeoutAnswer == nil {
+		return res, fmt.Errorf("%w: %s: %w", ErrTimeout, res.QAID, cause)
+	}
+	res.Answer = c.timeoutAnswer
+	return res, c.emit(ctx, m, p.question.ContentID, c.timeoutAnswer)
+}
+
+// drain 结束等待, 并取出与超时或取消同时到达的回答, 返回 false 表示没有回答
+func (c *Coordinator) drain(ctx context.Context, m *acp.Creator, p *pending, res *Result) (bool, error) {
+	c.mu.Lock()
+	p.answered = true
+	c.mu.Unlock()
+
+	select {
+	case d := <-p.ch:
+		res.Outcome, res.Answer = OutcomeAnswered, d.answer
+		if d.emitted {
+			return true, nil
+		}
+		return true, c.emit(ctx, m, p.question.ContentID, d.answer)
+	default:
+		return false, nil
+	}
+}
+
+// emit 写入回答, 不受 ctx 取消影响以保证事件流完整
+func (c *Coordinator) emit(ctx context.Context, m *acp.Creator, contentID string, answer map[string]any) error {
+	return m.AddEventContext(context.WithoutCancel(ctx),
+		acp.NewContentDeltaEvent(contentID, acp.NewStreamQAResultContent(answer)))
+}
+
+// Ask 在 blockID 下发出一个完整的 QA 内容并等待回答, 结束后关闭内容.
+// 返回值与 Wait 相同.
+func (c *Coordinator) Ask(ctx context.Context, m *acp.Creator, blockID, contentID string, q acp.StreamQAContent) (*Result, error) {
+	if _, err := c.register(m, contentID, q, time.Now().UnixMilli()); err != nil {
+		return nil, err
+	}
+
+	if err := m.AddEventContext(ctx, acp.NewContentStartEvent(contentID, blockID)); err != nil {
+		c.remove(q.QAID)
+		return nil, err
+	}
+	if err := m.AddEventContext(ctx, acp.NewContentDeltaEvent(contentID, q)); err != nil {
+		c.remove(q.QAID)
+		return nil, err
+	}
+
+	res, err := c.Wait(ctx, m, q.QAID)
+	if endErr := m.AddEventContext(context.WithoutCancel(ctx), acp.NewContentEndEvent(contentID)); endErr != nil {
+		return res, errors.Join(err, endErr)
+	}
+	return res, err
+}
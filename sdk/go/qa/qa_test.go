@@ -0,0 +1,340 @@
+package qa
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRun(t *testing.T, c *Coordinator) *acp.Creator {
+	t.Helper()
+	m := acp.NewCreator(nil, acp.WithInterceptors(c.Intercept))
+	require.NoError(t, m.AddEvent(acp.NewRunStartedEvent("s1", "r1")))
+	require.NoError(t, m.AddEvent(acp.NewBlockStartEvent("b1")))
+	return m
+}
+
+func question(qaid string) acp.StreamQAContent {
+	return acp.NewStreamQAContent(qaid, "select", "confirm", "continue?", map[string]any{"choices": []any{"yes", "no"}})
+}
+
+// answerWhenPending 等问题登记后提交回答
+func answerWhenPending(t *testing.T, c *Coordinator, qaid string, answer map[string]any) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		for range 1000 {
+			if len(c.Pending()) > 0 {
+				done <- c.Deliver(qaid, answer)
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		done <- errors.New("question never registered")
+	}()
+	return done
+}
+
+func qaContent(t *testing.T, m *acp.Creator, i int) *acp.QAContent {
+	t.Helper()
+	qa, ok := m.Blocks[0].Contents[i].(*acp.QAContent)
+	require.True(t, ok)
+	return qa
+}
+
+func TestAsk(t *testing.T) {
+	c := New()
+	m := newRun(t, c)
+
+	done := answerWhenPending(t, c, "q1", map[string]any{"choice": "yes"})
+	res, err := c.Ask(context.Background(), m, "b1", "c1", question("q1"))
+	require.NoError(t, err)
+	require.NoError(t, <-done)
+
+	assert.Equal(t, &Result{QAID: "q1", Outcome: OutcomeAnswered, Answer: map[string]any{"choice": "yes"}}, res)
+	assert.Equal(t, map[string]any{"choice": "yes"}, qaContent(t, m, 0).Answer)
+	assert.Empty(t, c.Pending())
+	assert.Empty(t, m.OpenContents())
+
+	assert.ErrorIs(t, c.Deliver("q1", nil), ErrUnknownQuestion)
+}
+
+func TestWaitWithInterceptor(t *testing.T) {
+	c := New()
+	m := newRun(t, c)
+
+	require.NoError(t, m.AddEvent(acp.NewContentStartEvent("c1", "b1")))
+	require.NoError(t, m.AddEvent(acp.NewContentDeltaEvent("c1", question("q1"))))
+
+	pending := c.Pending()
+	require.Len(t, pending, 1)
+	assert.Equal(t, "c1", pending[0].ContentID)
+	assert.Equal(t, "continue?", pending[0].Message)
+
+	// 同一 QAID 不可用于其他内容
+	require.NoError(t, m.AddEvent(acp.NewContentStartEvent("c2", "b1")))
+	assert.ErrorIs(t, m.AddEvent(acp.NewContentDeltaEvent("c2", question("q1"))), ErrDuplicate)
+
+	done := answerWhenPending(t, c, "q1", map[string]any{"choice": "no"})
+	res, err := c.Wait(context.Background(), m, "q1")
+	require.NoError(t, err)
+	require.NoError(t, <-done)
+	assert.Equal(t, OutcomeAnswered, res.Outcome)
+	require.NoError(t, m.AddEvent(acp.NewContentEndEvent("c1")))
+	assert.Equal(t, map[string]any{"choice": "no"}, qaContent(t, m, 0).Answer)
+
+	_, err = c.Wait(context.Background(), m, "q1")
+	assert.ErrorIs(t, err, ErrUnknownQuestion)
+}
+
+func TestProducerEmitsResult(t *testing.T) {
+	c := New()
+	m := newRun(t, c)
+
+	require.NoError(t, m.AddEvent(acp.NewContentStartEvent("c1", "b1")))
+	require.NoError(t, m.AddEvent(acp.NewContentDeltaEvent("c1", question("q1"))))
+	require.NoError(t, m.AddEvent(acp.NewContentDeltaEvent("c1", acp.NewStreamQAResultContent(map[string]any{"choice": "auto"}))))
+	assert.Empty(t, c.Pending())
+	assert.ErrorIs(t, c.Deliver("q1", nil), ErrAlreadyAnswered)
+
+	// Wait 不重复写入回答
+	res, err := c.Wait(context.Background(), m, "q1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"choice": "auto"}, res.Answer)
+	require.NoError(t, m.AddEvent(acp.NewContentEndEvent("c1")))
+	assert.Equal(t, map[string]any{"choice": "auto"}, qaContent(t, m, 0).Answer)
+}
+
+func TestTimeout(t *testing.T) {
+	c := New(WithTimeout(10 * time.Millisecond))
+	m := newRun(t, c)
+
+	res, err := c.Ask(context.Background(), m, "b1", "c1", question("q1"))
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, acp.ErrorCodeTimeout, acp.ToRunError(err).Code)
+	assert.Equal(t, OutcomeTimeout, res.Outcome)
+	assert.Nil(t, qaContent(t, m, 0).Answer)
+	assert.Empty(t, m.OpenContents())
+
+	// 超时后的回答被拒绝
+	assert.ErrorIs(t, c.Deliver("q1", map[string]any{"choice": "late"}), ErrUnknownQuestion)
+}
+
+func TestTimeoutAnswer(t *testing.T) {
+	c := New(WithTimeoutAnswer(map[string]any{"choice": "no"}))
+	m := newRun(t, c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	res, err := c.Ask(ctx, m, "b1", "c1", question("q1"))
+	require.NoError(t, err)
+	assert.Equal(t, OutcomeTimeout, res.Outcome)
+	assert.Equal(t, map[string]any{"choice": "no"}, qaContent(t, m, 0).Answer)
+}
+
+func TestCanceled(t *testing.T) {
+	c := New()
+	m := newRun(t, c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for len(c.Pending()) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+	res, err := c.Ask(ctx, m, "b1", "c1", question("q1"))
+	assert.ErrorIs(t, err, ErrCanceled)
+	assert.Equal(t, acp.ErrorCodeCanceled, acp.ToRunError(err).Code)
+	assert.Equal(t, OutcomeCanceled, res.Outcome)
+	assert.Empty(t, c.Pending())
+	// 取消后仍正常结束内容
+	assert.Empty(t, m.OpenContents())
+}
+
+func TestValidator(t *testing.T) {
+	c := New(WithValidator(func(q Question, answer map[string]any) error {
+		if answer["choice"] == nil {
+			return errors.New("choice required")
+		}
+		return nil
+	}))
+	m := newRun(t, c)
+	require.NoError(t, m.AddEvent(acp.NewContentStartEvent("c1", "b1")))
+	require.NoError(t, m.AddEvent(acp.NewContentDeltaEvent("c1", question("q1"))))
+
+	assert.EqualError(t, c.Deliver("q1", map[string]any{}), "choice required")
+	require.NoError(t, c.HandleMessage([]byte(`{"qa_id":"q1","answer":{"choice":"yes"}}`)))
+	assert.ErrorIs(t, c.Deliver("q1", map[string]any{"choice": "no"}), ErrAlreadyAnswered)
+
+	res, err := c.Wait(context.Background(), m, "q1")
+	require.NoError(t, err)
+	assert.Equal(t, "yes", res.Answer["choice"])
+
+	assert.Error(t, c.HandleMessage([]byte(`{"answer":{}}`)))
+	assert.Error(t, c.HandleMessage([]byte(`not json`)))
+}
+
+func TestCallbacksOutsideLock(t *testing.T) {
+	var c *Coordinator
+	c = New(
+		WithValidator(func(q Question, answer map[string]any) error {
+			// 回调中可以访问 Coordinator, 期间问题被其他请求回答
+			if len(c.Pending()) == 1 && answer["race"] != nil {
+				require.NoError(t, c.Deliver(q.QAID, map[string]any{"choice": "no"}))
+			}
+			return nil
+		}),
+		WithAuthorizer(func(r *http.Request, q Question) bool {
+			return len(c.Pending()) > 0
+		}),
+	)
+	m := newRun(t, c)
+	require.NoError(t, m.AddEvent(acp.NewContentStartEvent("c1", "b1")))
+	require.NoError(t, m.AddEvent(acp.NewContentDeltaEvent("c1", question("q1"))))
+
+	done := make(chan error, 1)
+	go func() {
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+		if !strings.Contains(rw.Body.String(), `"qa_id":"q1"`) {
+			done <- errors.New("question not listed")
+			return
+		}
+		done <- c.Deliver("q1", map[string]any{"choice": "yes", "race": true})
+	}()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, ErrAlreadyAnswered)
+	case <-time.After(time.Second):
+		t.Fatal("callback deadlocked")
+	}
+
+	res, err := c.Wait(context.Background(), m, "q1")
+	require.NoError(t, err)
+	assert.Equal(t, "no", res.Answer["choice"])
+}
+
+func TestServeHTTP(t *testing.T) {
+	c := New()
+	m := newRun(t, c)
+	require.NoError(t, m.AddEvent(acp.NewContentStartEvent("c1", "b1")))
+	require.NoError(t, m.AddEvent(acp.NewContentDeltaEvent("c1", question("q1"))))
+
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	var body strings.Builder
+	_, err = io.Copy(&body, resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body.String(), `"qa_id":"q1"`)
+
+	post := func(payload string) int {
+		resp, err := http.Post(srv.URL, "application/json", strings.NewReader(payload))
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusBadRequest, post(`{`))
+	assert.Equal(t, http.StatusNotFound, post(`{"qa_id":"missing","answer":{}}`))
+	assert.Equal(t, http.StatusNoContent, post(`{"qa_id":"q1","answer":{"choice":"yes"}}`))
+	assert.Equal(t, http.StatusConflict, post(`{"qa_id":"q1","answer":{"choice":"no"}}`))
+
+	req, err := http.NewRequest(http.MethodDelete, srv.URL, nil)
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	res, err := c.Wait(context.Background(), m, "q1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"choice": "yes"}, res.Answer)
+	require.NoError(t, m.AddEvent(acp.NewContentEndEvent("c1")))
+	assert.Equal(t, map[string]any{"choice": "yes"}, qaContent(t, m, 0).Answer)
+}
+
+func TestContentEndRemovesQuestion(t *testing.T) {
+	c := New()
+	m := newRun(t, c)
+	other := newRun(t, c)
+
+	// 不同运行可使用相同的内容 ID
+	require.NoError(t, m.AddEvent(acp.NewContentStartEvent("c1", "b1")))
+	require.NoError(t, m.AddEvent(acp.NewContentDeltaEvent("c1", question("q1"))))
+	require.NoError(t, other.AddEvent(acp.NewContentStartEvent("c1", "b1")))
+	require.NoError(t, other.AddEvent(acp.NewContentDeltaEvent("c1", question("q2"))))
+
+	require.NoError(t, other.AddEvent(acp.NewContentDeltaEvent("c1", acp.NewStreamQAResultContent(map[string]any{"choice": "auto"}))))
+	assert.ErrorIs(t, c.Deliver("q2", nil), ErrAlreadyAnswered)
+	require.Len(t, c.Pending(), 1)
+	assert.Equal(t, "q1", c.Pending()[0].QAID)
+
+	require.NoError(t, other.AddEvent(acp.NewContentEndEvent("c1")))
+	require.NoError(t, m.AddEvent(acp.NewContentEndEvent("c1")))
+	assert.Empty(t, c.Pending())
+	assert.Empty(t, c.pending)
+	assert.Empty(t, c.byContent)
+	assert.ErrorIs(t, c.Deliver("q1", nil), ErrUnknownQuestion)
+}
+
+func TestCanceledKeepsConcurrentAnswer(t *testing.T) {
+	c := New()
+	m := newRun(t, c)
+	require.NoError(t, m.AddEvent(acp.NewContentStartEvent("c1", "b1")))
+	require.NoError(t, m.AddEvent(acp.NewContentDeltaEvent("c1", question("q1"))))
+
+	// 取消前已提交的回答不会丢失
+	require.NoError(t, c.Deliver("q1", map[string]any{"choice": "yes"}))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, err := c.Wait(ctx, m, "q1")
+	require.NoError(t, err)
+	assert.Equal(t, OutcomeAnswered, res.Outcome)
+	require.NoError(t, m.AddEvent(acp.NewContentEndEvent("c1")))
+	assert.Equal(t, map[string]any{"choice": "yes"}, qaContent(t, m, 0).Answer)
+}
+
+func TestServeHTTPAuthorizer(t *testing.T) {
+	c := New(WithAuthorizer(func(r *http.Request, q Question) bool {
+		return r.Header.Get("X-Run-ID") == q.RunID
+	}))
+	m := acp.NewCreator(nil, acp.WithInterceptors(c.Intercept))
+	require.NoError(t, m.AddEvent(acp.NewRunStartedEvent("s1", "r1")))
+	require.NoError(t, m.AddEvent(acp.NewBlockStartEvent("b1")))
+	require.NoError(t, m.AddEvent(acp.NewContentStartEvent("c1", "b1")))
+	require.NoError(t, m.AddEvent(acp.NewContentDeltaEvent("c1", question("q1"))))
+	assert.Equal(t, "s1", c.Pending()[0].SessionID)
+	assert.Equal(t, "r1", c.Pending()[0].RunID)
+
+	do := func(method, runID, payload string) (int, string) {
+		req := httptest.NewRequest(method, "/", strings.NewReader(payload))
+		req.Header.Set("X-Run-ID", runID)
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, req)
+		return rw.Code, rw.Body.String()
+	}
+
+	_, body := do(http.MethodGet, "r2", "")
+	assert.JSONEq(t, `[]`, body)
+	_, body = do(http.MethodGet, "r1", "")
+	assert.Contains(t, body, `"qa_id":"q1"`)
+
+	code, _ := do(http.MethodPost, "r2", `{"qa_id":"q1","answer":{"choice":"yes"}}`)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = do(http.MethodPost, "r1", `{"qa_id":"q1","answer":{"choice":"yes"}}`)
+	assert.Equal(t, http.StatusNoContent, code)
+}
@@ -0,0 +1,402 @@
+// Package redact 检测并脱敏文本、事件流和消息中的个人信息与密钥
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+)
+
+// 匹配区间, 为原文中的字节偏移, 左闭右开
+type Match struct {
+	Start int
+	End   int
+}
+
+// 敏感信息检测器
+type Detector interface {
+	Name() string
+	Find(text string) []Match
+}
+
+// 可选: 报告尚未结束的敏感串起点, 流式脱敏时从该处起暂缓输出, 直至完整匹配或内容结束
+type PrefixDetector interface {
+	Prefixes(text string) []int
+}
+
+type regexDetector struct {
+	name   string
+	re     *regexp.Regexp
+	prefix *regexp.Regexp
+}
+
+// Regex 以正则表达式构造检测器, 有捕获分组时只脱敏第一个分组
+func Regex(name string, re *regexp.Regexp) Detector {
+	return &regexDetector{name: name, re: re}
+}
+
+// RegexWithPrefix 同 Regex, prefix 匹配可能超过暂缓长度的敏感串开头(如私钥头)
+func RegexWithPrefix(name string, re, prefix *regexp.Regexp) Detector {
+	return &regexDetector{name: name, re: re, prefix: prefix}
+}
+
+func (d *regexDetector) Name() string { return d.name }
+
+func (d *regexDetector) Prefixes(text string) []int {
+	if d.prefix == nil {
+		return nil
+	}
+	var starts []int
+	for _, loc := range d.prefix.FindAllStringIndex(text, -1) {
+		starts = append(starts, loc[0])
+	}
+	return starts
+}
+
+func (d *regexDetector) Find(text string) []Match {
+	var matches []Match
+	for _, loc := range d.re.FindAllStringSubmatchIndex(text, -1) {
+		if len(loc) >= 4 && loc[2] >= 0 {
+			matches = append(matches, Match{Start: loc[2], End: loc[3]})
+		} else {
+			matches = append(matches, Match{Start: loc[0], End: loc[1]})
+		}
+	}
+	return matches
+}
+
+// Builtin 返回内置检测器: 邮箱、电话号码和常见服务的密钥
+func Builtin() []Detector {
+	return []Detector{
+		RegexWithPrefix("private_key",
+			regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+			regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)),
+		RegexWithPrefix("jwt",
+			regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`),
+			regexp.MustCompile(`\beyJ[A-Za-z0-9_.-]*$`)),
+		Regex("aws_access_key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)),
+		Regex("github_token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{40,})`)),
+		Regex("slack_token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)),
+		Regex("api_key", regexp.MustCompile(`\bsk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}`)),
+		Regex("google_api_key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`)),
+		Regex("bearer_token", regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9._~+/=-]{20,})`)),
+		Regex("email", regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)),
+		Regex("phone", regexp.MustCompile(`(?:\+\d{1,3}[ -]?)?(?:\b1[3-9]\d{9}\b|\(?\b\d{3}\)?[ .-]\d{3}[ .-]\d{4}\b)`)),
+	}
+}
+
+// 一次脱敏记录, 偏移为原文中的字节位置
+type Finding struct {
+	Detector  string `json:"detector"`
+	ContentID string `json:"content_id,omitempty"`
+	Field     string `json:"field,omitempty"`
+	Start     int    `json:"start"`
+	End       int    `json:"end"`
+}
+
+type Redactor struct {
+	builtin   bool
+	detectors []Detector
+	replace   func(detector, match string) string
+	holdback  int
+}
+
+type Option func(*Redactor)
+
+// WithDetectors 追加检测器
+func WithDetectors(detectors ...Detector) Option {
+	return func(r *Redactor) { r.detectors = append(r.detectors, detectors...) }
+}
+
+// WithoutBuiltin 不使用内置检测器, 需配合 WithDetectors
+func WithoutBuiltin() Option {
+	return func(r *Redactor) { r.builtin = false }
+}
+
+// WithReplacement 自定义替换文本, 默认 [REDACTED:<detector>]
+func WithReplacement(fn func(detector, match string) string) Option {
+	return func(r *Redactor) { r.replace = fn }
+}
+
+// WithHoldback 流式脱敏时暂缓输出的尾部字节数, 应不小于最长敏感串, 默认 256
+func WithHoldback(n int) Option {
+	return func(r *Redactor) { r.holdback = max(n, 0) }
+}
+
+func New(opts ...Option) *Redactor {
+	r := &Redactor{
+		builtin:  true,
+		replace:  func(detector, _ string) string { return fmt.Sprintf("[REDACTED:%s]", detector) },
+		holdback: 256,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.builtin {
+		r.detectors = append(Builtin(), r.detectors...)
+	}
+	return r
+}
+
+type namedMatch struct {
+	Match
+	detector string
+}
+
+// find 返回按起点排序且互不重叠的匹配, 重叠时保留先出现且更长的
+func (r *Redactor) find(text string) []namedMatch {
+	var all []namedMatch
+	for _, d := range r.detectors {
+		for _, m := range d.Find(text) {
+			if m.End > m.Start {
+				all = append(all, namedMatch{Match: m, detector: d.Name()})
+			}
+		}
+	}
+	slices.SortStableFunc(all, func(a, b namedMatch) int {
+		if a.Start != b.Start {
+			return a.Start - b.Start
+		}
+		return b.End - a.End
+	})
+
+	merged := all[:0]
+	for _, m := range all {
+		if n := len(merged); n > 0 && m.Start < merged[n-1].End {
+			merged[n-1].End = max(merged[n-1].End, m.End)
+			continue
+		}
+		merged = append(merged, m)
+	}
+	return merged
+}
+
+// openPrefix 返回未被完整匹配覆盖的敏感串前缀中最靠前的起点
+func (r *Redactor) openPrefix(text string, matches []namedMatch) (int, bool) {
+	start, ok := len(text), false
+	for _, d := range r.detectors {
+		pd, isPrefix := d.(PrefixDetector)
+		if !isPrefix {
+			continue
+		}
+		for _, p := range pd.Prefixes(text) {
+			covered := slices.ContainsFunc(matches, func(m namedMatch) bool { return m.Start <= p && p < m.End })
+			if !covered && p < start {
+				start, ok = p, true
+			}
+		}
+	}
+	return start, ok
+}
+
+// Redact 脱敏文本, 返回结果和原文中的匹配位置
+func (r *Redactor) Redact(text string) (string, []Finding) {
+	matches := r.find(text)
+	if len(matches) == 0 {
+		return text, nil
+	}
+	return r.apply(text, matches, 0)
+}
+
+// apply 替换匹配区间, 记录的偏移加上 offset
+func (r *Redactor) apply(text string, matches []namedMatch, offset int) (string, []Finding) {
+	out := make([]byte, 0, len(text))
+	findings := make([]Finding, 0, len(matches))
+	last := 0
+	for _, m := range matches {
+		out = append(out, text[last:m.Start]...)
+		out = append(out, r.replace(m.detector, text[m.Start:m.End])...)
+		findings = append(findings, Finding{Detector: m.detector, Start: offset + m.Start, End: offset + m.End})
+		last = m.End
+	}
+	out = append(out, text[last:]...)
+	return string(out), findings
+}
+
+// 记录脱敏位置的区块元数据键
+const MetadataKey = "redactions"
+
+// RedactMessage 原地脱敏消息中各内容的文本字段(含 QA、变量、搜索结果、待办与错误详情中的字符串),
+// 并把脱敏位置记录到所在区块的元数据. 文件 ID、数据内容等二进制或标识类字段不处理.
+func (r *Redactor) RedactMessage(msg *acp.Message) []Finding {
+	var all []Finding
+	for i := range msg.Blocks {
+		b := &msg.Blocks[i]
+		var findings []Finding
+		for j, c := range b.Contents {
+			for _, fd := range fields(c) {
+				redacted, fs := r.Redact(*fd.text)
+				fd.write(redacted)
+				for _, f := range fs {
+					f.ContentID = fmt.Sprintf("%s/%d", b.ID, j)
+					f.Field = fd.name
+					findings = append(findings, f)
+				}
+			}
+		}
+		if len(findings) > 0 {
+			if b.Metadata == nil {
+				b.Metadata = make(map[string]any)
+			}
+			b.Metadata[MetadataKey] = appendFindings(b.Metadata[MetadataKey], findings)
+			all = append(all, findings...)
+		}
+	}
+
+	for i := range msg.Blocks {
+		for j := range msg.Blocks[i].Errors {
+			r.redactRunError(&msg.Blocks[i].Errors[j])
+		}
+	}
+	if msg.Errors != "" {
+		msg.Errors, _ = r.Redact(msg.Errors)
+	}
+	r.redactRunError(msg.RunError)
+	return all
+}
+
+// redactRunError 脱敏结构化错误及其原因链的消息文本与详情
+func (r *Redactor) redactRunError(e *acp.RunError) {
+	for ; e != nil; e = e.Cause {
+		e.Message, _ = r.Redact(e.Message)
+		for _, fd := range valueFields("details", e.Details) {
+			redacted, _ := r.Redact(*fd.text)
+			fd.write(redacted)
+		}
+	}
+}
+
+// appendFindings 追加到已有记录之后, 从 JSON 解码的消息中已有记录为 []any
+func appendFindings(existing any, findings []Finding) []Finding {
+	var prev []Finding
+	switch v := existing.(type) {
+	case nil:
+	case []Finding:
+		prev = v
+	default:
+		if data, err := json.Marshal(v); err == nil {
+			_ = json.Unmarshal(data, &prev)
+		}
+	}
+	return append(prev, findings...)
+}
+
+type field struct {
+	name string
+	text *string
+	set  func(string) // map 与切片元素不可寻址, 通过 set 写回
+}
+
+func (f field) write(s string) {
+	if f.set != nil {
+		f.set(s)
+		return
+	}
+	*f.text = s
+}
+
+// fields 返回内容中需要脱敏的文本字段
+func fields(c acp.Content) []field {
+	switch c := c.(type) {
+	case *acp.TextContent:
+		return []field{{name: acp.ContentTypeText, text: &c.Text}}
+	case *acp.ThinkingContent:
+		return []field{{name: acp.ContentTypeThinking, text: &c.Text}}
+	case *acp.ToolCallContent:
+		return withApproval(c.Approval, withError(c.Error, field{name: acp.ContentTypeToolArgs, text: &c.ToolArgs}, field{name: acp.ContentTypeToolResult, text: &c.ToolResult}))
+	case *acp.MCPContent:
+		return withApproval(c.Approval, withError(c.Error, field{name: acp.ContentTypeMcpArgs, text: &c.ToolArgs}, field{name: acp.ContentTypeMcpResult, text: &c.ToolResult}))
+	case *acp.CommandContent:
+		return withApproval(c.Approval, withError(c.Error, field{name: acp.ContentTypeCommandExecution, text: &c.Command}, field{name: acp.ContentTypeCommandExecutionResult, text: &c.Result}))
+	case *acp.CodeExecutionContent:
+		return withError(c.Error, field{name: acp.ContentTypeCodeExecution, text: &c.Code}, field{name: acp.ContentTypeCodeExecutionResult, text: &c.Result})
+	case *acp.QAContent:
+		fs := append([]field{{name: acp.ContentTypeQA, text: &c.Message}}, valueFields(acp.ContentTypeQA, c.Options)...)
+		return append(fs, valueFields(acp.ContentTypeQAResult, c.Answer)...)
+	case *acp.VariableContent:
+		return valueFields(acp.ContentTypeVariable, c.Variables)
+	case *acp.WebSearchContent:
+		fs := []field{{name: acp.ContentTypeWebSearch, text: &c.Query}, {name: acp.ContentTypeWebSearchResult, text: &c.Answer}}
+		for i := range c.Results {
+			res := &c.Results[i]
+			fs = append(fs,
+				field{name: acp.ContentTypeWebSearchResult, text: &res.Title},
+				field{name: acp.ContentTypeWebSearchResult, text: &res.Url},
+				field{name: acp.ContentTypeWebSearchResult, text: &res.Snippet})
+		}
+		return withError(c.Error, fs...)
+	case *acp.TodoListContent:
+		fs := make([]field, 0, len(c.Todos))
+		for i := range c.Todos {
+			fs = append(fs, field{name: acp.ContentTypeTodoList, text: &c.Todos[i].Content})
+		}
+		return fs
+	default:
+		return nil
+	}
+}
+
+func withError(e *acp.Error, fs ...field) []field {
+	if e != nil {
+		fs = append(fs, field{name: "error", text: &e.Message})
+	}
+	return fs
+}
+
+func withApproval(a *acp.Approval, fs []field) []field {
+	if a != nil {
+		fs = append(fs,
+			field{name: acp.ContentTypeApproval, text: &a.Args},
+			field{name: acp.ContentTypeApproval, text: &a.OriginalArgs},
+			field{name: acp.ContentTypeApproval, text: &a.Reason})
+	}
+	return fs
+}
+
+// valueFields 返回 JSON 值(map/切片)中的字符串, 字段名为 name 加键路径, 按键排序
+func valueFields(name string, v any) []field {
+	var fs []field
+	switch v := v.(type) {
+	case map[string]any:
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			if str, ok := v[k].(string); ok {
+				fs = append(fs, field{name: name + "." + k, text: &str, set: func(s string) { v[k] = s }})
+				continue
+			}
+			fs = append(fs, valueFields(name+"."+k, v[k])...)
+		}
+	case []any:
+		for i := range v {
+			if str, ok := v[i].(string); ok {
+				fs = append(fs, field{name: fmt.Sprintf("%s.%d", name, i), text: &str, set: func(s string) { v[i] = s }})
+				continue
+			}
+			fs = append(fs, valueFields(fmt.Sprintf("%s.%d", name, i), v[i])...)
+		}
+	}
+	return fs
+}
+
+// cloneValue 深拷贝 JSON 值中的 map 与切片, 流式脱敏时不修改调用方的数据
+func cloneValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[k] = cloneValue(e)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = cloneValue(e)
+		}
+		return out
+	default:
+		return v
+	}
+}
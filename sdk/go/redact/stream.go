@@ -0,0 +1,383 @@
+package redact
+
+import (
+	"context"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+)
+
+// 单个字段的流式缓冲
+type buffer struct {
+	field    string
+	template acp.StreamContent
+	pending  string
+	offset   int // pending 在原文中的起始偏移
+}
+
+// Stream 对事件流做增量脱敏, 每条流一个实例.
+// 文本类增量的尾部会暂缓输出, 以识别跨增量边界的敏感串, 内容结束时全部输出;
+// 私钥等较长的敏感串从开头起暂缓, 直至完整出现.
+// 作为拦截器使用时, 会在内容结束后把脱敏位置写入所在区块的元数据.
+type Stream struct {
+	r        *Redactor
+	mux      sync.Mutex
+	buffers  map[string]*buffer        // content_id -> 当前字段缓冲
+	offsets  map[string]map[string]int // content_id -> field -> 已处理的原文长度
+	blocks   map[string]string         // content_id -> block_id
+	findings map[string][]Finding      // content_id -> 待写入元数据的记录
+	audit    []Finding
+}
+
+func NewStream(r *Redactor) *Stream {
+	return &Stream{
+		r:        r,
+		buffers:  make(map[string]*buffer),
+		offsets:  make(map[string]map[string]int),
+		blocks:   make(map[string]string),
+		findings: make(map[string][]Finding),
+	}
+}
+
+// Intercept 实现 acp.Interceptor
+func (s *Stream) Intercept(ctx context.Context, e acp.Event, next acp.Handler) error {
+	for _, out := range s.Apply(e) {
+		if err := next(ctx, out); err != nil {
+			return err
+		}
+	}
+	if evt, ok := e.(acp.ContentEndEvent); ok {
+		if m, ok := acp.CreatorFrom(ctx); ok {
+			s.recordMetadata(m, evt.ContentID)
+		}
+	}
+	return nil
+}
+
+// Apply 返回脱敏后应输出的事件
+func (s *Stream) Apply(e acp.Event) []acp.Event {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	switch evt := e.(type) {
+	case acp.ContentStartEvent:
+		s.blocks[evt.ContentID] = evt.RelatedBlockID
+		return []acp.Event{e}
+
+	case acp.ContentDeltaEvent:
+		return s.delta(evt)
+
+	case acp.ContentEndEvent:
+		out := s.flush(evt.ContentID, evt.TimestampMs)
+		delete(s.offsets, evt.ContentID)
+		return append(out, e)
+
+	default:
+		return []acp.Event{e}
+	}
+}
+
+func (s *Stream) delta(evt acp.ContentDeltaEvent) []acp.Event {
+	text, field, ok := streamText(evt.Content)
+	if !ok {
+		// 非流式字段整体脱敏, 先输出之前暂缓的内容保证顺序
+		out := s.flush(evt.ContentID, evt.TimestampMs)
+		evt.Content = s.whole(evt.ContentID, evt.Content)
+		return append(out, evt)
+	}
+
+	var out []acp.Event
+	b := s.buffers[evt.ContentID]
+	if b != nil && b.field != field {
+		out = s.flush(evt.ContentID, evt.TimestampMs)
+		b = nil
+	}
+	if b == nil {
+		b = &buffer{field: field, offset: s.offset(evt.ContentID, field)}
+		s.buffers[evt.ContentID] = b
+	}
+	b.template = evt.Content
+	b.pending += text
+
+	// 签名标志思考内容结束, 立即输出全部暂缓内容, 且保证签名被发送
+	if thinking, ok := evt.Content.(acp.StreamThinkingContent); ok && thinking.Signature != "" {
+		flushed := s.flush(evt.ContentID, evt.TimestampMs)
+		if len(flushed) == 0 {
+			thinking.Delta = ""
+			evt.Content = thinking
+			flushed = []acp.Event{evt}
+		}
+		return append(out, flushed...)
+	}
+
+	matches := s.r.find(b.pending)
+	cut := max(len(b.pending)-s.r.holdback, 0)
+	for _, m := range matches {
+		// 触及暂缓区的匹配可能随后续增量变长
+		if m.End > cut {
+			cut = min(cut, m.Start)
+			break
+		}
+	}
+	// 未结束的长敏感串(如私钥)可能超过暂缓长度, 从其开头起全部暂缓
+	if start, ok := s.r.openPrefix(b.pending, matches); ok {
+		cut = min(cut, start)
+	}
+	for cut > 0 && cut < len(b.pending) && !utf8.RuneStart(b.pending[cut]) {
+		cut--
+	}
+	if cut == 0 {
+		return out
+	}
+
+	emitted := matches[:0]
+	for _, m := range matches {
+		if m.End <= cut {
+			emitted = append(emitted, m)
+		}
+	}
+	if e, ok := s.emit(evt.ContentID, b, b.pending[:cut], emitted, evt.TimestampMs); ok {
+		out = append(out, e)
+	}
+	b.pending = b.pending[cut:]
+	b.offset += cut
+	return out
+}
+
+// flush 输出内容当前字段暂缓的全部文本
+func (s *Stream) flush(contentID string, ts int64) []acp.Event {
+	b, ok := s.buffers[contentID]
+	if !ok {
+		return nil
+	}
+	delete(s.buffers, contentID)
+
+	offsets := s.offsets[contentID]
+	if offsets == nil {
+		offsets = make(map[string]int)
+		s.offsets[contentID] = offsets
+	}
+	offsets[b.field] = b.offset + len(b.pending)
+
+	if b.pending == "" {
+		return nil
+	}
+	e, ok := s.emit(contentID, b, b.pending, s.r.find(b.pending), ts)
+	if !ok {
+		return nil
+	}
+	return []acp.Event{e}
+}
+
+func (s *Stream) emit(contentID string, b *buffer, text string, matches []namedMatch, ts int64) (acp.Event, bool) {
+	redacted, findings := s.r.apply(text, matches, b.offset)
+	s.record(contentID, b.field, findings)
+	if redacted == "" {
+		return nil, false
+	}
+
+	e := acp.NewContentDeltaEvent(contentID, withText(b.template, redacted))
+	e.TimestampMs = ts
+	return e, true
+}
+
+// whole 脱敏不能拆分的增量, 如命令和带错误的结果(结果文本与错误信息一并脱敏)
+func (s *Stream) whole(contentID string, sc acp.StreamContent) acp.StreamContent {
+	redactError := func(e *acp.Error) *acp.Error {
+		if e == nil {
+			return nil
+		}
+		msg, findings := s.r.Redact(e.Message)
+		s.record(contentID, "error", findings)
+		return &acp.Error{Type: e.Type, Message: msg}
+	}
+
+	switch c := sc.(type) {
+	case acp.StreamCommandContent:
+		var findings []Finding
+		c.Command, findings = s.r.Redact(c.Command)
+		s.record(contentID, acp.ContentTypeCommandExecution, findings)
+		return c
+	case acp.StreamToolResultContent:
+		c.Delta = s.redactText(contentID, acp.ContentTypeToolResult, c.Delta)
+		c.Error = redactError(c.Error)
+		return c
+	case acp.StreamMCPResultContent:
+		c.Delta = s.redactText(contentID, acp.ContentTypeMcpResult, c.Delta)
+		c.Error = redactError(c.Error)
+		return c
+	case acp.StreamCommandResultContent:
+		c.Delta = s.redactText(contentID, acp.ContentTypeCommandExecutionResult, c.Delta)
+		c.Error = redactError(c.Error)
+		return c
+	case acp.StreamCodeExecutionResultContent:
+		c.Delta = s.redactText(contentID, acp.ContentTypeCodeExecutionResult, c.Delta)
+		c.Error = redactError(c.Error)
+		return c
+	case acp.StreamApprovalContent:
+		c.Args = s.redactText(contentID, acp.ContentTypeApproval, c.Args)
+		c.OriginalArgs = s.redactText(contentID, acp.ContentTypeApproval, c.OriginalArgs)
+		c.Reason = s.redactText(contentID, acp.ContentTypeApproval, c.Reason)
+		return c
+	case acp.StreamQAContent:
+		c.Message = s.redactText(contentID, acp.ContentTypeQA, c.Message)
+		c.Options = s.redactMap(contentID, acp.ContentTypeQA, c.Options)
+		return c
+	case acp.StreamQAResultContent:
+		c.Answer = s.redactMap(contentID, acp.ContentTypeQAResult, c.Answer)
+		return c
+	case acp.StreamVariableContent:
+		c.Delta = s.redactMap(contentID, acp.ContentTypeVariable, c.Delta)
+		return c
+	case acp.StreamWebSearchContent:
+		c.Delta = s.redactText(contentID, acp.ContentTypeWebSearch, c.Delta)
+		return c
+	case acp.StreamWebSearchResultContent:
+		c.Answer = s.redactText(contentID, acp.ContentTypeWebSearchResult, c.Answer)
+		results := make([]acp.WebSearchResult, len(c.Results))
+		for i, res := range c.Results {
+			res.Title = s.redactText(contentID, acp.ContentTypeWebSearchResult, res.Title)
+			res.Url = s.redactText(contentID, acp.ContentTypeWebSearchResult, res.Url)
+			res.Snippet = s.redactText(contentID, acp.ContentTypeWebSearchResult, res.Snippet)
+			results[i] = res
+		}
+		c.Results = results
+		c.Error = redactError(c.Error)
+		return c
+	case acp.StreamTodoListContent:
+		todos := make([]acp.TodoItem, len(c.Todos))
+		for i, todo := range c.Todos {
+			todo.Content = s.redactText(contentID, acp.ContentTypeTodoList, todo.Content)
+			todos[i] = todo
+		}
+		c.Todos = todos
+		return c
+	default:
+		return sc
+	}
+}
+
+func (s *Stream) redactText(contentID, field, text string) string {
+	redacted, findings := s.r.Redact(text)
+	s.record(contentID, field, findings)
+	return redacted
+}
+
+// redactMap 脱敏 map 的副本
+func (s *Stream) redactMap(contentID, field string, m map[string]any) map[string]any {
+	if m == nil {
+		return nil
+	}
+	out := cloneValue(m).(map[string]any)
+	for _, fd := range valueFields(field, out) {
+		fd.write(s.redactText(contentID, fd.name, *fd.text))
+	}
+	return out
+}
+
+func (s *Stream) offset(contentID, field string) int {
+	return s.offsets[contentID][field]
+}
+
+func (s *Stream) record(contentID, field string, findings []Finding) {
+	for _, f := range findings {
+		f.ContentID = contentID
+		f.Field = field
+		s.findings[contentID] = append(s.findings[contentID], f)
+		s.audit = append(s.audit, f)
+	}
+}
+
+// Findings 返回迄今为止的全部脱敏记录
+func (s *Stream) Findings() []Finding {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	out := make([]Finding, len(s.audit))
+	copy(out, s.audit)
+	return out
+}
+
+// recordMetadata 内容结束后把脱敏位置写入区块元数据
+func (s *Stream) recordMetadata(m *acp.Creator, contentID string) {
+	s.mux.Lock()
+	findings := s.findings[contentID]
+	blockID := s.blocks[contentID]
+	delete(s.findings, contentID)
+	delete(s.blocks, contentID)
+	s.mux.Unlock()
+
+	if len(findings) == 0 {
+		return
+	}
+	for i := len(m.Blocks) - 1; i >= 0; i-- {
+		if m.Blocks[i].ID == blockID {
+			if m.Blocks[i].Metadata == nil {
+				m.Blocks[i].Metadata = make(map[string]any)
+			}
+			m.Blocks[i].Metadata[MetadataKey] = appendFindings(m.Blocks[i].Metadata[MetadataKey], findings)
+			return
+		}
+	}
+}
+
+// streamText 返回可增量脱敏的文本字段
+func streamText(sc acp.StreamContent) (string, string, bool) {
+	switch c := sc.(type) {
+	case acp.StreamTextContent:
+		return c.Delta, acp.ContentTypeText, true
+	case acp.StreamThinkingContent:
+		return c.Delta, acp.ContentTypeThinking, true
+	case acp.StreamToolArgsContent:
+		return c.Delta, acp.ContentTypeToolArgs, true
+	case acp.StreamToolResultContent:
+		return c.Delta, acp.ContentTypeToolResult, c.Error == nil
+	case acp.StreamMCPArgsContent:
+		return c.Delta, acp.ContentTypeMcpArgs, true
+	case acp.StreamMCPResultContent:
+		return c.Delta, acp.ContentTypeMcpResult, c.Error == nil
+	case acp.StreamCommandResultContent:
+		return c.Delta, acp.ContentTypeCommandExecutionResult, c.Error == nil
+	case acp.StreamCodeExecutionContent:
+		return c.Delta, acp.ContentTypeCodeExecution, true
+	case acp.StreamCodeExecutionResultContent:
+		return c.Delta, acp.ContentTypeCodeExecutionResult, c.Error == nil
+	default:
+		return "", "", false
+	}
+}
+
+func withText(sc acp.StreamContent, text string) acp.StreamContent {
+	switch c := sc.(type) {
+	case acp.StreamTextContent:
+		c.Delta = text
+		return c
+	case acp.StreamThinkingContent:
+		c.Delta = text
+		return c
+	case acp.StreamToolArgsContent:
+		c.Delta = text
+		return c
+	case acp.StreamToolResultContent:
+		c.Delta = text
+		return c
+	case acp.StreamMCPArgsContent:
+		c.Delta = text
+		return c
+	case acp.StreamMCPResultContent:
+		c.Delta = text
+		return c
+	case acp.StreamCommandResultContent:
+		c.Delta = text
+		return c
+	case acp.StreamCodeExecutionContent:
+		c.Delta = text
+		return c
+	case acp.StreamCodeExecutionResultContent:
+		c.Delta = text
+		return c
+	default:
+		return sc
+	}
+}
@@ -0,0 +1,91 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+)
+
+type htmlFormatter struct{}
+
+func (htmlFormatter) format() Format { return FormatHTML }
+
+func (htmlFormatter) header(role string) string {
+	return fmt.Sprintf("<h3 class=\"acp-role acp-role-%s\">%s</h3>\n", html.EscapeString(role), html.EscapeString(roleTitle(role)))
+}
+
+func (htmlFormatter) subagent(label string) string {
+	return fmt.Sprintf("<p class=\"acp-subagent\"><strong>%s</strong></p>\n", html.EscapeString(label))
+}
+
+func (htmlFormatter) paragraph(text string) string {
+	return "<p>" + strings.ReplaceAll(html.EscapeString(text), "\n", "<br>") + "</p>\n"
+}
+
+func (htmlFormatter) details(summary, body string) string {
+	return fmt.Sprintf("<details class=\"acp-thinking\"><summary>%s</summary><pre>%s</pre></details>\n",
+		html.EscapeString(summary), html.EscapeString(body))
+}
+
+func (htmlFormatter) code(title, lang, code string) string {
+	class := ""
+	if lang != "" {
+		class = fmt.Sprintf(" class=\"language-%s\"", html.EscapeString(lang))
+	}
+	return fmt.Sprintf("<p><strong>%s</strong></p>\n<pre><code%s>%s</code></pre>\n",
+		html.EscapeString(title), class, html.EscapeString(code))
+}
+
+func (htmlFormatter) errorLine(e *acp.Error) string {
+	return fmt.Sprintf("<p class=\"acp-error\"><strong>Error</strong> (%s): %s</p>\n",
+		html.EscapeString(e.Type), html.EscapeString(e.Message))
+}
+
+func (htmlFormatter) links(query, answer string, results []acp.WebSearchResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<p><strong>Web search:</strong> %s</p>\n", html.EscapeString(query))
+	if answer != "" {
+		fmt.Fprintf(&sb, "<p>%s</p>\n", html.EscapeString(answer))
+	}
+	if len(results) > 0 {
+		sb.WriteString("<ul class=\"acp-web-search\">\n")
+		for _, r := range results {
+			href, ok := linkURL(r.Url)
+			if !ok {
+				fmt.Fprintf(&sb, "<li>%s</li>\n", html.EscapeString(r.Title))
+				continue
+			}
+			fmt.Fprintf(&sb, "<li><a href=\"%s\">%s</a></li>\n", html.EscapeString(href), html.EscapeString(r.Title))
+		}
+		sb.WriteString("</ul>\n")
+	}
+	return sb.String()
+}
+
+func (htmlFormatter) checklist(items []acp.TodoItem) string {
+	var sb strings.Builder
+	sb.WriteString("<ul class=\"acp-todo\">\n")
+	for _, t := range items {
+		checked := ""
+		if isDone(t.Status) {
+			checked = " checked"
+		}
+		fmt.Fprintf(&sb, "<li><input type=\"checkbox\" disabled%s> %s</li>\n", checked, html.EscapeString(t.Content))
+	}
+	sb.WriteString("</ul>\n")
+	return sb.String()
+}
+
+func (htmlFormatter) qa(question, answer string) string {
+	out := fmt.Sprintf("<dl class=\"acp-qa\">\n<dt>Q</dt><dd>%s</dd>\n", html.EscapeString(question))
+	if answer != "" {
+		out += fmt.Sprintf("<dt>A</dt><dd>%s</dd>\n", html.EscapeString(answer))
+	}
+	return out + "</dl>\n"
+}
+
+func (htmlFormatter) indent(body string, depth int) string {
+	return fmt.Sprintf("<div class=\"acp-block\" style=\"margin-left: %dem\">\n%s</div>\n", depth*2, body)
+}
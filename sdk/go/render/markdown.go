@@ -0,0 +1,115 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+)
+
+type markdown struct{}
+
+func (markdown) format() Format { return FormatMarkdown }
+
+func (markdown) header(role string) string {
+	return "### " + roleTitle(role) + "\n\n"
+}
+
+func (markdown) subagent(label string) string {
+	return "**" + label + "**\n\n"
+}
+
+func (markdown) paragraph(text string) string {
+	return text + "\n\n"
+}
+
+func (markdown) details(summary, body string) string {
+	return fmt.Sprintf("<details>\n<summary>%s</summary>\n\n%s\n\n</details>\n\n", summary, body)
+}
+
+func (markdown) code(title, lang, code string) string {
+	fence := fenceFor(code)
+	return fmt.Sprintf("**%s**\n\n%s%s\n%s\n%s\n\n", title, fence, lang, code, fence)
+}
+
+func (markdown) errorLine(e *acp.Error) string {
+	return fmt.Sprintf("> **Error** (%s): %s\n\n", e.Type, e.Message)
+}
+
+func (markdown) links(query, answer string, results []acp.WebSearchResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "**Web search:** %s\n\n", query)
+	if answer != "" {
+		sb.WriteString(answer + "\n\n")
+	}
+	for _, r := range results {
+		href, ok := linkURL(r.Url)
+		if !ok {
+			fmt.Fprintf(&sb, "- %s\n", escapeLinkText(r.Title))
+			continue
+		}
+		fmt.Fprintf(&sb, "- [%s](%s)\n", escapeLinkText(r.Title), escapeLinkURL(href))
+	}
+	if len(results) > 0 {
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func (markdown) checklist(items []acp.TodoItem) string {
+	var sb strings.Builder
+	for _, t := range items {
+		mark := " "
+		if isDone(t.Status) {
+			mark = "x"
+		}
+		fmt.Fprintf(&sb, "- [%s] %s\n", mark, t.Content)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+func (markdown) qa(question, answer string) string {
+	out := fmt.Sprintf("**Q:** %s\n\n", question)
+	if answer != "" {
+		out += fmt.Sprintf("**A:** %s\n\n", answer)
+	}
+	return out
+}
+
+// indent 以嵌套引用块表示子区块层级
+func (markdown) indent(body string, depth int) string {
+	if depth == 0 {
+		return body
+	}
+
+	prefix := strings.Repeat("> ", depth)
+	lines := strings.Split(strings.TrimRight(body, "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(prefix+l, " ")
+	}
+	return strings.Join(lines, "\n") + "\n\n"
+}
+
+// fenceFor 选择比内容中最长反引号序列更长的围栏
+func fenceFor(code string) string {
+	longest, run := 0, 0
+	for _, r := range code {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// escapeLinkURL 编码会提前结束链接目标的字符
+func escapeLinkURL(s string) string {
+	return strings.NewReplacer("(", "%28", ")", "%29", " ", "%20", "<", "%3C", ">", "%3E").Replace(s)
+}
+
+func escapeLinkText(s string) string {
+	return strings.NewReplacer("[", `\[`, "]", `\]`).Replace(s)
+}
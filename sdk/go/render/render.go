@@ -0,0 +1,292 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+)
+
+type Format int
+
+const (
+	FormatMarkdown Format = iota
+	FormatText
+	FormatHTML
+)
+
+// 自定义内容渲染, 返回值按目标格式原样输出, 返回 false 时使用内置渲染
+type TemplateFunc func(format Format, c acp.Content) (string, bool)
+
+type options struct {
+	redact    func(string) string
+	templates map[string]TemplateFunc
+}
+
+type Option func(*options)
+
+// WithRedactor 渲染前对所有输出的文本字段脱敏, 包括工具名, 搜索结果与文件 ID;
+// 错误类型与 MIME 类型等枚举值不脱敏, WithTemplate 的输出由模板自行处理
+func WithRedactor(fn func(string) string) Option {
+	return func(o *options) { o.redact = fn }
+}
+
+// WithTemplate 为指定内容类型注册渲染模板, 可覆盖内置类型
+func WithTemplate(contentType string, fn TemplateFunc) Option {
+	return func(o *options) { o.templates[contentType] = fn }
+}
+
+// 各输出格式的排版原语, 入参均为未转义的原始文本
+type formatter interface {
+	format() Format
+	header(role string) string
+	subagent(label string) string
+	paragraph(text string) string
+	details(summary, body string) string
+	code(title, lang, code string) string
+	errorLine(e *acp.Error) string
+	links(query, answer string, results []acp.WebSearchResult) string
+	checklist(items []acp.TodoItem) string
+	qa(question, answer string) string
+	indent(body string, depth int) string
+}
+
+func Markdown(msg *acp.Message, opts ...Option) string {
+	return render(markdown{}, msg, opts)
+}
+
+func Text(msg *acp.Message, opts ...Option) string {
+	return render(text{}, msg, opts)
+}
+
+func HTML(msg *acp.Message, opts ...Option) string {
+	return render(htmlFormatter{}, msg, opts)
+}
+
+// Transcript 按顺序渲染多条消息
+func Transcript(format Format, msgs []acp.Message, opts ...Option) string {
+	f := formatterOf(format)
+	parts := make([]string, 0, len(msgs))
+	for i := range msgs {
+		parts = append(parts, render(f, &msgs[i], opts))
+	}
+	return strings.Join(parts, "\n")
+}
+
+func formatterOf(format Format) formatter {
+	switch format {
+	case FormatText:
+		return text{}
+	case FormatHTML:
+		return htmlFormatter{}
+	default:
+		return markdown{}
+	}
+}
+
+type renderer struct {
+	f formatter
+	o *options
+}
+
+func render(f formatter, msg *acp.Message, opts []Option) string {
+	o := &options{
+		redact:    func(s string) string { return s },
+		templates: make(map[string]TemplateFunc),
+	}
+	for _, fn := range opts {
+		fn(o)
+	}
+
+	r := &renderer{f: f, o: o}
+	var sb strings.Builder
+	sb.WriteString(f.header(msg.Role))
+
+	tree, err := msg.BlockTree()
+	if err != nil {
+		// 区块关系异常时按平铺顺序渲染
+		for i := range msg.Blocks {
+			sb.WriteString(r.block(&msg.Blocks[i], 0))
+		}
+	} else {
+		tree.Walk(func(node *acp.BlockNode, depth int) bool {
+			sb.WriteString(r.block(node.Block, depth))
+			return true
+		})
+	}
+
+	if msg.Errors != "" {
+		errType := "run_error"
+		if msg.RunError != nil && msg.RunError.Code != "" {
+			errType = msg.RunError.Code
+		}
+		sb.WriteString(f.errorLine(&acp.Error{Type: errType, Message: r.o.redact(msg.Errors)}))
+	}
+	return sb.String()
+}
+
+func (r *renderer) block(b *acp.Block, depth int) string {
+	var sb strings.Builder
+	if b.IsSubagent {
+		label := "Subagent"
+		if name, ok := b.Metadata["name"].(string); ok && name != "" {
+			label += ": " + r.o.redact(name)
+		}
+		sb.WriteString(r.f.subagent(label))
+	}
+	for _, c := range b.Contents {
+		if c == nil {
+			continue
+		}
+		sb.WriteString(r.content(c))
+	}
+	for i := range b.Errors {
+		sb.WriteString(r.errorLine(b.Errors[i].ContentError()))
+	}
+	if sb.Len() == 0 {
+		return ""
+	}
+	return r.f.indent(sb.String(), depth)
+}
+
+func (r *renderer) content(c acp.Content) string {
+	if fn, ok := r.o.templates[c.Type()]; ok {
+		if out, ok := fn(r.f.format(), c); ok {
+			return out
+		}
+	}
+
+	red := r.o.redact
+	f := r.f
+	switch c := c.(type) {
+	case *acp.TextContent:
+		return f.paragraph(red(c.Text))
+
+	case *acp.ThinkingContent:
+		return f.details("Thinking", red(c.Text))
+
+	case *acp.ToolCallContent:
+		return r.call("Tool: "+red(c.ToolName), "json", c.ToolArgs, c.ToolResult, c.Error)
+
+	case *acp.MCPContent:
+		return r.call(fmt.Sprintf("MCP: %s/%s", red(c.Server), red(c.ToolName)), "json", c.ToolArgs, c.ToolResult, c.Error)
+
+	case *acp.CommandContent:
+		title := "Command"
+		if c.ExitCode != nil {
+			title = fmt.Sprintf("Command (exit code %d)", *c.ExitCode)
+		}
+		return r.call(title, "shell", c.Command, c.Result, c.Error)
+
+	case *acp.CodeExecutionContent:
+		return r.call("Code: "+c.Lang, c.Lang, c.Code, c.Result, c.Error)
+
+	case *acp.WebSearchContent:
+		results := make([]acp.WebSearchResult, 0, len(c.Results))
+		for _, res := range c.Results {
+			results = append(results, acp.WebSearchResult{Title: red(res.Title), Url: red(res.Url), Snippet: red(res.Snippet)})
+		}
+		out := f.links(red(c.Query), red(c.Answer), results)
+		if c.Error != nil {
+			out += r.errorLine(c.Error)
+		}
+		return out
+
+	case *acp.TodoListContent:
+		items := make([]acp.TodoItem, 0, len(c.Todos))
+		for _, t := range c.Todos {
+			t.Content = red(t.Content)
+			items = append(items, t)
+		}
+		return f.checklist(items)
+
+	case *acp.QAContent:
+		answer := ""
+		if c.Answer != nil {
+			answer = red(marshal(c.Answer))
+		}
+		return f.qa(red(c.Message), answer)
+
+	case *acp.FileContent:
+		return f.paragraph(fmt.Sprintf("[file: %s (%s)]", red(c.FileID), c.MimeType))
+
+	case *acp.ArtifactContent:
+		return f.paragraph(fmt.Sprintf("[artifact: %s (%s)]", red(c.FileID), c.MimeType))
+
+	case *acp.DataContent:
+		return f.paragraph(fmt.Sprintf("[data: %s]", c.MimeType))
+
+	case *acp.VariableContent:
+		return f.code("Variables", "json", red(marshalIndent(c.Variables)))
+
+	case *acp.SkillLoadedContent:
+		return f.paragraph("[skill loaded: " + red(c.Name) + "]")
+
+	case *acp.CustomContent:
+		return f.code("Custom", "", red(c.Raw))
+
+	case *acp.InteractionContent:
+		return f.paragraph("[interaction: " + red(c.InteractionID) + "]")
+
+	default:
+		return f.paragraph("[" + c.Type() + "]")
+	}
+}
+
+func (r *renderer) call(title, lang, input, result string, e *acp.Error) string {
+	red := r.o.redact
+	out := r.f.code(title, lang, red(input))
+	if result != "" {
+		out += r.f.code("Result", "", red(result))
+	}
+	if e != nil {
+		out += r.errorLine(e)
+	}
+	return out
+}
+
+func (r *renderer) errorLine(e *acp.Error) string {
+	return r.f.errorLine(&acp.Error{Type: e.Type, Message: r.o.redact(e.Message)})
+}
+
+func marshal(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func marshalIndent(v any) string {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// linkURL 仅允许 http/https 链接, 其余返回 false
+func linkURL(raw string) (string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", false
+	}
+	return u.String(), true
+}
+
+func isDone(status string) bool {
+	switch strings.ToLower(status) {
+	case "done", "completed", "complete", "finished":
+		return true
+	}
+	return false
+}
+
+func roleTitle(role string) string {
+	if role == "" {
+		return "Message"
+	}
+	return strings.ToUpper(role[:1]) + role[1:]
+}
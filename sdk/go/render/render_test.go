@@ -0,0 +1,139 @@
+package render
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func loadMessage(t *testing.T) *acp.Message {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", "message.json"))
+	require.NoError(t, err)
+
+	var msg acp.Message
+	require.NoError(t, json.Unmarshal(data, &msg))
+	return &msg
+}
+
+func assertGolden(t *testing.T, name, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		require.NoError(t, os.WriteFile(path, []byte(got), 0o644))
+	}
+
+	want, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), got)
+}
+
+func redactKeys(s string) string {
+	return strings.ReplaceAll(s, "sk-test-123", "[REDACTED]")
+}
+
+func customTemplate(format Format, c acp.Content) (string, bool) {
+	custom := c.(*acp.CustomContent)
+	if format == FormatHTML {
+		return "<div class=\"widget\">" + custom.Raw + "</div>\n", true
+	}
+	return "", false
+}
+
+func TestRenderGolden(t *testing.T) {
+	msg := loadMessage(t)
+	opts := []Option{WithRedactor(redactKeys), WithTemplate(acp.ContentTypeCustom, customTemplate)}
+
+	assertGolden(t, "message.golden.md", Markdown(msg, opts...))
+	assertGolden(t, "message.golden.txt", Text(msg, opts...))
+	assertGolden(t, "message.golden.html", HTML(msg, opts...))
+}
+
+func TestRenderRedaction(t *testing.T) {
+	msg := loadMessage(t)
+
+	assert.Contains(t, Markdown(msg), "sk-test-123")
+	for _, out := range []string{
+		Markdown(msg, WithRedactor(redactKeys)),
+		Text(msg, WithRedactor(redactKeys)),
+		HTML(msg, WithRedactor(redactKeys)),
+	} {
+		assert.NotContains(t, out, "sk-test-123")
+		assert.Contains(t, out, "[REDACTED]")
+	}
+}
+
+func TestRenderFallsBackOnBrokenTree(t *testing.T) {
+	msg := &acp.Message{Role: acp.RoleUser, Blocks: []acp.Block{
+		{ID: "a", ParentBlockID: "missing", Contents: []acp.Content{acp.NewTextContent("", "orphan")}},
+	}}
+	assert.Equal(t, "== User ==\n\norphan\n\n", Text(msg))
+}
+
+func TestTranscript(t *testing.T) {
+	msgs := []acp.Message{
+		{Role: acp.RoleUser, Blocks: []acp.Block{{Contents: []acp.Content{acp.NewTextContent("", "hi")}}}},
+		{Role: acp.RoleAssistant, Blocks: []acp.Block{{Contents: []acp.Content{acp.NewTextContent("", "hello")}}}, Errors: "boom"},
+	}
+	assert.Equal(t, "### User\n\nhi\n\n\n### Assistant\n\nhello\n\n> **Error** (run_error): boom\n\n", Transcript(FormatMarkdown, msgs))
+}
+
+func TestRenderStructuredErrors(t *testing.T) {
+	msg := &acp.Message{
+		Role: acp.RoleAssistant,
+		Blocks: []acp.Block{{
+			Contents: []acp.Content{acp.NewTextContent("", "partial")},
+			Errors:   []acp.RunError{{Code: acp.ErrorCodeToolFailed, Message: "search failed"}},
+		}},
+		Errors:   "slow down",
+		RunError: &acp.RunError{Code: acp.ErrorCodeRateLimited, Message: "slow down"},
+	}
+	assert.Equal(t, "== Assistant ==\n\npartial\n\nError (tool_failed): search failed\n\nError (rate_limited): slow down\n\n", Text(msg))
+}
+
+func TestRenderUnsafeLinks(t *testing.T) {
+	search := acp.NewWebSearchContent("q")
+	search.Results = []acp.WebSearchResult{
+		{Title: "xss", Url: "javascript:alert(1)"},
+		{Title: "wiki", Url: "https://en.wikipedia.org/wiki/Go_(language)"},
+	}
+	msg := &acp.Message{Role: acp.RoleAssistant, Blocks: []acp.Block{{Contents: []acp.Content{search}}}}
+
+	out := HTML(msg)
+	assert.NotContains(t, out, "javascript:")
+	assert.Contains(t, out, "<li>xss</li>")
+	assert.Contains(t, out, `<a href="https://en.wikipedia.org/wiki/Go_(language)">wiki</a>`)
+
+	out = Markdown(msg)
+	assert.NotContains(t, out, "javascript:")
+	assert.Contains(t, out, "- xss\n")
+	assert.Contains(t, out, "- [wiki](https://en.wikipedia.org/wiki/Go_%28language%29)\n")
+}
+
+func TestRenderRedactsAllFields(t *testing.T) {
+	search := acp.NewWebSearchContent("sk-test-123")
+	search.Results = []acp.WebSearchResult{{Title: "sk-test-123", Url: "https://example.com/?key=sk-test-123"}}
+	call := acp.NewToolCallContent("sk-test-123")
+	qa := acp.NewQAContent("", "", "", "key sk-test-123?", nil)
+	qa.Answer = map[string]any{"key": "sk-test-123"}
+	msg := &acp.Message{Role: acp.RoleAssistant, Blocks: []acp.Block{{Contents: []acp.Content{search, call, qa}}}}
+
+	for _, out := range []string{
+		Markdown(msg, WithRedactor(redactKeys)),
+		Text(msg, WithRedactor(redactKeys)),
+		HTML(msg, WithRedactor(redactKeys)),
+	} {
+		assert.NotContains(t, out, "sk-test-123")
+	}
+}
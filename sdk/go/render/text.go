@@ -0,0 +1,87 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+)
+
+type text struct{}
+
+func (text) format() Format { return FormatText }
+
+func (text) header(role string) string {
+	return "== " + roleTitle(role) + " ==\n\n"
+}
+
+func (text) subagent(label string) string {
+	return "[" + label + "]\n\n"
+}
+
+func (text) paragraph(s string) string {
+	return s + "\n\n"
+}
+
+func (text) details(summary, body string) string {
+	return fmt.Sprintf("[%s]\n%s\n\n", summary, indentLines(body, "  "))
+}
+
+func (text) code(title, lang, code string) string {
+	return fmt.Sprintf("%s:\n%s\n\n", title, indentLines(code, "    "))
+}
+
+func (text) errorLine(e *acp.Error) string {
+	return fmt.Sprintf("Error (%s): %s\n\n", e.Type, e.Message)
+}
+
+func (text) links(query, answer string, results []acp.WebSearchResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Web search: %s\n", query)
+	if answer != "" {
+		sb.WriteString(answer + "\n")
+	}
+	for _, r := range results {
+		fmt.Fprintf(&sb, "- %s <%s>\n", r.Title, r.Url)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+func (text) checklist(items []acp.TodoItem) string {
+	var sb strings.Builder
+	for _, t := range items {
+		mark := " "
+		if isDone(t.Status) {
+			mark = "x"
+		}
+		fmt.Fprintf(&sb, "[%s] %s\n", mark, t.Content)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+func (text) qa(question, answer string) string {
+	out := "Q: " + question + "\n"
+	if answer != "" {
+		out += "A: " + answer + "\n"
+	}
+	return out + "\n"
+}
+
+func (text) indent(body string, depth int) string {
+	if depth == 0 {
+		return body
+	}
+	return indentLines(strings.TrimRight(body, "\n"), strings.Repeat("  ", depth)) + "\n\n"
+}
+
+func indentLines(s, prefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		if l != "" {
+			lines[i] = prefix + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,208 @@
+// Package sign 为事件流提供 HMAC 链式签名与校验, 用于服务间转发时发现篡改
+package sign
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+)
+
+var (
+	ErrUnknownKey   = errors.New("unknown signing key")
+	ErrBadSignature = errors.New("event signature mismatch")
+	ErrSequence     = errors.New("event sequence broken")
+	ErrTruncated    = errors.New("event stream truncated")
+	ErrNonce        = errors.New("event stream nonce mismatch")
+)
+
+// 签名信封, Sig 为对序号、上一个签名和事件原文的 HMAC-SHA256.
+// 首个信封携带流的 Nonce 作为链的起点, 使不同流的签名链互不相同
+type Envelope struct {
+	Seq   int64           `json:"seq"`
+	KeyID string          `json:"kid"`
+	Nonce string          `json:"nonce,omitempty"`
+	Sig   string          `json:"sig"`
+	Event json.RawMessage `json:"event"`
+}
+
+const macVersion = "acp-sig-v2"
+
+func mac(key []byte, keyID string, seq int64, prev, event []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(macVersion))
+	h.Write([]byte(keyID))
+	h.Write([]byte{0})
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(seq))
+	h.Write(buf[:])
+	h.Write(prev)
+	h.Write(event)
+	return h.Sum(nil)
+}
+
+// Signer 为单条事件流签名, 序号从 1 开始
+type Signer struct {
+	mux   sync.Mutex
+	keyID string
+	key   []byte
+	nonce string
+	seq   int64
+	prev  []byte
+}
+
+type SignerOption func(*Signer)
+
+// WithNonce 使用指定的流标识(如 RunID)作为链的起点, 默认随机生成
+func WithNonce(nonce string) SignerOption {
+	return func(s *Signer) { s.nonce = nonce }
+}
+
+func NewSigner(keyID string, key []byte, opts ...SignerOption) *Signer {
+	s := &Signer{keyID: keyID, key: key}
+	for _, fn := range opts {
+		fn(s)
+	}
+	if s.nonce == "" {
+		s.nonce = rand.Text()
+	}
+	s.prev = []byte(s.nonce)
+	return s
+}
+
+// Nonce 返回流的起点标识, 可通过其他渠道告知接收方用于 ExpectNonce
+func (s *Signer) Nonce() string {
+	return s.nonce
+}
+
+// Rotate 切换签名密钥, 之后的事件使用新密钥, 链不中断
+func (s *Signer) Rotate(keyID string, key []byte) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.keyID, s.key = keyID, key
+}
+
+func (s *Signer) Sign(e acp.Event) (*Envelope, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.seq++
+	env := &Envelope{Seq: s.seq, KeyID: s.keyID, Event: data}
+	if s.seq == 1 {
+		env.Nonce = s.nonce
+	}
+	sig := mac(s.key, s.keyID, s.seq, s.prev, data)
+	s.prev = sig
+	env.Sig = base64.RawURLEncoding.EncodeToString(sig)
+	return env, nil
+}
+
+// 按密钥 ID 查找校验密钥, 轮换期间新旧密钥可同时存在
+type Keyring map[string][]byte
+
+// Verifier 校验单条事件流, 拒绝乱序、丢失、重放、被修改和运行结束后追加的事件.
+// 未设置 ExpectNonce 时接受任意起点, 整条流被替换为另一条合法流时无法发现
+type Verifier struct {
+	mux   sync.Mutex
+	keys  Keyring
+	nonce string
+	seq   int64
+	prev  []byte
+	done  bool
+	err   error
+}
+
+type VerifierOption func(*Verifier)
+
+// ExpectNonce 要求流的起点为 nonce, 拒绝其他流(如同一密钥签名的历史运行)的重放
+func ExpectNonce(nonce string) VerifierOption {
+	return func(v *Verifier) { v.nonce = nonce }
+}
+
+func NewVerifier(keys Keyring, opts ...VerifierOption) *Verifier {
+	v := &Verifier{keys: keys}
+	for _, fn := range opts {
+		fn(v)
+	}
+	return v
+}
+
+// Verify 校验信封并解出事件, 失败后校验器不再前进, 后续事件都返回首次的错误
+func (v *Verifier) Verify(env *Envelope) (acp.Event, error) {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+
+	if v.err != nil {
+		return nil, v.err
+	}
+	e, err := v.verify(env)
+	if err != nil {
+		v.err = err
+		return nil, err
+	}
+	return e, nil
+}
+
+func (v *Verifier) verify(env *Envelope) (acp.Event, error) {
+	if v.done {
+		return nil, fmt.Errorf("%w: seq %d after run end", ErrSequence, env.Seq)
+	}
+	if env.Seq != v.seq+1 {
+		return nil, fmt.Errorf("%w: got seq %d, want %d", ErrSequence, env.Seq, v.seq+1)
+	}
+
+	prev := v.prev
+	switch {
+	case env.Seq == 1 && env.Nonce == "":
+		return nil, fmt.Errorf("%w: missing nonce", ErrNonce)
+	case env.Seq == 1 && v.nonce != "" && env.Nonce != v.nonce:
+		return nil, fmt.Errorf("%w: got %q", ErrNonce, env.Nonce)
+	case env.Seq == 1:
+		prev = []byte(env.Nonce)
+	case env.Nonce != "":
+		return nil, fmt.Errorf("%w: nonce at seq %d", ErrNonce, env.Seq)
+	}
+
+	key, ok := v.keys[env.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, env.KeyID)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(env.Sig)
+	if err != nil || !hmac.Equal(sig, mac(key, env.KeyID, env.Seq, prev, env.Event)) {
+		return nil, fmt.Errorf("%w: seq %d", ErrBadSignature, env.Seq)
+	}
+
+	e, err := acp.UnmarshalEvent(env.Event)
+	if err != nil {
+		return nil, err
+	}
+
+	v.seq, v.prev = env.Seq, sig
+	switch e.Type() {
+	case acp.EventTypeRunFinished, acp.EventTypeRunError:
+		v.done = true
+	}
+	return e, nil
+}
+
+// Done 是否已校验到运行结束事件, 用于在流结束时识别尾部被截断
+func (v *Verifier) Done() bool {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+
+	return v.done
+}
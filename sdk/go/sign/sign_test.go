@@ -0,0 +1,239 @@
+package sign
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var keys = Keyring{
+	"k1": []byte("first-secret"),
+	"k2": []byte("second-secret"),
+}
+
+func events() []acp.Event {
+	return []acp.Event{
+		acp.NewRunStartedEvent("s1", "r1"),
+		acp.NewBlockStartEvent("b1"),
+		acp.NewContentStartEvent("c1", "b1"),
+		acp.NewContentDeltaEvent("c1", acp.NewStreamTextContent("<b>hello</b> & bye")),
+		acp.NewContentEndEvent("c1"),
+		acp.NewBlockEndEvent("b1", nil),
+		acp.NewRunFinishedEvent("r1"),
+	}
+}
+
+func signAll(t *testing.T, signer *Signer, evts []acp.Event) []*Envelope {
+	envs := make([]*Envelope, 0, len(evts))
+	for _, e := range evts {
+		env, err := signer.Sign(e)
+		require.NoError(t, err)
+		envs = append(envs, env)
+	}
+	return envs
+}
+
+func verifyAll(envs []*Envelope) ([]acp.Event, error) {
+	v := NewVerifier(keys)
+	var out []acp.Event
+	for _, env := range envs {
+		e, err := v.Verify(env)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func TestSignVerify(t *testing.T) {
+	evts := events()
+	envs := signAll(t, NewSigner("k1", keys["k1"]), evts)
+	for i, env := range envs {
+		assert.Equal(t, int64(i+1), env.Seq)
+	}
+
+	out, err := verifyAll(envs)
+	require.NoError(t, err)
+	assert.Equal(t, evts, out)
+}
+
+func TestVerifyRejects(t *testing.T) {
+	cases := map[string]struct {
+		mutate func(envs []*Envelope) []*Envelope
+		err    error
+	}{
+		"reordered": {func(e []*Envelope) []*Envelope {
+			e[2], e[3] = e[3], e[2]
+			return e
+		}, ErrSequence},
+		"dropped": {func(e []*Envelope) []*Envelope {
+			return append(e[:3], e[4:]...)
+		}, ErrSequence},
+		"replayed": {func(e []*Envelope) []*Envelope {
+			return append(e[:4], e[3:]...)
+		}, ErrSequence},
+		"modified": {func(e []*Envelope) []*Envelope {
+			e[3].Event = json.RawMessage(strings.Replace(string(e[3].Event), "hello", "HELLO", 1))
+			return e
+		}, ErrBadSignature},
+		"injected with renumbering": {func(e []*Envelope) []*Envelope {
+			forged := *e[3]
+			forged.Seq++
+			forged.Event = json.RawMessage(strings.Replace(string(forged.Event), "hello", "pwned", 1))
+			out := append([]*Envelope{}, e[:4]...)
+			out = append(out, &forged)
+			for _, env := range e[4:] {
+				cp := *env
+				cp.Seq++
+				out = append(out, &cp)
+			}
+			return out
+		}, ErrBadSignature},
+		"unknown key": {func(e []*Envelope) []*Envelope {
+			e[1].KeyID = "k9"
+			return e
+		}, ErrUnknownKey},
+		"swapped key id": {func(e []*Envelope) []*Envelope {
+			e[1].KeyID = "k2"
+			return e
+		}, ErrBadSignature},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			envs := c.mutate(signAll(t, NewSigner("k1", keys["k1"]), events()))
+			_, err := verifyAll(envs)
+			assert.ErrorIs(t, err, c.err)
+		})
+	}
+}
+
+func TestVerifyRejectsCrossStream(t *testing.T) {
+	old := signAll(t, NewSigner("k1", keys["k1"], WithNonce("r0")), events())
+	current := NewSigner("k1", keys["k1"], WithNonce("r1"))
+	envs := signAll(t, current, events())
+	assert.Equal(t, "r1", envs[0].Nonce)
+	assert.Empty(t, envs[1].Nonce)
+	assert.NotEqual(t, old[0].Sig, envs[0].Sig, "identical events in different streams must not share signatures")
+
+	// 拼接另一条流的后续事件
+	spliced := append([]*Envelope{envs[0]}, old[1:]...)
+	_, err := verifyAll(spliced)
+	assert.ErrorIs(t, err, ErrBadSignature)
+
+	// 整条重放另一条流
+	v := NewVerifier(keys, ExpectNonce(current.Nonce()))
+	_, err = v.Verify(old[0])
+	assert.ErrorIs(t, err, ErrNonce)
+
+	v = NewVerifier(keys, ExpectNonce(current.Nonce()))
+	for _, env := range envs {
+		_, err := v.Verify(env)
+		require.NoError(t, err)
+	}
+
+	// 首个信封必须携带 nonce, 之后的信封不能携带
+	stripped := *envs[0]
+	stripped.Nonce = ""
+	_, err = NewVerifier(keys).Verify(&stripped)
+	assert.ErrorIs(t, err, ErrNonce)
+
+	injected := *envs[1]
+	injected.Nonce = "r1"
+	_, err = verifyAll([]*Envelope{envs[0], &injected})
+	assert.ErrorIs(t, err, ErrNonce)
+}
+
+func TestSignerRandomNonce(t *testing.T) {
+	a, b := NewSigner("k1", keys["k1"]), NewSigner("k1", keys["k1"])
+	assert.NotEmpty(t, a.Nonce())
+	assert.NotEqual(t, a.Nonce(), b.Nonce())
+}
+
+func TestVerifierLatchesFailure(t *testing.T) {
+	envs := signAll(t, NewSigner("k1", keys["k1"]), events())
+	v := NewVerifier(keys)
+
+	_, err := v.Verify(envs[0])
+	require.NoError(t, err)
+	forged := *envs[1]
+	forged.Event = json.RawMessage(strings.Replace(string(forged.Event), "b1", "b2", 1))
+	_, err = v.Verify(&forged)
+	require.ErrorIs(t, err, ErrBadSignature)
+
+	// 失败后即使收到原始事件也不再放行
+	_, err = v.Verify(envs[1])
+	assert.ErrorIs(t, err, ErrBadSignature)
+}
+
+func TestVerifyRejectsAfterRunEnd(t *testing.T) {
+	signer := NewSigner("k1", keys["k1"])
+	evts := append(events(), acp.NewBlockStartEvent("b2"))
+	envs := signAll(t, signer, evts)
+
+	out, err := verifyAll(envs)
+	assert.ErrorIs(t, err, ErrSequence)
+	assert.Len(t, out, len(evts)-1)
+}
+
+func TestKeyRotation(t *testing.T) {
+	signer := NewSigner("k1", keys["k1"])
+	evts := events()
+	envs := signAll(t, signer, evts[:3])
+	signer.Rotate("k2", keys["k2"])
+	envs = append(envs, signAll(t, signer, evts[3:])...)
+
+	assert.Equal(t, "k1", envs[2].KeyID)
+	assert.Equal(t, "k2", envs[3].KeyID)
+
+	out, err := verifyAll(envs)
+	require.NoError(t, err)
+	assert.Len(t, out, len(evts))
+
+	// 旧密钥下线后无法校验
+	v := NewVerifier(Keyring{"k2": keys["k2"]})
+	_, err = v.Verify(envs[0])
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestWriterReader(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, NewSigner("k1", keys["k1"]))
+	m := acp.NewCreator(nil, acp.WithInterceptors(Forward(w)))
+	for _, e := range events() {
+		require.NoError(t, m.AddEvent(e))
+	}
+
+	var got []acp.Event
+	require.NoError(t, NewReader(bytes.NewReader(buf.Bytes()), keys).ReadAll(func(e acp.Event) error {
+		got = append(got, e)
+		return nil
+	}))
+	assert.Equal(t, events()[3].(acp.ContentDeltaEvent).Content, got[3].(acp.ContentDeltaEvent).Content)
+	assert.Len(t, got, len(events()))
+
+	// 篡改传输中的内容
+	tampered := strings.Replace(buf.String(), "bye", "BYE", 1)
+	err := NewReader(strings.NewReader(tampered), keys).ReadAll(func(acp.Event) error { return nil })
+	assert.ErrorIs(t, err, ErrBadSignature)
+
+	// 截掉最后一个事件
+	frames := strings.SplitAfter(buf.String(), "\n\n")
+	truncated := strings.Join(frames[:len(frames)-2], "")
+	r := NewReader(strings.NewReader(truncated), keys)
+	for {
+		_, err = r.Next()
+		if err != nil {
+			break
+		}
+	}
+	assert.ErrorIs(t, err, ErrTruncated)
+	assert.NotErrorIs(t, err, io.EOF)
+}
@@ -0,0 +1,108 @@
+package sign
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+	"github.com/manucorporat/sse"
+)
+
+// Writer 以 SSE 发送签名信封, 事件名为原事件类型
+type Writer struct {
+	writer io.Writer
+	signer *Signer
+}
+
+func NewWriter(w io.Writer, signer *Signer) *Writer {
+	return &Writer{writer: w, signer: signer}
+}
+
+func (w *Writer) Send(e acp.Event) error {
+	env, err := w.signer.Sign(e)
+	if err != nil {
+		return err
+	}
+
+	if err := sse.Encode(w.writer, sse.Event{
+		Id:    fmt.Sprintf("%d", env.Seq),
+		Event: string(e.Type()),
+		Data:  env,
+	}); err != nil {
+		return err
+	}
+
+	if f, ok := w.writer.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// Forward 在 Creator 处理成功后把事件签名发送到 w
+func Forward(w *Writer) acp.Interceptor {
+	return func(ctx context.Context, e acp.Event, next acp.Handler) error {
+		if err := next(ctx, e); err != nil {
+			return err
+		}
+		return w.Send(e)
+	}
+}
+
+// Reader 读取并校验签名的 SSE 事件流
+type Reader struct {
+	scanner  *bufio.Scanner
+	verifier *Verifier
+}
+
+func NewReader(r io.Reader, keys Keyring, opts ...VerifierOption) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	return &Reader{scanner: scanner, verifier: NewVerifier(keys, opts...)}
+}
+
+// Next 返回下一个通过校验的事件.
+// 流正常结束返回 io.EOF; 未收到运行结束事件就结束时返回 ErrTruncated.
+func (r *Reader) Next() (acp.Event, error) {
+	for r.scanner.Scan() {
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		data, ok := bytes.CutPrefix(line, []byte("data:"))
+		if !ok {
+			continue
+		}
+
+		var env Envelope
+		if err := json.Unmarshal(bytes.TrimSpace(data), &env); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrBadSignature, err)
+		}
+		return r.verifier.Verify(&env)
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !r.verifier.Done() {
+		return nil, ErrTruncated
+	}
+	return nil, io.EOF
+}
+
+// ReadAll 校验并把事件依次交给 fn, 直到流结束或出错
+func (r *Reader) ReadAll(fn func(acp.Event) error) error {
+	for {
+		e, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+}
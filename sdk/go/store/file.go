@@ -0,0 +1,272 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+)
+
+const (
+	messagesFile = "messages.jsonl"
+	eventsFile   = "events.jsonl"
+)
+
+// 文件存储, 消息与事件以 JSONL 追加写入目录, 打开时回放建立内存索引.
+// 同一消息多次保存会追加多行, 打开时若存在旧版本则重写 messages.jsonl 只保留最新版本
+type FileStore struct {
+	mux      sync.Mutex
+	index    *MemoryStore
+	messages *os.File
+	events   *os.File
+	sync     bool
+
+	messageLines int // 打开时回放的消息行数
+}
+
+type FileOption func(*FileStore)
+
+// WithSync 每次写入后刷盘
+func WithSync() FileOption {
+	return func(s *FileStore) { s.sync = true }
+}
+
+// OpenFile 打开或创建 dir 下的文件存储
+func OpenFile(dir string, opts ...FileOption) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	s := &FileStore{index: NewMemoryStore()}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	var err error
+	path := filepath.Join(dir, messagesFile)
+	if s.messages, err = openLog(path, s.replayMessage); err != nil {
+		return nil, err
+	}
+	if s.messageLines > len(s.index.messages) {
+		if err := s.compactMessages(path); err != nil {
+			s.messages.Close()
+			return nil, err
+		}
+	}
+	if s.events, err = openLog(filepath.Join(dir, eventsFile), s.replayEvent); err != nil {
+		s.messages.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func openLog(path string, replay func([]byte) error) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(f)
+	line, offset := 0, int64(0)
+	for {
+		data, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(data) > 0 {
+				// 末尾未写完的行视为崩溃残留, 截断后继续追加
+				if err := f.Truncate(offset); err != nil {
+					f.Close()
+					return nil, err
+				}
+			}
+			break
+		}
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		line++
+		offset += int64(len(data))
+		if err := replay(data); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("%s:%d: %w", filepath.Base(path), line, err)
+		}
+	}
+	return f, nil
+}
+
+func (s *FileStore) replayMessage(data []byte) error {
+	var head struct {
+		ID        string `json:"id"`
+		SessionID string `json:"session_id"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return err
+	}
+	s.index.putMessage(head.ID, head.SessionID, data)
+	s.messageLines++
+	return nil
+}
+
+// compactMessages 按会话内顺序写出每条消息的最新版本, 写入临时文件后替换原文件
+func (s *FileStore) compactMessages(path string) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	for _, sessionID := range slices.Sorted(maps.Keys(s.index.sessions)) {
+		for _, id := range s.index.sessions[sessionID] {
+			// 回放得到的数据含行尾换行
+			w.Write(bytes.TrimSuffix(s.index.messages[id], []byte("\n")))
+			w.WriteByte('\n')
+		}
+	}
+	err = errors.Join(w.Flush(), f.Sync(), f.Close())
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := s.messages.Close(); err != nil {
+		return err
+	}
+	s.messages, err = os.OpenFile(path, os.O_RDWR|os.O_APPEND, 0o644)
+	return err
+}
+
+func (s *FileStore) replayEvent(data []byte) error {
+	var raw struct {
+		RunID string          `json:"run_id"`
+		Seq   int64           `json:"seq"`
+		Event json.RawMessage `json:"event"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if next := s.index.nextSeq(raw.RunID); raw.Seq != next {
+		return fmt.Errorf("%w: run %s seq %d, want %d", ErrInvalidInput, raw.RunID, raw.Seq, next)
+	}
+	s.index.events[raw.RunID] = append(s.index.events[raw.RunID], raw.Event)
+	return nil
+}
+
+func (s *FileStore) SaveMessage(ctx context.Context, msg *acp.Message) error {
+	if msg == nil || msg.ID == "" {
+		return fmt.Errorf("%w: message id required", ErrInvalidInput)
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.messages == nil {
+		return ErrClosed
+	}
+	if err := s.write(s.messages, data); err != nil {
+		return err
+	}
+
+	s.index.mux.Lock()
+	s.index.putMessage(msg.ID, msg.SessionID, data)
+	s.index.mux.Unlock()
+	return nil
+}
+
+func (s *FileStore) GetMessage(ctx context.Context, id string) (*acp.Message, error) {
+	return s.index.GetMessage(ctx, id)
+}
+
+func (s *FileStore) ListMessages(ctx context.Context, sessionID string, page Page) ([]acp.Message, error) {
+	return s.index.ListMessages(ctx, sessionID, page)
+}
+
+func (s *FileStore) AppendEvents(ctx context.Context, runID string, events ...acp.Event) (int64, error) {
+	if runID == "" {
+		return 0, fmt.Errorf("%w: run id required", ErrInvalidInput)
+	}
+
+	encoded, err := encodeEvents(events)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.events == nil {
+		return 0, ErrClosed
+	}
+
+	seq := s.index.nextSeq(runID)
+	var buf []byte
+	for i, data := range encoded {
+		line, err := json.Marshal(struct {
+			RunID string          `json:"run_id"`
+			Seq   int64           `json:"seq"`
+			Event json.RawMessage `json:"event"`
+		}{runID, seq + int64(i), data})
+		if err != nil {
+			return 0, err
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+	if len(buf) > 0 {
+		// 整批一次写入, 避免部分成功
+		if err := s.write(s.events, buf[:len(buf)-1]); err != nil {
+			return 0, err
+		}
+	}
+
+	s.index.mux.Lock()
+	s.index.events[runID] = append(s.index.events[runID], encoded...)
+	last := int64(len(s.index.events[runID]))
+	s.index.mux.Unlock()
+	return last, nil
+}
+
+func (s *FileStore) LoadEvents(ctx context.Context, runID string, fromSeq int64) ([]EventRecord, error) {
+	return s.index.LoadEvents(ctx, runID, fromSeq)
+}
+
+func (s *FileStore) write(f *os.File, data []byte) error {
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if s.sync {
+		return f.Sync()
+	}
+	return nil
+}
+
+func (s *FileStore) Close() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.messages == nil {
+		return nil
+	}
+	err := errors.Join(s.messages.Close(), s.events.Close())
+	s.messages, s.events = nil, nil
+	s.index.Close()
+	return err
+}
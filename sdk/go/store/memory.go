@@ -0,0 +1,177 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+)
+
+// 内存存储, 消息与事件以 JSON 保存, 读写互不共享引用
+type MemoryStore struct {
+	mux      sync.RWMutex
+	closed   bool
+	messages map[string][]byte   // message_id -> message
+	sessions map[string][]string // session_id -> message_ids
+	owners   map[string]string   // message_id -> session_id
+	events   map[string][][]byte // run_id -> events, 下标为 seq-1
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		messages: make(map[string][]byte),
+		sessions: make(map[string][]string),
+		owners:   make(map[string]string),
+		events:   make(map[string][][]byte),
+	}
+}
+
+func (s *MemoryStore) SaveMessage(ctx context.Context, msg *acp.Message) error {
+	if msg == nil || msg.ID == "" {
+		return fmt.Errorf("%w: message id required", ErrInvalidInput)
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	s.putMessage(msg.ID, msg.SessionID, data)
+	return nil
+}
+
+func (s *MemoryStore) putMessage(id, sessionID string, data []byte) {
+	if owner, ok := s.owners[id]; !ok || owner != sessionID {
+		if ok {
+			s.sessions[owner] = slices.DeleteFunc(s.sessions[owner], func(v string) bool { return v == id })
+		}
+		s.sessions[sessionID] = append(s.sessions[sessionID], id)
+		s.owners[id] = sessionID
+	}
+	s.messages[id] = data
+}
+
+func (s *MemoryStore) GetMessage(ctx context.Context, id string) (*acp.Message, error) {
+	s.mux.RLock()
+	data, ok := s.messages[id]
+	closed := s.closed
+	s.mux.RUnlock()
+
+	if closed {
+		return nil, ErrClosed
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: message %s", ErrNotFound, id)
+	}
+
+	var msg acp.Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (s *MemoryStore) ListMessages(ctx context.Context, sessionID string, page Page) ([]acp.Message, error) {
+	s.mux.RLock()
+	if s.closed {
+		s.mux.RUnlock()
+		return nil, ErrClosed
+	}
+	ids := paginate(s.sessions[sessionID], page)
+	items := make([][]byte, 0, len(ids))
+	for _, id := range ids {
+		items = append(items, s.messages[id])
+	}
+	s.mux.RUnlock()
+
+	msgs := make([]acp.Message, 0, len(items))
+	for _, data := range items {
+		var msg acp.Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+func (s *MemoryStore) AppendEvents(ctx context.Context, runID string, events ...acp.Event) (int64, error) {
+	if runID == "" {
+		return 0, fmt.Errorf("%w: run id required", ErrInvalidInput)
+	}
+
+	encoded, err := encodeEvents(events)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.closed {
+		return 0, ErrClosed
+	}
+	s.events[runID] = append(s.events[runID], encoded...)
+	return int64(len(s.events[runID])), nil
+}
+
+func (s *MemoryStore) LoadEvents(ctx context.Context, runID string, fromSeq int64) ([]EventRecord, error) {
+	s.mux.RLock()
+	if s.closed {
+		s.mux.RUnlock()
+		return nil, ErrClosed
+	}
+	all := s.events[runID]
+	start := max(fromSeq, 1)
+	var items [][]byte
+	if start <= int64(len(all)) {
+		items = slices.Clone(all[start-1:])
+	}
+	s.mux.RUnlock()
+
+	records := make([]EventRecord, 0, len(items))
+	for i, data := range items {
+		e, err := acp.UnmarshalEvent(data)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, EventRecord{RunID: runID, Seq: start + int64(i), Event: e})
+	}
+	return records, nil
+}
+
+// nextSeq 返回运行的下一个事件序号
+func (s *MemoryStore) nextSeq(runID string) int64 {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	return int64(len(s.events[runID])) + 1
+}
+
+func (s *MemoryStore) Close() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.closed = true
+	return nil
+}
+
+func encodeEvents(events []acp.Event) ([][]byte, error) {
+	encoded := make([][]byte, 0, len(events))
+	for _, e := range events {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil, err
+		}
+		encoded = append(encoded, data)
+	}
+	return encoded, nil
+}
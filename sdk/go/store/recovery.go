@@ -0,0 +1,52 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+)
+
+// 将运行事件追加到 Store 的 acp.EventLog 实现
+type EventLog struct {
+	ctx   context.Context
+	store Store
+	runID string
+}
+
+func NewEventLog(ctx context.Context, s Store, runID string) *EventLog {
+	return &EventLog{ctx: ctx, store: s, runID: runID}
+}
+
+func (l *EventLog) Append(e acp.Event) error {
+	_, err := l.store.AppendEvents(l.ctx, l.runID, e)
+	return err
+}
+
+// LoadRunEvents 读取运行的全部事件
+func LoadRunEvents(ctx context.Context, s Store, runID string) ([]acp.Event, error) {
+	records, err := s.LoadEvents(ctx, runID, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]acp.Event, 0, len(records))
+	for _, r := range records {
+		events = append(events, r.Event)
+	}
+	return events, nil
+}
+
+// Recover 从 Store 中的事件日志恢复运行, 恢复后的 Creator 继续写入同一日志
+func Recover(ctx context.Context, s Store, runID string, writer *acp.SSEWriter, opts ...acp.CreatorOption) (*acp.Creator, error) {
+	events, err := LoadRunEvents(ctx, s, runID)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%w: run %s has no events", ErrNotFound, runID)
+	}
+
+	opts = append(opts, acp.WithEventLog(NewEventLog(ctx, s, runID)))
+	return acp.RecoverCreator(writer, events, opts...)
+}
@@ -0,0 +1,58 @@
+package store_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+	"github.com/llm-infra/acp/sdk/go/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoverAfterRestart(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	s, err := store.OpenFile(dir)
+	require.NoError(t, err)
+	m := acp.NewCreator(nil, acp.WithEventLog(store.NewEventLog(ctx, s, "r1")))
+	require.NoError(t, m.AddEvent(acp.NewRunStartedEvent("s1", "r1")))
+	require.NoError(t, m.AddEvent(acp.NewBlockStartEvent("b1")))
+	require.NoError(t, m.AddEvent(acp.NewContentStartEvent("c1", "b1")))
+	require.NoError(t, m.AddEvent(acp.NewContentDeltaEvent("c1", acp.NewStreamTextContent("partial"))))
+	require.NoError(t, s.Close())
+
+	// 模拟进程重启
+	s, err = store.OpenFile(dir)
+	require.NoError(t, err)
+	defer s.Close()
+
+	recovered, err := store.Recover(ctx, s, "r1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b1"}, recovered.OpenBlocks())
+	assert.Equal(t, []string{"c1"}, recovered.OpenContents())
+	require.NoError(t, recovered.Abort("restarted"))
+
+	events, err := store.LoadRunEvents(ctx, s, "r1")
+	require.NoError(t, err)
+	require.Len(t, events, 7)
+	assert.Equal(t, acp.EventTypeRunError, events[6].Type())
+
+	// 重连的客户端收到完整历史
+	var buf bytes.Buffer
+	require.NoError(t, acp.ReplayEvents(acp.NewSSEWriter(&buf), events))
+	assert.Equal(t, 7, strings.Count(buf.String(), "event:"))
+
+	final, err := acp.RecoverCreator(nil, events)
+	require.NoError(t, err)
+	assert.True(t, final.Finished())
+	assert.Equal(t, "partial", final.Blocks[0].Contents[0].(*acp.TextContent).Text)
+}
+
+func TestRecoverUnknownRun(t *testing.T) {
+	_, err := store.Recover(context.Background(), store.NewMemoryStore(), "missing", nil)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+)
+
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrInvalidInput = errors.New("invalid input")
+	ErrClosed       = errors.New("store closed")
+)
+
+// 分页参数, Limit 为 0 时不限制条数
+type Page struct {
+	Offset int
+	Limit  int
+}
+
+// 事件记录, Seq 由存储按运行从 1 开始递增分配
+type EventRecord struct {
+	RunID string    `json:"run_id"`
+	Seq   int64     `json:"seq"`
+	Event acp.Event `json:"event"`
+}
+
+func (r *EventRecord) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		RunID string          `json:"run_id"`
+		Seq   int64           `json:"seq"`
+		Event json.RawMessage `json:"event"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	e, err := acp.UnmarshalEvent(raw.Event)
+	if err != nil {
+		return err
+	}
+
+	r.RunID = raw.RunID
+	r.Seq = raw.Seq
+	r.Event = e
+	return nil
+}
+
+// 消息与事件日志的持久化接口
+type Store interface {
+	// SaveMessage 按消息 ID 新增或覆盖
+	SaveMessage(ctx context.Context, msg *acp.Message) error
+	GetMessage(ctx context.Context, id string) (*acp.Message, error)
+	// ListMessages 按首次保存顺序返回会话内的消息
+	ListMessages(ctx context.Context, sessionID string, page Page) ([]acp.Message, error)
+
+	// AppendEvents 追加运行事件, 返回分配的最后一个序号
+	AppendEvents(ctx context.Context, runID string, events ...acp.Event) (int64, error)
+	// LoadEvents 返回序号不小于 fromSeq 的事件
+	LoadEvents(ctx context.Context, runID string, fromSeq int64) ([]EventRecord, error)
+
+	Close() error
+}
+
+func paginate[T any](items []T, page Page) []T {
+	if page.Offset >= len(items) {
+		return []T{}
+	}
+	items = items[max(page.Offset, 0):]
+	if page.Limit > 0 && page.Limit < len(items) {
+		items = items[:page.Limit]
+	}
+	return items
+}
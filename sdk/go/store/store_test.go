@@ -0,0 +1,127 @@
+package store_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+	"github.com/llm-infra/acp/sdk/go/store"
+	"github.com/llm-infra/acp/sdk/go/store/storetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		return store.NewMemoryStore()
+	})
+}
+
+func TestFileStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		s, err := store.OpenFile(t.TempDir())
+		require.NoError(t, err)
+		return s
+	})
+}
+
+func TestFileStoreReopen(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	s, err := store.OpenFile(dir, store.WithSync())
+	require.NoError(t, err)
+	require.NoError(t, s.SaveMessage(ctx, &acp.Message{ID: "m1", SessionID: "s1", Role: "user"}))
+	require.NoError(t, s.SaveMessage(ctx, &acp.Message{ID: "m2", SessionID: "s1", Role: "assistant"}))
+	require.NoError(t, s.SaveMessage(ctx, &acp.Message{ID: "m1", SessionID: "s1", Role: "system"}))
+	_, err = s.AppendEvents(ctx, "r1", acp.NewRunStartedEvent("s1", "r1"), acp.NewRunFinishedEvent("r1"))
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	s, err = store.OpenFile(dir)
+	require.NoError(t, err)
+	defer s.Close()
+
+	msgs, err := s.ListMessages(ctx, "s1", store.Page{})
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "system", msgs[0].Role)
+	assert.Equal(t, "m2", msgs[1].ID)
+
+	seq, err := s.AppendEvents(ctx, "r1", acp.NewRunFinishedEvent("r1"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), seq)
+
+	records, err := s.LoadEvents(ctx, "r1", 1)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.IsType(t, acp.RunStartedEvent{}, records[0].Event)
+}
+
+func TestFileStoreCompactsMessages(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "messages.jsonl")
+
+	s, err := store.OpenFile(dir)
+	require.NoError(t, err)
+	for _, role := range []string{"user", "assistant", "system"} {
+		require.NoError(t, s.SaveMessage(ctx, &acp.Message{ID: "m1", SessionID: "s1", Role: role}))
+	}
+	require.NoError(t, s.SaveMessage(ctx, &acp.Message{ID: "m2", SessionID: "s1", Role: "user"}))
+	require.NoError(t, s.Close())
+
+	s, err = store.OpenFile(dir)
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), "\n"))
+
+	// 重写后继续追加
+	require.NoError(t, s.SaveMessage(ctx, &acp.Message{ID: "m3", SessionID: "s1", Role: "user"}))
+	require.NoError(t, s.Close())
+
+	s, err = store.OpenFile(dir)
+	require.NoError(t, err)
+	defer s.Close()
+
+	msgs, err := s.ListMessages(ctx, "s1", store.Page{})
+	require.NoError(t, err)
+	require.Len(t, msgs, 3)
+	assert.Equal(t, "m1", msgs[0].ID)
+	assert.Equal(t, "system", msgs[0].Role)
+	assert.Equal(t, "m3", msgs[2].ID)
+}
+
+func TestFileStoreTruncatedTail(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	s, err := store.OpenFile(dir)
+	require.NoError(t, err)
+	_, err = s.AppendEvents(ctx, "r1", acp.NewRunStartedEvent("s1", "r1"))
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	// 模拟写入中途崩溃
+	f, err := os.OpenFile(filepath.Join(dir, "events.jsonl"), os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"run_id":"r1","seq":2,"ev`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	s, err = store.OpenFile(dir)
+	require.NoError(t, err)
+	defer s.Close()
+
+	seq, err := s.AppendEvents(ctx, "r1", acp.NewRunFinishedEvent("r1"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), seq)
+
+	records, err := s.LoadEvents(ctx, "r1", 0)
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+}
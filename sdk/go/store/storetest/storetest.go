@@ -0,0 +1,256 @@
+// Package storetest 提供 store.Store 实现的一致性测试
+package storetest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+	"github.com/llm-infra/acp/sdk/go/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run 对 newStore 创建的存储运行全部用例, 每个用例使用独立实例
+func Run(t *testing.T, newStore func(t *testing.T) store.Store) {
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, s store.Store)
+	}{
+		{"SaveAndGetMessage", testSaveAndGetMessage},
+		{"GetMessageNotFound", testGetMessageNotFound},
+		{"SaveMessageInvalid", testSaveMessageInvalid},
+		{"SaveMessageOverwrite", testSaveMessageOverwrite},
+		{"MessageIsolation", testMessageIsolation},
+		{"ListMessages", testListMessages},
+		{"ListMessagesPaging", testListMessagesPaging},
+		{"AppendAndLoadEvents", testAppendAndLoadEvents},
+		{"LoadEventsFromSeq", testLoadEventsFromSeq},
+		{"AppendEventsInvalid", testAppendEventsInvalid},
+		{"ConcurrentAppend", testConcurrentAppend},
+		{"Closed", testClosed},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := newStore(t)
+			t.Cleanup(func() { s.Close() })
+			c.fn(t, s)
+		})
+	}
+}
+
+func newMessage(id, sessionID, text string) *acp.Message {
+	return &acp.Message{
+		ID:        id,
+		SessionID: sessionID,
+		Role:      "assistant",
+		Blocks: []acp.Block{{
+			ID:       "b1",
+			Contents: []acp.Content{acp.NewTextContent("c1", text)},
+		}},
+	}
+}
+
+func testSaveAndGetMessage(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	msg := newMessage("m1", "s1", "hello")
+	require.NoError(t, s.SaveMessage(ctx, msg))
+
+	got, err := s.GetMessage(ctx, "m1")
+	require.NoError(t, err)
+	assert.Equal(t, "m1", got.ID)
+	assert.Equal(t, "s1", got.SessionID)
+	require.Len(t, got.Blocks, 1)
+	require.Len(t, got.Blocks[0].Contents, 1)
+	assert.Equal(t, "hello", got.Blocks[0].Contents[0].(*acp.TextContent).Text)
+}
+
+func testGetMessageNotFound(t *testing.T, s store.Store) {
+	_, err := s.GetMessage(context.Background(), "missing")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func testSaveMessageInvalid(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	assert.ErrorIs(t, s.SaveMessage(ctx, nil), store.ErrInvalidInput)
+	assert.ErrorIs(t, s.SaveMessage(ctx, &acp.Message{}), store.ErrInvalidInput)
+}
+
+func testSaveMessageOverwrite(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	require.NoError(t, s.SaveMessage(ctx, newMessage("m1", "s1", "v1")))
+	require.NoError(t, s.SaveMessage(ctx, newMessage("m2", "s1", "other")))
+	require.NoError(t, s.SaveMessage(ctx, newMessage("m1", "s1", "v2")))
+
+	got, err := s.GetMessage(ctx, "m1")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", got.Blocks[0].Contents[0].(*acp.TextContent).Text)
+
+	// 覆盖不改变会话内顺序
+	msgs, err := s.ListMessages(ctx, "s1", store.Page{})
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "m1", msgs[0].ID)
+	assert.Equal(t, "m2", msgs[1].ID)
+}
+
+func testMessageIsolation(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	msg := newMessage("m1", "s1", "original")
+	require.NoError(t, s.SaveMessage(ctx, msg))
+
+	msg.Blocks[0].Contents[0].(*acp.TextContent).Text = "mutated"
+	got, err := s.GetMessage(ctx, "m1")
+	require.NoError(t, err)
+	assert.Equal(t, "original", got.Blocks[0].Contents[0].(*acp.TextContent).Text)
+
+	got.Role = "changed"
+	again, err := s.GetMessage(ctx, "m1")
+	require.NoError(t, err)
+	assert.Equal(t, "assistant", again.Role)
+}
+
+func testListMessages(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	require.NoError(t, s.SaveMessage(ctx, newMessage("m1", "s1", "a")))
+	require.NoError(t, s.SaveMessage(ctx, newMessage("m2", "s2", "b")))
+	require.NoError(t, s.SaveMessage(ctx, newMessage("m3", "s1", "c")))
+
+	msgs, err := s.ListMessages(ctx, "s1", store.Page{})
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "m1", msgs[0].ID)
+	assert.Equal(t, "m3", msgs[1].ID)
+
+	msgs, err = s.ListMessages(ctx, "none", store.Page{})
+	require.NoError(t, err)
+	assert.Empty(t, msgs)
+}
+
+func testListMessagesPaging(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	for i := range 5 {
+		require.NoError(t, s.SaveMessage(ctx, newMessage(fmt.Sprintf("m%d", i), "s1", "x")))
+	}
+
+	msgs, err := s.ListMessages(ctx, "s1", store.Page{Offset: 1, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "m1", msgs[0].ID)
+	assert.Equal(t, "m2", msgs[1].ID)
+
+	msgs, err = s.ListMessages(ctx, "s1", store.Page{Offset: 4, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "m4", msgs[0].ID)
+
+	msgs, err = s.ListMessages(ctx, "s1", store.Page{Offset: 5})
+	require.NoError(t, err)
+	assert.Empty(t, msgs)
+}
+
+func runEvents(runID string) []acp.Event {
+	return []acp.Event{
+		acp.NewRunStartedEvent("s1", runID),
+		acp.NewBlockStartEvent("b1"),
+		acp.NewContentStartEvent("c1", "b1"),
+		acp.NewContentDeltaEvent("c1", acp.NewStreamTextContent("hi")),
+		acp.NewContentEndEvent("c1"),
+		acp.NewBlockEndEvent("b1", &acp.Usage{PromptTokens: 3}),
+		acp.NewRunFinishedEvent(runID),
+	}
+}
+
+func testAppendAndLoadEvents(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	events := runEvents("r1")
+
+	seq, err := s.AppendEvents(ctx, "r1", events[:3]...)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), seq)
+
+	seq, err = s.AppendEvents(ctx, "r1", events[3:]...)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(events)), seq)
+
+	_, err = s.AppendEvents(ctx, "r2", acp.NewRunStartedEvent("s1", "r2"))
+	require.NoError(t, err)
+
+	records, err := s.LoadEvents(ctx, "r1", 0)
+	require.NoError(t, err)
+	require.Len(t, records, len(events))
+	for i, r := range records {
+		assert.Equal(t, "r1", r.RunID)
+		assert.Equal(t, int64(i+1), r.Seq)
+		assert.Equal(t, events[i], r.Event)
+	}
+
+	records, err = s.LoadEvents(ctx, "missing", 0)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+func testLoadEventsFromSeq(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	events := runEvents("r1")
+	_, err := s.AppendEvents(ctx, "r1", events...)
+	require.NoError(t, err)
+
+	records, err := s.LoadEvents(ctx, "r1", 5)
+	require.NoError(t, err)
+	require.Len(t, records, len(events)-4)
+	assert.Equal(t, int64(5), records[0].Seq)
+	assert.Equal(t, events[4], records[0].Event)
+
+	records, err = s.LoadEvents(ctx, "r1", int64(len(events)+1))
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+func testAppendEventsInvalid(t *testing.T, s store.Store) {
+	_, err := s.AppendEvents(context.Background(), "", acp.NewRunFinishedEvent("r1"))
+	assert.ErrorIs(t, err, store.ErrInvalidInput)
+}
+
+func testConcurrentAppend(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	const workers, batches = 4, 10
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range batches {
+				_, err := s.AppendEvents(ctx, "r1", acp.NewBlockStartEvent("b"), acp.NewBlockEndEvent("b", nil))
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	records, err := s.LoadEvents(ctx, "r1", 1)
+	require.NoError(t, err)
+	require.Len(t, records, workers*batches*2)
+	for i, r := range records {
+		assert.Equal(t, int64(i+1), r.Seq)
+	}
+}
+
+func testClosed(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	require.NoError(t, s.Close())
+
+	assert.ErrorIs(t, s.SaveMessage(ctx, newMessage("m1", "s1", "x")), store.ErrClosed)
+	_, err := s.GetMessage(ctx, "m1")
+	assert.ErrorIs(t, err, store.ErrClosed)
+	_, err = s.ListMessages(ctx, "s1", store.Page{})
+	assert.ErrorIs(t, err, store.ErrClosed)
+	_, err = s.AppendEvents(ctx, "r1", acp.NewRunFinishedEvent("r1"))
+	assert.ErrorIs(t, err, store.ErrClosed)
+	_, err = s.LoadEvents(ctx, "r1", 0)
+	assert.ErrorIs(t, err, store.ErrClosed)
+}
@@ -0,0 +1,287 @@
+// Package tracing 根据 ACP 生命周期事件生成 OpenTelemetry 链路
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/llm-infra/acp/sdk/go/tracing"
+
+// 属性键
+const (
+	AttrSessionID = attribute.Key("acp.session_id")
+	AttrRunID     = attribute.Key("acp.run_id")
+	AttrBlockID   = attribute.Key("acp.block_id")
+	AttrContentID = attribute.Key("acp.content_id")
+	AttrSubagent  = attribute.Key("acp.block.subagent")
+	AttrParallel  = attribute.Key("acp.block.parallel")
+
+	AttrToolName  = attribute.Key("gen_ai.tool.name")
+	AttrMCPServer = attribute.Key("acp.mcp.server")
+	AttrExitCode  = attribute.Key("acp.command.exit_code")
+	AttrCodeLang  = attribute.Key("acp.code.lang")
+	AttrErrorType = attribute.Key("error.type")
+	AttrRetryable = attribute.Key("acp.error.retryable")
+
+	AttrModel           = attribute.Key("gen_ai.response.model")
+	AttrInputTokens     = attribute.Key("gen_ai.usage.input_tokens")
+	AttrOutputTokens    = attribute.Key("gen_ai.usage.output_tokens")
+	AttrCachedTokens    = attribute.Key("acp.usage.cached_prompt_tokens")
+	AttrReasoningTokens = attribute.Key("acp.usage.reasoning_tokens")
+	AttrCost            = attribute.Key("acp.usage.cost")
+)
+
+type span struct {
+	ctx  context.Context
+	span trace.Span
+}
+
+// 待定内容, 首个增量确定类型后才创建 span
+type pendingContent struct {
+	blockID string
+	start   int64
+}
+
+// Tracer 以 Intercept 作为拦截器注册到 Creator, 只记录被成功处理的事件:
+// 每次运行一个根 span, 每个区块一个子 span(按 ParentBlockID 嵌套),
+// 工具、MCP、命令和代码执行内容各一个 span. span 时间取自事件时间戳.
+// 应在会改写事件的拦截器之后注册, 以记录最终写入的事件.
+type Tracer struct {
+	mux      sync.Mutex
+	ctx      context.Context
+	tracer   trace.Tracer
+	started  bool
+	run      *span
+	blocks   map[string]*span
+	contents map[string]*span
+	pending  map[string]pendingContent
+}
+
+// NewTracer 以 ctx 中的 span 作为运行 span 的父节点
+func NewTracer(ctx context.Context, provider trace.TracerProvider) *Tracer {
+	return &Tracer{
+		ctx:      ctx,
+		tracer:   provider.Tracer(instrumentationName),
+		blocks:   make(map[string]*span),
+		contents: make(map[string]*span),
+		pending:  make(map[string]pendingContent),
+	}
+}
+
+// Intercept 实现 acp.Interceptor
+func (t *Tracer) Intercept(ctx context.Context, e acp.Event, next acp.Handler) error {
+	if err := next(ctx, e); err != nil {
+		return err
+	}
+	t.observe(e)
+	return nil
+}
+
+func (t *Tracer) observe(e acp.Event) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	ts := timestamp(e)
+	switch evt := e.(type) {
+	case acp.RunStartedEvent:
+		// 重复的 RunStarted 会被 Creator 忽略
+		if t.started {
+			return
+		}
+		t.started = true
+		ctx, s := t.tracer.Start(t.ctx, "acp.run",
+			trace.WithTimestamp(ts),
+			trace.WithAttributes(AttrSessionID.String(evt.SessionID), AttrRunID.String(evt.RunID)))
+		t.run = &span{ctx: ctx, span: s}
+
+	case acp.RunFinishedEvent:
+		t.endRun(ts, nil)
+
+	case acp.RunErrorEvent:
+		t.endRun(ts, &evt)
+
+	case acp.BlockStartEvent:
+		parent := t.rootContext()
+		if p, ok := t.blocks[evt.ParentBlockID]; ok && evt.ParentBlockID != "" {
+			parent = p.ctx
+		}
+		ctx, s := t.tracer.Start(parent, "acp.block",
+			trace.WithTimestamp(ts),
+			trace.WithAttributes(
+				AttrBlockID.String(evt.BlockID),
+				AttrSubagent.Bool(evt.IsSubagent),
+				AttrParallel.Bool(evt.IsParallel),
+			))
+		t.blocks[evt.BlockID] = &span{ctx: ctx, span: s}
+
+	case acp.BlockEndEvent:
+		s, ok := t.blocks[evt.BlockID]
+		if !ok {
+			return
+		}
+		s.span.SetAttributes(usageAttributes(evt.Usage)...)
+		s.span.End(trace.WithTimestamp(ts))
+		delete(t.blocks, evt.BlockID)
+
+	case acp.BlockErrorEvent:
+		s, ok := t.blocks[evt.BlockID]
+		if !ok {
+			return
+		}
+		s.span.AddEvent("acp.block_error", trace.WithTimestamp(ts), trace.WithAttributes(
+			AttrErrorType.String(evt.Error.Code),
+			AttrRetryable.Bool(evt.Error.Retryable),
+			AttrContentID.String(evt.Error.ContentID),
+		))
+		s.span.SetStatus(codes.Error, evt.Error.Message)
+
+	case acp.ContentStartEvent:
+		t.pending[evt.ContentID] = pendingContent{blockID: evt.RelatedBlockID, start: evt.Timestamp()}
+
+	case acp.ContentDeltaEvent:
+		t.onDelta(evt)
+
+	case acp.ContentEndEvent:
+		delete(t.pending, evt.ContentID)
+		if s, ok := t.contents[evt.ContentID]; ok {
+			s.span.End(trace.WithTimestamp(ts))
+			delete(t.contents, evt.ContentID)
+		}
+	}
+}
+
+func (t *Tracer) onDelta(evt acp.ContentDeltaEvent) {
+	if s, ok := t.contents[evt.ContentID]; ok {
+		switch sc := evt.Content.(type) {
+		case acp.StreamToolResultContent:
+			setError(s.span, sc.Error)
+		case acp.StreamMCPResultContent:
+			setError(s.span, sc.Error)
+		case acp.StreamCodeExecutionResultContent:
+			setError(s.span, sc.Error)
+		case acp.StreamCommandResultContent:
+			if sc.ExitCode != nil {
+				s.span.SetAttributes(AttrExitCode.Int(*sc.ExitCode))
+				if *sc.ExitCode != 0 && sc.Error == nil {
+					s.span.SetStatus(codes.Error, "non-zero exit code")
+				}
+			}
+			setError(s.span, sc.Error)
+		}
+		return
+	}
+
+	p, ok := t.pending[evt.ContentID]
+	if !ok {
+		return
+	}
+
+	var name string
+	var attrs []attribute.KeyValue
+	switch sc := evt.Content.(type) {
+	case acp.StreamToolCallContent:
+		name, attrs = "acp.tool_call", []attribute.KeyValue{AttrToolName.String(sc.ToolName)}
+	case acp.StreamMCPCallContent:
+		name, attrs = "acp.mcp_call", []attribute.KeyValue{AttrToolName.String(sc.ToolName), AttrMCPServer.String(sc.Server)}
+	case acp.StreamCommandContent:
+		name = "acp.command_execution"
+	case acp.StreamCodeExecutionContent:
+		name, attrs = "acp.code_execution", []attribute.KeyValue{AttrCodeLang.String(sc.Lang)}
+	default:
+		// 其他内容不单独建 span
+		delete(t.pending, evt.ContentID)
+		return
+	}
+
+	parent := t.rootContext()
+	if b, ok := t.blocks[p.blockID]; ok {
+		parent = b.ctx
+	}
+	attrs = append(attrs, AttrContentID.String(evt.ContentID))
+	ctx, s := t.tracer.Start(parent, name,
+		trace.WithTimestamp(time.UnixMilli(p.start)),
+		trace.WithAttributes(attrs...))
+	t.contents[evt.ContentID] = &span{ctx: ctx, span: s}
+	delete(t.pending, evt.ContentID)
+}
+
+// endRun 结束运行 span, 并关闭仍未结束的内容与区块 span
+func (t *Tracer) endRun(ts time.Time, runErr *acp.RunErrorEvent) {
+	for id, s := range t.contents {
+		s.span.End(trace.WithTimestamp(ts))
+		delete(t.contents, id)
+	}
+	for id, s := range t.blocks {
+		s.span.End(trace.WithTimestamp(ts))
+		delete(t.blocks, id)
+	}
+	clear(t.pending)
+
+	if t.run == nil {
+		return
+	}
+	if runErr != nil {
+		errType := "run_error"
+		if runErr.Info != nil {
+			errType = runErr.Info.Code
+			t.run.span.SetAttributes(AttrRetryable.Bool(runErr.Info.Retryable))
+		}
+		t.run.span.SetAttributes(AttrErrorType.String(errType))
+		t.run.span.SetStatus(codes.Error, runErr.Error)
+	}
+	t.run.span.End(trace.WithTimestamp(ts))
+	t.run = nil
+}
+
+func (t *Tracer) rootContext() context.Context {
+	if t.run != nil {
+		return t.run.ctx
+	}
+	return t.ctx
+}
+
+func setError(s trace.Span, e *acp.Error) {
+	if e == nil {
+		return
+	}
+	s.SetAttributes(AttrErrorType.String(e.Type))
+	s.SetStatus(codes.Error, e.Message)
+}
+
+func usageAttributes(u *acp.Usage) []attribute.KeyValue {
+	if u == nil {
+		return nil
+	}
+
+	attrs := []attribute.KeyValue{
+		AttrInputTokens.Int64(u.PromptTokens),
+		AttrOutputTokens.Int64(u.CompletionTokens),
+	}
+	if u.CachedPromptTokens > 0 {
+		attrs = append(attrs, AttrCachedTokens.Int64(u.CachedPromptTokens))
+	}
+	if u.ReasoningTokens > 0 {
+		attrs = append(attrs, AttrReasoningTokens.Int64(u.ReasoningTokens))
+	}
+	if u.Model != "" {
+		attrs = append(attrs, AttrModel.String(u.Model))
+	}
+	if u.Cost != nil {
+		attrs = append(attrs, AttrCost.Float64(*u.Cost))
+	}
+	return attrs
+}
+
+func timestamp(e acp.Event) time.Time {
+	if e.Timestamp() > 0 {
+		return time.UnixMilli(e.Timestamp())
+	}
+	return time.Now()
+}
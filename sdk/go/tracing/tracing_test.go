@@ -0,0 +1,148 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/llm-infra/acp/sdk/go/acp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newCreator(t *testing.T) (*acp.Creator, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	return acp.NewCreator(nil, acp.WithInterceptors(NewTracer(context.Background(), provider).Intercept)), exporter
+}
+
+func spanByName(spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	for _, s := range spans {
+		if s.Name == name {
+			return s
+		}
+	}
+	return tracetest.SpanStub{}
+}
+
+func attr(s tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range s.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracerRun(t *testing.T) {
+	m, exporter := newCreator(t)
+
+	require.NoError(t, m.AddEvent(acp.NewRunStartedEvent("s1", "r1")))
+	require.NoError(t, m.AddEvent(acp.NewBlockStartEvent("b1")))
+	require.NoError(t, m.AddEvent(acp.NewContentStartEvent("c1", "b1")))
+	require.NoError(t, m.AddEvent(acp.NewContentDeltaEvent("c1", acp.NewStreamToolCallContent("search"))))
+	require.NoError(t, m.AddEvent(acp.NewContentDeltaEvent("c1", acp.NewStreamToolArgsContent(`{}`))))
+	require.NoError(t, m.AddEvent(acp.NewContentDeltaEvent("c1", acp.NewStreamToolErrorContent(&acp.Error{Type: "timeout", Message: "too slow"}))))
+	require.NoError(t, m.AddEvent(acp.NewContentEndEvent("c1")))
+	require.NoError(t, m.AddEvent(acp.NewBlockStartEvent("b2", acp.WithParentBlockID("b1"), acp.WithIsSubagent())))
+	require.NoError(t, m.AddEvent(acp.NewContentStartEvent("c2", "b2")))
+	require.NoError(t, m.AddEvent(acp.NewContentDeltaEvent("c2", acp.NewStreamCommandContent("ls"))))
+	require.NoError(t, m.AddEvent(acp.NewContentDeltaEvent("c2", acp.NewStreamCommandResultContent("out", 2))))
+	require.NoError(t, m.AddEvent(acp.NewContentEndEvent("c2")))
+	require.NoError(t, m.AddEvent(acp.NewContentStartEvent("c3", "b2")))
+	require.NoError(t, m.AddEvent(acp.NewContentDeltaEvent("c3", acp.NewStreamTextContent("no span"))))
+	require.NoError(t, m.AddEvent(acp.NewContentEndEvent("c3")))
+	require.NoError(t, m.AddEvent(acp.NewBlockEndEvent("b2", nil)))
+	require.NoError(t, m.AddEvent(acp.NewBlockEndEvent("b1", &acp.Usage{PromptTokens: 10, CompletionTokens: 5, Model: "gpt"})))
+	require.NoError(t, m.AddEvent(acp.NewRunFinishedEvent("r1")))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 5)
+
+	run := spanByName(spans, "acp.run")
+	assert.Equal(t, "r1", attr(run, AttrRunID).AsString())
+	assert.Equal(t, "s1", attr(run, AttrSessionID).AsString())
+	assert.Equal(t, codes.Unset, run.Status.Code)
+	assert.False(t, run.Parent.IsValid())
+
+	var b1, b2 tracetest.SpanStub
+	for _, s := range spans {
+		if s.Name == "acp.block" {
+			if attr(s, AttrBlockID).AsString() == "b1" {
+				b1 = s
+			} else {
+				b2 = s
+			}
+		}
+	}
+	assert.Equal(t, run.SpanContext.SpanID(), b1.Parent.SpanID())
+	assert.Equal(t, b1.SpanContext.SpanID(), b2.Parent.SpanID())
+	assert.True(t, attr(b2, AttrSubagent).AsBool())
+	assert.Equal(t, int64(10), attr(b1, AttrInputTokens).AsInt64())
+	assert.Equal(t, int64(5), attr(b1, AttrOutputTokens).AsInt64())
+	assert.Equal(t, "gpt", attr(b1, AttrModel).AsString())
+
+	tool := spanByName(spans, "acp.tool_call")
+	assert.Equal(t, b1.SpanContext.SpanID(), tool.Parent.SpanID())
+	assert.Equal(t, "search", attr(tool, AttrToolName).AsString())
+	assert.Equal(t, "timeout", attr(tool, AttrErrorType).AsString())
+	assert.Equal(t, codes.Error, tool.Status.Code)
+
+	cmd := spanByName(spans, "acp.command_execution")
+	assert.Equal(t, b2.SpanContext.SpanID(), cmd.Parent.SpanID())
+	assert.Equal(t, int64(2), attr(cmd, AttrExitCode).AsInt64())
+	assert.Equal(t, codes.Error, cmd.Status.Code)
+
+	for _, s := range spans {
+		assert.Equal(t, run.SpanContext.TraceID(), s.SpanContext.TraceID())
+		assert.False(t, s.EndTime.Before(s.StartTime))
+	}
+}
+
+func TestTracerRunError(t *testing.T) {
+	m, exporter := newCreator(t)
+
+	require.NoError(t, m.AddEvent(acp.NewRunStartedEvent("s1", "r1")))
+	require.NoError(t, m.AddEvent(acp.NewBlockStartEvent("b1")))
+	require.NoError(t, m.AddEvent(acp.NewContentStartEvent("c1", "b1")))
+	require.NoError(t, m.AddEvent(acp.NewContentDeltaEvent("c1", acp.NewStreamMCPCallContent("fs", "read"))))
+	require.NoError(t, m.AddEvent(acp.NewRunErrorEvent("r1", "model overloaded")))
+
+	// 未结束的区块和内容随运行一起结束
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 3)
+
+	run := spanByName(spans, "acp.run")
+	assert.Equal(t, codes.Error, run.Status.Code)
+	assert.Equal(t, "model overloaded", run.Status.Description)
+
+	mcp := spanByName(spans, "acp.mcp_call")
+	assert.Equal(t, "fs", attr(mcp, AttrMCPServer).AsString())
+	assert.Equal(t, "read", attr(mcp, AttrToolName).AsString())
+}
+
+func TestTracerStructuredErrors(t *testing.T) {
+	m, exporter := newCreator(t)
+
+	require.NoError(t, m.AddEvent(acp.NewRunStartedEvent("s1", "r1")))
+	require.NoError(t, m.AddEvent(acp.NewBlockStartEvent("b1")))
+	require.NoError(t, m.AddEvent(acp.NewBlockErrorEvent("b1", &acp.RunError{Code: acp.ErrorCodeToolFailed, Message: "search failed", ContentID: "c1"})))
+	require.NoError(t, m.AddEvent(acp.NewBlockEndEvent("b1", nil)))
+	require.NoError(t, m.AddEvent(acp.NewRunErrorEventFrom("r1", &acp.RunError{Code: acp.ErrorCodeRateLimited, Message: "slow down", Retryable: true})))
+
+	spans := exporter.GetSpans()
+	block := spanByName(spans, "acp.block")
+	assert.Equal(t, codes.Error, block.Status.Code)
+	require.Len(t, block.Events, 1)
+	assert.Equal(t, "acp.block_error", block.Events[0].Name)
+
+	run := spanByName(spans, "acp.run")
+	assert.Equal(t, acp.ErrorCodeRateLimited, attr(run, AttrErrorType).AsString())
+	assert.True(t, attr(run, AttrRetryable).AsBool())
+	assert.Equal(t, "slow down", run.Status.Description)
+}